@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mybft/internal/redisx"
+	"mybft/internal/storage"
+	"mybft/internal/tlsx"
+)
+
+// 解析节点编号列表，如 "1,2,3"；为空时返回 1..n。
+func parseNodeIDs(raw string, n int) ([]int, error) {
+	var ids []int
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		id, err := strconv.Atoi(item)
+		if err != nil || id < 1 || id > n {
+			return nil, fmt.Errorf("invalid node id %q (N=%d)", item, n)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		for id := 1; id <= n; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Finding 是合并各节点视图后的一条证据：HeldBy 为保存了该证据的节点，
+// IncludedHeight 为任一节点观察到的上链高度（0 表示尚未打包进已提交区块）。
+type Finding struct {
+	ID             string `json:"id"`
+	Kind           string `json:"kind"`
+	Offender       int    `json:"offender"`
+	View           int    `json:"view"`
+	Height         int    `json:"height"`
+	Reporter       int    `json:"reporter,omitempty"`
+	IncludedHeight int    `json:"included_height,omitempty"`
+	HeldBy         []int  `json:"held_by"`
+}
+
+// Offender 汇总单个作恶节点被抓到的证据。
+type Offender struct {
+	Node      int            `json:"node"`
+	Evidence  int            `json:"evidence"`
+	Committed int            `json:"committed"`
+	Kinds     map[string]int `json:"kinds"`
+}
+
+type Report struct {
+	Queried     []int      `json:"queried"`
+	Unreachable []int      `json:"unreachable,omitempty"`
+	Caught      []int      `json:"caught"`
+	Offenders   []Offender `json:"offenders"`
+	Findings    []Finding  `json:"findings"`
+}
+
+func fetchEvidence(client *http.Client, addr, kind string) ([]storage.EvidenceRecord, error) {
+	url := tlsx.Scheme(tlsx.Enabled()) + "://" + addr + "/evidence"
+	if kind != "" {
+		url += "?kind=" + kind
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	var records []storage.EvidenceRecord
+	err = json.NewDecoder(resp.Body).Decode(&records)
+	return records, err
+}
+
+func evidenceID(r storage.EvidenceRecord) string {
+	return fmt.Sprintf("%s:%d:%d", r.Kind, r.View, r.Offender)
+}
+
+// 合并各节点保存的证据：同一 ID 只计一次。
+func buildReport(ids []int, byNode map[int][]storage.EvidenceRecord) Report {
+	report := Report{Queried: ids, Caught: []int{}, Offenders: []Offender{}, Findings: []Finding{}}
+	findings := map[string]*Finding{}
+	for _, id := range ids {
+		records, ok := byNode[id]
+		if !ok {
+			report.Unreachable = append(report.Unreachable, id)
+			continue
+		}
+		for _, r := range records {
+			key := evidenceID(r)
+			f, ok := findings[key]
+			if !ok {
+				f = &Finding{ID: key, Kind: r.Kind, Offender: r.Offender, View: r.View, Height: r.Height, Reporter: r.Reporter}
+				findings[key] = f
+			}
+			f.HeldBy = append(f.HeldBy, id)
+			if r.IncludedHeight > 0 && (f.IncludedHeight == 0 || r.IncludedHeight < f.IncludedHeight) {
+				f.IncludedHeight = r.IncludedHeight
+			}
+		}
+	}
+	offenders := map[int]*Offender{}
+	for _, f := range findings {
+		report.Findings = append(report.Findings, *f)
+		o, ok := offenders[f.Offender]
+		if !ok {
+			o = &Offender{Node: f.Offender, Kinds: map[string]int{}}
+			offenders[f.Offender] = o
+		}
+		o.Evidence++
+		o.Kinds[f.Kind]++
+		if f.IncludedHeight > 0 {
+			o.Committed++
+		}
+	}
+	sort.Slice(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Offender != b.Offender {
+			return a.Offender < b.Offender
+		}
+		if a.View != b.View {
+			return a.View < b.View
+		}
+		return a.Kind < b.Kind
+	})
+	for id, o := range offenders {
+		report.Caught = append(report.Caught, id)
+		report.Offenders = append(report.Offenders, *o)
+	}
+	sort.Ints(report.Caught)
+	sort.Slice(report.Offenders, func(i, j int) bool { return report.Offenders[i].Node < report.Offenders[j].Node })
+	return report
+}
+
+func printReport(report Report) {
+	for _, f := range report.Findings {
+		fmt.Printf("evidence id=%s kind=%s offender=%d view=%d height=%d reporter=%d included_height=%d held_by=%v\n",
+			f.ID, f.Kind, f.Offender, f.View, f.Height, f.Reporter, f.IncludedHeight, f.HeldBy)
+	}
+	for _, o := range report.Offenders {
+		kinds := make([]string, 0, len(o.Kinds))
+		for k, v := range o.Kinds {
+			kinds = append(kinds, fmt.Sprintf("%s:%d", k, v))
+		}
+		sort.Strings(kinds)
+		fmt.Printf("offender node=%d evidence=%d committed=%d kinds=%s\n", o.Node, o.Evidence, o.Committed, strings.Join(kinds, ","))
+	}
+	fmt.Printf("queried=%v unreachable=%v caught=%v\n", report.Queried, report.Unreachable, report.Caught)
+}
+
+// 向运行中的集群查询各节点保存的作恶证据（GET /evidence），合并后按作恶节点汇总，
+// 报告实验中被抓到的故障节点及证据是否已打包进已提交区块。
+func main() {
+	nodes := flag.String("nodes", "", "node ids to query (default: all)")
+	kind := flag.String("kind", "", "only report evidence of this kind: conflicting_proposal|duplicate_vote|invalid_qc")
+	timeout := flag.Duration("timeout", 3*time.Second, "per-node request timeout")
+	out := flag.String("out", "", "report file (JSON, optional)")
+	flag.Parse()
+
+	rdb := redisx.NewClient()
+	cfg, err := redisx.ReadClusterConfig(rdb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ids, err := parseNodeIDs(*nodes, cfg.N)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := tlsx.NewHTTPClient(rdb, tlsx.Enabled(), *timeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	byNode := map[int][]storage.EvidenceRecord{}
+	for _, id := range ids {
+		records, err := fetchEvidence(client, fmt.Sprintf("127.0.0.1:%d", cfg.BasePort+id), *kind)
+		if err != nil {
+			log.Printf("node=%d query evidence: %v", id, err)
+			continue
+		}
+		byNode[id] = records
+	}
+	report := buildReport(ids, byNode)
+	printReport(report)
+	if *out == "" {
+		return
+	}
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, append(raw, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"mybft/internal/bench"
+)
+
+// envFlags 收集可重复的 -env KEY=VALUE 参数。
+type envFlags map[string]string
+
+func (e envFlags) String() string { return fmt.Sprint(map[string]string(e)) }
+
+func (e envFlags) Set(raw string) error {
+	k, v, ok := strings.Cut(raw, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("want KEY=VALUE: %q", raw)
+	}
+	e[k] = v
+	return nil
+}
+
+// 解析逗号分隔的列表，如 "4,7,10"。
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func parseInts(raw string) ([]int, error) {
+	var out []int
+	for _, item := range splitList(raw) {
+		v, err := strconv.Atoi(item)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid integer %q", item)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func parseDurations(raw string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, item := range splitList(raw) {
+		v, err := time.ParseDuration(item)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid duration %q", item)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// writeReport 把比较表写成 <prefix>.md 与 <prefix>.csv。
+func writeReport(prefix string, results []bench.Result) error {
+	rows := bench.Compare(results)
+	if err := os.MkdirAll(filepath.Dir(prefix), 0o755); err != nil {
+		return err
+	}
+	for ext, write := range map[string]func(io.Writer, []bench.Row) error{".md": bench.WriteMarkdown, ".csv": bench.WriteRowsCSV} {
+		f, err := os.Create(prefix + ext)
+		if err != nil {
+			return err
+		}
+		err = write(f, rows)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bench.WriteMarkdown(os.Stdout, rows)
+}
+
+// 在本机编排一次完整实验：编译、启动 genkey/client/N 个节点、注入故障、回收进程并写出结果。
+// -sweep 时按 算法 × N × 批大小 × 注入时延 扫参并生成比较报告；-from 只根据已有结果重新生成报告。
+func main() {
+	env := envFlags{}
+	alg := flag.String("alg", "sbft", "consensus algorithm: sbft|hotstuff|fast-hotstuff|hpbft")
+	n := flag.Int("n", 4, "number of nodes")
+	duration := flag.Duration("duration", 30*time.Second, "maximum run time")
+	heights := flag.Int("heights", 0, "stop once the client has confirmed this height (0 = run for -duration)")
+	faults := flag.String("faults", "", "fault plan, e.g. crash:3@10s,bootstrap:3@20s")
+	seed := flag.String("seed", "", "shortcut for -env MYBFT_WORKLOAD_SEED=...")
+	access := flag.String("access", "", "shortcut for -env MYBFT_WORKLOAD_ACCESS=uniform|zipf|conflict")
+	label := flag.String("label", "", "label recorded in the results")
+	out := flag.String("out", "results/bench.json", "results file (.json or .csv)")
+	binDir := flag.String("bin", "", "directory with prebuilt genkey/client/node (default: build into the work dir)")
+	workDir := flag.String("workdir", "", "directory for data and logs (default: results/runs/<alg>-n<N>-<time>)")
+	batch := flag.Int("batch", 0, "MYBFT_BATCH_MAX_TX for the run (0 = node default)")
+	delay := flag.Duration("delay", 0, "injected per-message network delay on nodes")
+	sweep := flag.Bool("sweep", false, "run a parameter sweep instead of a single run")
+	algs := flag.String("algs", "sbft,hotstuff,fast-hotstuff,hpbft", "sweep: algorithms")
+	ns := flag.String("ns", "4", "sweep: node counts")
+	batches := flag.String("batches", "", "sweep: batch sizes (MYBFT_BATCH_MAX_TX)")
+	delays := flag.String("delays", "", "sweep: injected delays, e.g. 0,10ms,50ms")
+	repeats := flag.Int("repeats", 1, "sweep: runs per configuration")
+	report := flag.String("report", "results/report", "sweep: comparison report prefix (.md and .csv are written)")
+	from := flag.String("from", "", "regenerate the comparison report from an existing JSON results file")
+	flag.Var(env, "env", "extra KEY=VALUE passed to client and nodes (repeatable)")
+	flag.Parse()
+
+	if *from != "" {
+		results, err := bench.ReadResults(*from)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writeReport(*report, results); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	plan, err := bench.ParseFaults(*faults)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *seed != "" {
+		env["MYBFT_WORKLOAD_SEED"] = *seed
+	}
+	if *access != "" {
+		env["MYBFT_WORKLOAD_ACCESS"] = *access
+	}
+	if *workDir == "" {
+		name := fmt.Sprintf("%s-n%d-%s", *alg, *n, time.Now().Format("20060102-150405"))
+		if *sweep {
+			name = "sweep-" + time.Now().Format("20060102-150405")
+		}
+		*workDir = filepath.Join("results", "runs", name)
+	}
+	dir, err := filepath.Abs(*workDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bin := *binDir
+	if bin == "" {
+		bin = filepath.Join(dir, "bin")
+		if err := bench.Build(bin); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if bin, err = filepath.Abs(bin); err != nil {
+		log.Fatal(err)
+	}
+
+	spec := bench.Spec{Label: *label, Alg: *alg, N: *n, Duration: *duration, Heights: *heights, Batch: *batch, Delay: *delay, Env: env, Faults: plan, BinDir: bin, WorkDir: dir}
+	if *sweep {
+		w := bench.Sweep{Base: spec, Algs: splitList(*algs), Repeats: *repeats}
+		if w.Ns, err = parseInts(*ns); err != nil {
+			log.Fatal(err)
+		}
+		if w.Batches, err = parseInts(*batches); err != nil {
+			log.Fatal(err)
+		}
+		if w.Delays, err = parseDurations(*delays); err != nil {
+			log.Fatal(err)
+		}
+		var results []bench.Result
+		bench.RunSweep(w, func(r bench.Result) {
+			results = append(results, r)
+			// 每次运行后落盘，扫参中途中断也能保留已完成的结果。
+			if err := bench.WriteResults(*out, results); err != nil {
+				log.Printf("bench write results: %v", err)
+			}
+		})
+		if err := writeReport(*report, results); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	log.Printf("bench alg=%s n=%d duration=%s heights=%d faults=%q workdir=%s", spec.Alg, spec.N, spec.Duration, spec.Heights, *faults, dir)
+	result, runErr := bench.Run(spec)
+	if err := bench.WriteResults(*out, []bench.Result{result}); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("heights=%d txs=%d throughput=%f tx/s p50=%f p99=%f max=%f results=%s\n",
+		result.Heights, result.Txs, result.Throughput, result.Latency.P50, result.Latency.P99, result.Latency.Max, *out)
+	if runErr != nil {
+		log.Printf("bench run: %v", runErr)
+		os.Exit(1)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"strconv"
 
 	"mybft/internal/common"
+	"mybft/internal/crypto"
 	"mybft/internal/redisx"
+	"mybft/internal/tlsx"
 )
 
 // 生成演示用密钥材料（非真实安全的门限密钥）。
@@ -20,25 +22,59 @@ func randKey() string {
 }
 
 // 初始化集群配置与节点密钥，写入 Redis 供 node/client 读取。
+// extra 为额外生成密钥的节点数（编号 N+1 起），供之后经成员变更加入集群；cluster:config 的 N 仍为初始集合。
+// 每个节点另有一对 X25519 密钥（dh_sk/dh_pk），MYBFT_AUTH=mac 时用于协商成对的 MAC 密钥。
+// 同时生成本地 CA 及 client 与各节点的证书，供 MYBFT_TLS=1 时的双向 TLS 使用。
+// MYBFT_WEIGHTS 按编号顺序给出各节点的投票权重（如 "3,1,1,1"），未给出的节点权重为 1。
 func main() {
-	if len(os.Args) != 2 {
-		log.Fatal("usage: genkey N")
+	if len(os.Args) != 2 && len(os.Args) != 3 {
+		log.Fatal("usage: genkey N [extra]")
 	}
 	n, err := strconv.Atoi(os.Args[1])
 	if err != nil || n < 1 {
 		log.Fatal("invalid N")
 	}
+	extra := 0
+	if len(os.Args) == 3 {
+		if extra, err = strconv.Atoi(os.Args[2]); err != nil || extra < 0 {
+			log.Fatal("invalid extra")
+		}
+	}
+	weightsRaw := os.Getenv("MYBFT_WEIGHTS")
+	var weights map[int]int
+	if weightsRaw != "" {
+		if weights, err = common.ParseWeights(weightsRaw); err != nil {
+			log.Fatalf("invalid MYBFT_WEIGHTS: %v", err)
+		}
+	}
+	ids := make([]int, n+extra)
+	for i := range ids {
+		ids[i] = i + 1
+	}
 	rdb := redisx.NewClient()
-	th := common.CalcThresholds(n)
-	rdb.HSet("cluster:config", map[string]string{"N": strconv.Itoa(n), "basePort": "9000", "clientAddr": "127.0.0.1:8000", "t": strconv.Itoa(th.T)})
-	for i := 1; i <= n; i++ {
+	th := common.CalcThresholds(common.TotalPower(weights, ids[:n]))
+	rdb.HSet("cluster:config", map[string]string{"N": strconv.Itoa(n), "basePort": "9000", "clientAddr": "127.0.0.1:8000", "t": strconv.Itoa(th.T), "weights": weightsRaw})
+	for i := 1; i <= n+extra; i++ {
+		dhSK, dhPK, err := crypto.NewDHKey()
+		if err != nil {
+			log.Fatalf("generate dh key: %v", err)
+		}
 		key := fmt.Sprintf("Node:%d", i)
 		rdb.HSet(key, map[string]string{
 			"threshold_pk": "demo-threshold-pk",
 			"threshold_sk": randKey(),
 			"agg_pk":       "demo-agg-pk",
 			"agg_sk":       randKey(),
+			"dh_pk":        dhPK,
+			"dh_sk":        dhSK,
 		})
 	}
-	log.Printf("generated keys for N=%d t=%d q=%d", n, th.T, th.Q)
+	ca, err := tlsx.NewCA()
+	if err != nil {
+		log.Fatalf("generate tls ca: %v", err)
+	}
+	if err := ca.Save(rdb, ids); err != nil {
+		log.Fatalf("save tls certificates: %v", err)
+	}
+	log.Printf("generated keys for N=%d power=%d t=%d q=%d extra=%d", n, th.N, th.T, th.Q, extra)
 }
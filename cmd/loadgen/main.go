@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"mybft/internal/loadgen"
+	"mybft/internal/redisx"
+	"mybft/internal/tlsx"
+	"mybft/internal/workload"
+)
+
+// 解析节点编号列表，如 "1,2,3"；为空时返回 1..n。
+func parseNodeIDs(raw string, n int) ([]int, error) {
+	var ids []int
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		id, err := strconv.Atoi(item)
+		if err != nil || id < 1 || id > n {
+			return nil, fmt.Errorf("invalid node id %q (N=%d)", item, n)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		for id := 1; id <= n; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// 向运行中的集群提交真实交易并轮询提交通知，统计逐笔的端到端时延。
+// 交易内容由 MYBFT_WORKLOAD_* 配置的负载生成器产生；建议节点以 MYBFT_SYNTHETIC_LOAD=0 启动。
+func main() {
+	nodes := flag.String("nodes", "", "node ids to submit to, round-robin (default: all)")
+	watch := flag.Int("watch", 0, "node id polled for commit notifications (default: first of -nodes)")
+	appName := flag.String("app", "transfer", "application running on the nodes: transfer|kvstore")
+	rate := flag.Float64("rate", 200, "target submission rate in tx/s")
+	batch := flag.Int("batch", 10, "transactions per submission")
+	duration := flag.Duration("duration", 30*time.Second, "submission time")
+	drain := flag.Duration("drain", 10*time.Second, "maximum wait for outstanding transactions after submission stops")
+	poll := flag.Duration("poll", 50*time.Millisecond, "commit polling interval")
+	out := flag.String("out", "results/loadgen.json", "summary file (JSON)")
+	txsOut := flag.String("txs", "", "per-transaction CSV (optional)")
+	flag.Parse()
+
+	rdb := redisx.NewClient()
+	cfg, err := redisx.ReadClusterConfig(rdb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := tlsx.NewHTTPClient(rdb, tlsx.Enabled(), 5*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ids, err := parseNodeIDs(*nodes, cfg.N)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wl, err := workload.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	addr := func(id int) string { return fmt.Sprintf("127.0.0.1:%d", cfg.BasePort+id) }
+	spec := loadgen.Config{App: *appName, Rate: *rate, Batch: *batch, Duration: *duration, Drain: *drain, Poll: *poll, Workload: wl, TLS: tlsx.Enabled(), Client: client}
+	for _, id := range ids {
+		spec.Nodes = append(spec.Nodes, addr(id))
+	}
+	if *watch > 0 {
+		spec.Watch = addr(*watch)
+	}
+	log.Printf("loadgen app=%s nodes=%v rate=%g batch=%d duration=%s", spec.App, ids, spec.Rate, spec.Batch, spec.Duration)
+	res, records, err := loadgen.Run(spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := loadgen.WriteResult(*out, res); err != nil {
+		log.Fatal(err)
+	}
+	if *txsOut != "" {
+		if err := loadgen.WriteTxs(*txsOut, records); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("submitted=%d committed=%d rejected=%d failed=%d pending=%d throughput=%f tx/s p50=%f p99=%f max=%f results=%s\n",
+		res.Submitted, res.Committed, res.Rejected, res.Failed, res.Pending, res.Throughput, res.Latency.P50, res.Latency.P99, res.Latency.Max, *out)
+}
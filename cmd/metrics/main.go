@@ -1,33 +1,128 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"mybft/internal/analysis"
+	"mybft/internal/histogram"
 	"mybft/internal/storage"
 	leveldbstore "mybft/internal/storage/leveldb"
 )
 
-func main() {
-	dataRoot := os.Getenv("MYBFT_DATA_DIR")
-	if dataRoot == "" {
-		dataRoot = "data"
+const usage = `usage:
+  metrics                                   list all heights of the latest run
+  metrics <height>                          show one height of the latest run
+  metrics runs                              list runs with their metadata
+  metrics list    [--run ID]                list all heights of a run
+  metrics range   --from H [--to H]         list heights in [from, to]
+  metrics summary [--from H] [--to H]       latency percentiles and throughput over a range
+  metrics export  --format csv|json|ndjson [--from H] [--to H] [--out FILE]
+  metrics samples [--since DUR|RFC3339|UNIXNANO]
+  metrics phases  [--from H] [--to H]       per-phase latency breakdown per height and mean per algorithm
+  metrics diff    <dataDirA[@run]> <dataDirB[@run]> [--from H] [--to H]
+common flags: --data DIR (default $MYBFT_DATA_DIR or data)
+              --run ID   (default the latest run; "legacy" selects data written before run IDs)`
+
+// 旧版本未按运行隔离写入的数据在 --run 中以该名称选择。
+const legacyRun = "legacy"
+
+func defaultDataRoot() string {
+	if dataRoot := os.Getenv("MYBFT_DATA_DIR"); dataRoot != "" {
+		return dataRoot
 	}
+	return "data"
+}
+
+func openStores(dataRoot string) *leveldbstore.ClientStores {
 	stores, err := leveldbstore.OpenClientStores(dataRoot)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer stores.Close()
+	return stores
+}
 
-	switch len(os.Args) {
-	case 1:
-		records, err := stores.Metrics.ListMetrics()
+// runMetrics 解析 --run：空值取最新运行，legacy 取旧数据。
+func runMetrics(stores *leveldbstore.ClientStores, run string) storage.MetricsStore {
+	switch run {
+	case legacyRun:
+		return stores.Metrics("")
+	case "":
+		latest, err := stores.LatestRun()
 		if err != nil {
 			log.Fatal(err)
 		}
+		return stores.Metrics(latest)
+	}
+	if _, err := stores.Runs.LoadRun(run); err != nil {
+		log.Fatalf("run %s: %v", run, err)
+	}
+	return stores.Metrics(run)
+}
+
+func loadRange(dataRoot, run string, from, to int) []storage.MetricRecord {
+	stores := openStores(dataRoot)
+	defer stores.Close()
+	records, err := runMetrics(stores, run).ListMetrics()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return analysis.InRange(records, from, to)
+}
+
+// 查看 client 在本地 LevelDB 中保存的逐高度指标，支持区间查询、汇总、导出与两次运行的对比。
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) == 1 {
+		listAll(defaultDataRoot(), "")
+		return
+	}
+	if height, err := strconv.Atoi(os.Args[1]); err == nil {
+		if len(os.Args) != 2 || height < 1 {
+			log.Fatal(usage)
+		}
+		showHeight(defaultDataRoot(), "", height)
+		return
+	}
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	dataRoot := fs.String("data", defaultDataRoot(), "client data directory")
+	run := fs.String("run", "", "run ID (default latest, legacy for data without run IDs)")
+	from := fs.Int("from", 1, "first height")
+	to := fs.Int("to", 0, "last height (0 = latest)")
+	format := fs.String("format", "csv", "export format: csv|json|ndjson")
+	out := fs.String("out", "", "export destination (default stdout)")
+	since := fs.String("since", "", "samples since a duration ago (e.g. 30s), an RFC3339 time or unix nanoseconds")
+	args := os.Args[2:]
+	var positional []string
+	// 允许位置参数与 --flag 交错出现，例如 diff a b --from 10。
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			log.Fatal(err)
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+
+	switch os.Args[1] {
+	case "runs":
+		listRuns(*dataRoot)
+	case "list":
+		listAll(*dataRoot, *run)
+	case "range":
+		records := loadRange(*dataRoot, *run, *from, *to)
 		if len(records) == 0 {
 			fmt.Println("no metrics found")
 			return
@@ -35,21 +130,102 @@ func main() {
 		for _, record := range records {
 			printMetric(record)
 		}
-	case 2:
-		height, err := strconv.Atoi(os.Args[1])
-		if err != nil || height < 1 {
-			log.Fatal("usage: metrics [height]")
+	case "summary":
+		printSummary(os.Stdout, analysis.Summarize(loadRange(*dataRoot, *run, *from, *to)))
+	case "export":
+		w := io.Writer(os.Stdout)
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
 		}
-		record, err := stores.Metrics.LoadMetric(height)
+		if err := export(w, *format, loadRange(*dataRoot, *run, *from, *to)); err != nil {
+			log.Fatal(err)
+		}
+	case "phases":
+		listPhases(*dataRoot, *run, *from, *to)
+	case "samples":
+		sinceNano, err := parseSince(*since)
 		if err != nil {
 			log.Fatal(err)
 		}
-		printMetric(record)
+		listSamples(*dataRoot, *run, sinceNano)
+	case "diff":
+		if len(positional) != 2 {
+			log.Fatal(usage)
+		}
+		diff(positional[0], positional[1], *from, *to)
 	default:
-		log.Fatal("usage: metrics [height]")
+		log.Fatal(usage)
 	}
 }
 
+func listRuns(dataRoot string) {
+	stores := openStores(dataRoot)
+	defer stores.Close()
+	runs, err := stores.Runs.ListRuns()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if legacy, err := stores.Metrics("").ListMetrics(); err == nil && len(legacy) > 0 {
+		fmt.Printf("run=%s records=%d\n", legacyRun, len(legacy))
+	}
+	if len(runs) == 0 {
+		fmt.Println("no runs found")
+		return
+	}
+	for _, r := range runs {
+		records, err := stores.Metrics(r.ID).ListMetrics()
+		if err != nil {
+			log.Fatal(err)
+		}
+		keys := make([]string, 0, len(r.Config))
+		for k := range r.Config {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		config := make([]string, len(keys))
+		for i, k := range keys {
+			config[i] = k + "=" + r.Config[k]
+		}
+		fmt.Printf("run=%s alg=%s n=%d started_at=%s records=%d config=[%s]\n",
+			r.ID, r.Alg, r.N, time.Unix(0, r.StartedAt).Format(time.RFC3339), len(records), strings.Join(config, " "))
+	}
+}
+
+func listAll(dataRoot, run string) {
+	stores := openStores(dataRoot)
+	defer stores.Close()
+	metrics := runMetrics(stores, run)
+	records, err := metrics.ListMetrics()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(records) == 0 {
+		fmt.Println("no metrics found")
+		return
+	}
+	for _, record := range records {
+		printMetric(record)
+	}
+	if hist, err := metrics.LoadHistogram("latency:run"); err == nil {
+		printLatency("run", histogram.Import(hist).Summary())
+	}
+}
+
+func showHeight(dataRoot, run string, height int) {
+	stores := openStores(dataRoot)
+	defer stores.Close()
+	record, err := runMetrics(stores, run).LoadMetric(height)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printMetric(record)
+}
+
 func printMetric(record storage.MetricRecord) {
 	recordedAt := time.Unix(0, record.RecordedAt).Format(time.RFC3339)
 	fmt.Printf(
@@ -61,4 +237,219 @@ func printMetric(record storage.MetricRecord) {
 		record.WindowSeconds,
 		recordedAt,
 	)
+	if record.WindowLatency != nil {
+		printLatency("window", *record.WindowLatency)
+	}
+	if record.RunLatency != nil {
+		printLatency("run", *record.RunLatency)
+	}
+}
+
+func printLatency(scope string, sum storage.LatencySummary) {
+	fmt.Printf(
+		"  %s latency count=%d min=%f mean=%f p50=%f p90=%f p99=%f p999=%f max=%f\n",
+		scope, sum.Count, sum.Min, sum.Mean, sum.P50, sum.P90, sum.P99, sum.P999, sum.Max,
+	)
+}
+
+func printSummary(w io.Writer, sum analysis.Summary) {
+	fmt.Fprintf(w, "heights=%d..%d records=%d txs=%d span=%fs throughput=%f tx/s\n",
+		sum.FromHeight, sum.ToHeight, sum.Records, sum.Txs, sum.Span, sum.Throughput)
+	l := sum.Latency
+	fmt.Fprintf(w, "latency min=%f mean=%f p50=%f p90=%f p99=%f p999=%f max=%f\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P99, l.P999, l.Max)
+}
+
+func export(w io.Writer, format string, records []storage.MetricRecord) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"height", "latency", "batch", "throughput", "window_seconds", "recorded_at"})
+		for _, r := range records {
+			_ = cw.Write([]string{
+				strconv.Itoa(r.Height),
+				strconv.FormatFloat(r.Latency, 'f', 6, 64),
+				strconv.Itoa(r.Batch),
+				strconv.FormatFloat(r.Throughput, 'f', 6, 64),
+				strconv.Itoa(r.WindowSeconds),
+				strconv.FormatInt(r.RecordedAt, 10),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q: want csv|json|ndjson", format)
+}
+
+// listPhases 打印区间内每个高度的分阶段耗时，并按算法给出各阶段平均值。
+func listPhases(dataRoot, run string, from, to int) {
+	stores := openStores(dataRoot)
+	defer stores.Close()
+	records, err := runMetrics(stores, run).ListPhases()
+	if err != nil {
+		log.Fatal(err)
+	}
+	type acc struct {
+		sum   map[string]float64
+		count map[string]int
+	}
+	byAlg := map[string]*acc{}
+	var algs []string
+	printed := 0
+	for _, r := range records {
+		if r.Height < from || (to > 0 && r.Height > to) {
+			continue
+		}
+		printed++
+		a, ok := byAlg[r.Alg]
+		if !ok {
+			a = &acc{sum: map[string]float64{}, count: map[string]int{}}
+			byAlg[r.Alg] = a
+			algs = append(algs, r.Alg)
+		}
+		parts := []string{fmt.Sprintf("height=%d alg=%s leader=%d reports=%d", r.Height, r.Alg, r.Leader, r.Reports)}
+		for _, phase := range storage.PhaseOrder {
+			if d, ok := r.Durations[phase]; ok {
+				parts = append(parts, fmt.Sprintf("%s=%f", phase, d))
+				a.sum[phase] += d
+				a.count[phase]++
+			}
+		}
+		fmt.Println(strings.Join(parts, " "))
+	}
+	if printed == 0 {
+		fmt.Println("no phase records found")
+		return
+	}
+	sort.Strings(algs)
+	for _, alg := range algs {
+		a := byAlg[alg]
+		parts := []string{"mean alg=" + alg}
+		for _, phase := range storage.PhaseOrder {
+			if a.count[phase] > 0 {
+				parts = append(parts, fmt.Sprintf("%s=%f", phase, a.sum[phase]/float64(a.count[phase])))
+			}
+		}
+		fmt.Println(strings.Join(parts, " "))
+	}
+}
+
+// parseSince 接受相对时长（30s 表示 30 秒前）、RFC3339 时间或 unix 纳秒，空值表示全部。
+func parseSince(raw string) (int64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return time.Now().Add(-d).UnixNano(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UnixNano(), nil
+	}
+	if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return v, nil
+	}
+	return 0, fmt.Errorf("invalid --since %q", raw)
+}
+
+func listSamples(dataRoot, run string, since int64) {
+	stores := openStores(dataRoot)
+	defer stores.Close()
+	samples, err := runMetrics(stores, run).LoadThroughputSamplesSince(since)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(samples) == 0 {
+		fmt.Println("no samples found")
+		return
+	}
+	total := 0
+	for _, sample := range samples {
+		total += sample.TxCount
+		fmt.Printf("recorded_at=%s tx_count=%d\n", time.Unix(0, sample.RecordedAt).Format(time.RFC3339Nano), sample.TxCount)
+	}
+	span := float64(samples[len(samples)-1].RecordedAt-samples[0].RecordedAt) / 1e9
+	rate := 0.0
+	if span > 0 {
+		rate = float64(total) / span
+	}
+	fmt.Printf("samples=%d txs=%d span=%fs rate=%f tx/s\n", len(samples), total, span, rate)
+}
+
+// splitTarget 把 dir@run 拆成数据目录与运行 ID，省略 @run 时取该目录最新的运行。
+func splitTarget(target string) (string, string) {
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		return target[:i], target[i+1:]
+	}
+	return target, ""
+}
+
+// diff 对比两个数据目录（或同一目录中的两次运行）在同一高度区间上的汇总，并列出批大小不一致或只在一方出现的高度。
+func diff(targetA, targetB string, from, to int) {
+	dirA, runA := splitTarget(targetA)
+	dirB, runB := splitTarget(targetB)
+	a := loadRange(dirA, runA, from, to)
+	b := loadRange(dirB, runB, from, to)
+	sa, sb := analysis.Summarize(a), analysis.Summarize(b)
+	fmt.Printf("A=%s\n", targetA)
+	printSummary(os.Stdout, sa)
+	fmt.Printf("B=%s\n", targetB)
+	printSummary(os.Stdout, sb)
+	rows := []struct {
+		name string
+		a, b float64
+	}{
+		{"records", float64(sa.Records), float64(sb.Records)},
+		{"txs", float64(sa.Txs), float64(sb.Txs)},
+		{"throughput", sa.Throughput, sb.Throughput},
+		{"latency_mean", sa.Latency.Mean, sb.Latency.Mean},
+		{"latency_p50", sa.Latency.P50, sb.Latency.P50},
+		{"latency_p90", sa.Latency.P90, sb.Latency.P90},
+		{"latency_p99", sa.Latency.P99, sb.Latency.P99},
+		{"latency_max", sa.Latency.Max, sb.Latency.Max},
+	}
+	fmt.Printf("%-14s %14s %14s %14s %9s\n", "metric", "A", "B", "B-A", "change")
+	for _, r := range rows {
+		change := "n/a"
+		if r.a != 0 {
+			change = fmt.Sprintf("%+.1f%%", (r.b-r.a)/r.a*100)
+		}
+		fmt.Printf("%-14s %14.6f %14.6f %+14.6f %9s\n", r.name, r.a, r.b, r.b-r.a, change)
+	}
+	byHeight := make(map[int]storage.MetricRecord, len(a))
+	for _, r := range a {
+		byHeight[r.Height] = r
+	}
+	var onlyB []string
+	common := 0
+	for _, r := range b {
+		ra, ok := byHeight[r.Height]
+		if !ok {
+			onlyB = append(onlyB, strconv.Itoa(r.Height))
+			continue
+		}
+		delete(byHeight, r.Height)
+		common++
+		if ra.Batch != r.Batch {
+			fmt.Printf("height=%d batch differs: A=%d B=%d\n", r.Height, ra.Batch, r.Batch)
+		}
+	}
+	var onlyA []string
+	for _, r := range a {
+		if _, ok := byHeight[r.Height]; ok {
+			onlyA = append(onlyA, strconv.Itoa(r.Height))
+		}
+	}
+	fmt.Printf("common_heights=%d only_a=[%s] only_b=[%s]\n", common, strings.Join(onlyA, ","), strings.Join(onlyB, ","))
 }
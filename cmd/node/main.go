@@ -9,9 +9,10 @@ import (
 )
 
 // 启动单个共识节点，按指定算法处理消息并参与闭环流程。
+// 追加 --bootstrap 时先从同伴安装最新快照，再进入共识。
 func main() {
-	if len(os.Args) != 3 {
-		log.Fatal("usage: node id alg")
+	if len(os.Args) != 3 && !(len(os.Args) == 4 && os.Args[3] == "--bootstrap") {
+		log.Fatal("usage: node id alg [--bootstrap]")
 	}
 	id, err := strconv.Atoi(os.Args[1])
 	if err != nil || id < 1 {
@@ -23,7 +24,8 @@ func main() {
 	default:
 		log.Fatalf("invalid alg: %s", alg)
 	}
-	if err := nodesvc.Run(id, alg); err != nil {
+	opts := nodesvc.Options{Bootstrap: len(os.Args) == 4}
+	if err := nodesvc.Run(id, alg, opts); err != nil {
 		log.Fatal(err)
 	}
 }
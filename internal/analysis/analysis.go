@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"mybft/internal/histogram"
+	"mybft/internal/storage"
+)
+
+// Summary 汇总一组逐高度指标记录：时延单位为秒，吞吐量为交易数除以首个提案到最后提交的时长。
+type Summary struct {
+	Records    int                    `json:"records"`
+	FromHeight int                    `json:"from_height"`
+	ToHeight   int                    `json:"to_height"`
+	Txs        int                    `json:"txs"`
+	Span       float64                `json:"span"`
+	Throughput float64                `json:"throughput"`
+	Latency    storage.LatencySummary `json:"latency"`
+}
+
+// InRange 返回高度在 [from, to] 内的记录并按高度排序，to<=0 表示不设上界。
+func InRange(records []storage.MetricRecord, from, to int) []storage.MetricRecord {
+	out := make([]storage.MetricRecord, 0, len(records))
+	for _, r := range records {
+		if r.Height < from || (to > 0 && r.Height > to) {
+			continue
+		}
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Height < out[j].Height })
+	return out
+}
+
+// Summarize 计算记录的时延分位数与吞吐量。
+func Summarize(records []storage.MetricRecord) Summary {
+	var sum Summary
+	if len(records) == 0 {
+		return sum
+	}
+	h := histogram.New()
+	first := records[0].RecordedAt - int64(records[0].Latency*1e9)
+	last := records[0].RecordedAt
+	sum.FromHeight, sum.ToHeight = records[0].Height, records[0].Height
+	for _, r := range records {
+		h.RecordDuration(time.Duration(r.Latency * float64(time.Second)))
+		sum.Txs += r.Batch
+		if start := r.RecordedAt - int64(r.Latency*1e9); start < first {
+			first = start
+		}
+		if r.RecordedAt > last {
+			last = r.RecordedAt
+		}
+		if r.Height < sum.FromHeight {
+			sum.FromHeight = r.Height
+		}
+		if r.Height > sum.ToHeight {
+			sum.ToHeight = r.Height
+		}
+	}
+	sum.Records = len(records)
+	sum.Span = float64(last-first) / 1e9
+	if sum.Span > 0 {
+		sum.Throughput = float64(sum.Txs) / sum.Span
+	}
+	sum.Latency = h.Summary()
+	return sum
+}
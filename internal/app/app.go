@@ -0,0 +1,127 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"mybft/internal/storage"
+	"mybft/internal/workload"
+)
+
+const (
+	CodeOK        = 0
+	CodeMalformed = 1
+	CodeRejected  = 2
+)
+
+// Application 是共识与执行之间的 ABCI 风格接口：共识引擎只负责排序，
+// 交易校验、提案构造与执行都交给应用完成。
+type Application interface {
+	// Info 返回应用名称与最近一次 Commit 的高度和 app hash。
+	Info() Info
+	// CheckTx 在交易进入交易池前做无状态与已提交状态上的检查。
+	CheckTx(tx string) CheckTxResult
+	// PrepareProposal 由 leader 调用，从候选交易中挑出可执行的部分组成提案。
+	PrepareProposal(req ProposalRequest) []string
+	// ProcessProposal 由副本在投票前调用，判断提案能否在待提交状态上完整执行。
+	ProcessProposal(req ProposalRequest) bool
+	// FinalizeBlock 执行已提交区块，结果暂存到 Commit。
+	FinalizeBlock(req FinalizeBlockRequest) (FinalizeBlockResult, error)
+	// Commit 持久化 FinalizeBlock 的结果并返回该高度的 app hash。
+	Commit() (storage.StateRootRecord, error)
+	// StateRoot 查询指定高度提交后的 app hash 记录。
+	StateRoot(height int) (storage.StateRootRecord, error)
+	// ExportState / RestoreState 用于快照状态同步。
+	ExportState() (json.RawMessage, error)
+	RestoreState(height int, blockID string, state json.RawMessage, appHash string) error
+}
+
+// LoadGenerator 由支持模拟负载的应用实现，在没有外部交易时为 leader 生成交易。
+type LoadGenerator interface {
+	GenerateTxs(height, count int, pending [][]string) []string
+}
+
+// Speculator 由支持推测执行的应用实现：未提交区块在父块的推测状态上执行一次，
+// 提交时直接落账该结果，投票前校验与提交执行不必各自重放整条待提交链。
+type Speculator interface {
+	// Speculate 在 parent（nil 为已提交状态）之上严格执行整批交易，任一笔失败返回 false。
+	Speculate(parent SpecState, txs []string) (SpecState, bool)
+	// FinalizeSpeculative 以推测状态作为 FinalizeBlock 的结果，随后的 Commit 直接落账；
+	// st 必须直接叠加在当前已提交状态之上。
+	FinalizeSpeculative(req FinalizeBlockRequest, st SpecState) (FinalizeBlockResult, error)
+}
+
+// SpecState 是应用私有的单个区块推测状态，调用方只负责按区块保存、转移与丢弃。
+type SpecState interface {
+	// Rebase 在父状态提交后改为直接叠加在已提交状态之上。
+	Rebase()
+}
+
+// Querier 由支持只读查询的应用实现，查询基于最近一次 Commit 后的已提交状态。
+type Querier interface {
+	Query(path, data string) (json.RawMessage, error)
+}
+
+// ErrUnknownQuery 表示应用不支持该查询路径。
+var ErrUnknownQuery = errors.New("unknown query path")
+
+type Info struct {
+	Name       string `json:"name"`
+	LastHeight int    `json:"last_height"`
+	AppHash    string `json:"app_hash"`
+}
+
+type CheckTxResult struct {
+	Code     int    `json:"code"`
+	Log      string `json:"log,omitempty"`
+	Sender   string `json:"sender,omitempty"`
+	Nonce    int    `json:"nonce,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+func (r CheckTxResult) OK() bool { return r.Code == CodeOK }
+
+// ProposalRequest 描述一个待构造或待校验的提案；Pending 为父链上尚未提交区块的交易（由旧到新）。
+type ProposalRequest struct {
+	Height  int
+	BlockID string
+	Txs     []string
+	Pending [][]string
+	MaxTxs  int
+}
+
+type FinalizeBlockRequest struct {
+	Height  int
+	BlockID string
+	Txs     []string
+}
+
+type TxResult struct {
+	Code int    `json:"code"`
+	Log  string `json:"log,omitempty"`
+}
+
+type FinalizeBlockResult struct {
+	TxResults []TxResult
+	Applied   int
+	Skipped   int
+}
+
+// Stores 汇总应用可用的持久化存储。
+type Stores struct {
+	Ledger storage.LedgerStore
+	KV     storage.KVStore
+}
+
+// New 按名称创建应用：transfer（默认，模拟转账账本）或 kvstore；gen 用于生成模拟负载。
+func New(name string, stores Stores, gen *workload.Generator) (Application, error) {
+	switch name {
+	case "", "transfer":
+		return NewTransferApp(stores.Ledger, gen)
+	case "kvstore":
+		return NewKVStoreApp(stores.KV, gen)
+	default:
+		return nil, fmt.Errorf("unknown app: %s", name)
+	}
+}
@@ -0,0 +1,283 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
+	"mybft/internal/common"
+	"mybft/internal/storage"
+	"mybft/internal/workload"
+)
+
+// KVStoreApp 是简单的键值应用，交易格式为 "set <key> <value>" 或 "del <key>"。
+type KVStoreApp struct {
+	mu      sync.RWMutex
+	gen     *workload.Generator
+	store   storage.KVStore
+	entries map[string]string
+	last    storage.StateRootRecord
+	staged  *stagedKV
+}
+
+type kvOp struct {
+	del   bool
+	key   string
+	value string
+}
+
+type stagedKV struct {
+	height  int
+	blockID string
+	writes  map[string]*string
+	result  FinalizeBlockResult
+}
+
+func NewKVStoreApp(store storage.KVStore, gen *workload.Generator) (*KVStoreApp, error) {
+	if gen == nil {
+		gen = workload.New(workload.DefaultConfig())
+	}
+	a := &KVStoreApp{gen: gen, store: store, entries: map[string]string{}}
+	if store != nil {
+		entries, err := store.LoadKV()
+		if err != nil {
+			return nil, err
+		}
+		a.entries = entries
+		last, err := store.LoadLatestStateRoot()
+		if err != nil && !errors.Is(err, goleveldb.ErrNotFound) {
+			return nil, err
+		}
+		a.last = last
+	}
+	if a.last.Root == "" {
+		a.last.Root = kvHash(a.entries)
+	}
+	return a, nil
+}
+
+func parseKVTx(line string) (kvOp, bool) {
+	parts := strings.Fields(line)
+	switch {
+	case len(parts) == 3 && parts[0] == "set":
+		return kvOp{key: parts[1], value: parts[2]}, true
+	case len(parts) == 2 && parts[0] == "del":
+		return kvOp{del: true, key: parts[1]}, true
+	}
+	return kvOp{}, false
+}
+
+func (a *KVStoreApp) Info() Info {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return Info{Name: "kvstore", LastHeight: a.last.Height, AppHash: a.last.Root}
+}
+
+// CheckTx 只做格式检查；kv 交易没有 nonce，Sender 取交易 ID，使同一键上的多笔写入可同时待打包。
+func (a *KVStoreApp) CheckTx(line string) CheckTxResult {
+	if _, ok := parseKVTx(line); !ok {
+		return CheckTxResult{Code: CodeMalformed, Log: "malformed kv tx"}
+	}
+	return CheckTxResult{Code: CodeOK, Sender: "kv:" + common.TxID(line)}
+}
+
+func (a *KVStoreApp) PrepareProposal(req ProposalRequest) []string {
+	out := make([]string, 0, len(req.Txs))
+	for _, line := range req.Txs {
+		if req.MaxTxs > 0 && len(out) >= req.MaxTxs {
+			break
+		}
+		if _, ok := parseKVTx(line); ok {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func (a *KVStoreApp) ProcessProposal(req ProposalRequest) bool {
+	for _, line := range req.Txs {
+		if _, ok := parseKVTx(line); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// kvSpec 是键值应用的推测状态：写入不依赖已有值，只需保存本块合并后的写集合。
+type kvSpec struct {
+	writes map[string]*string
+}
+
+func (st *kvSpec) Rebase() {}
+
+func (a *KVStoreApp) Speculate(_ SpecState, txs []string) (SpecState, bool) {
+	writes, result := kvWrites(txs)
+	if result.Skipped > 0 {
+		return nil, false
+	}
+	return &kvSpec{writes: writes}, true
+}
+
+func (a *KVStoreApp) FinalizeSpeculative(req FinalizeBlockRequest, st SpecState) (FinalizeBlockResult, error) {
+	spec, ok := st.(*kvSpec)
+	if !ok {
+		return FinalizeBlockResult{}, fmt.Errorf("unexpected speculative state %T", st)
+	}
+	a.mu.RLock()
+	last := a.last.Height
+	a.mu.RUnlock()
+	if req.Height != last+1 {
+		return FinalizeBlockResult{}, fmt.Errorf("height %d does not follow last committed height %d", req.Height, last)
+	}
+	result := FinalizeBlockResult{TxResults: make([]TxResult, len(req.Txs)), Applied: len(req.Txs)}
+	a.staged = &stagedKV{height: req.Height, blockID: req.BlockID, writes: spec.writes, result: result}
+	return result, nil
+}
+
+func (a *KVStoreApp) FinalizeBlock(req FinalizeBlockRequest) (FinalizeBlockResult, error) {
+	a.mu.RLock()
+	last := a.last.Height
+	a.mu.RUnlock()
+	if req.Height != last+1 {
+		return FinalizeBlockResult{}, fmt.Errorf("height %d does not follow last committed height %d", req.Height, last)
+	}
+	writes, result := kvWrites(req.Txs)
+	a.staged = &stagedKV{height: req.Height, blockID: req.BlockID, writes: writes, result: result}
+	return result, nil
+}
+
+// kvWrites 宽松解析整批交易：格式错误的交易跳过并记录结果码，其余按顺序合并为写集合。
+func kvWrites(txs []string) (map[string]*string, FinalizeBlockResult) {
+	writes := map[string]*string{}
+	result := FinalizeBlockResult{TxResults: make([]TxResult, 0, len(txs))}
+	for _, line := range txs {
+		op, ok := parseKVTx(line)
+		if !ok {
+			result.TxResults = append(result.TxResults, TxResult{Code: CodeMalformed, Log: "malformed kv tx"})
+			result.Skipped++
+			continue
+		}
+		if op.del {
+			writes[op.key] = nil
+		} else {
+			v := op.value
+			writes[op.key] = &v
+		}
+		result.TxResults = append(result.TxResults, TxResult{Code: CodeOK})
+		result.Applied++
+	}
+	return writes, result
+}
+
+func (a *KVStoreApp) Commit() (storage.StateRootRecord, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.staged == nil {
+		return a.last, nil
+	}
+	st := a.staged
+	a.staged = nil
+	sets := map[string]string{}
+	deletes := make([]string, 0)
+	for k, v := range st.writes {
+		if v == nil {
+			delete(a.entries, k)
+			deletes = append(deletes, k)
+			continue
+		}
+		a.entries[k] = *v
+		sets[k] = *v
+	}
+	root := storage.StateRootRecord{
+		Height:    st.height,
+		BlockID:   st.blockID,
+		Root:      kvHash(a.entries),
+		Applied:   st.result.Applied,
+		Skipped:   st.result.Skipped,
+		CreatedAt: time.Now().UnixNano(),
+	}
+	if a.store != nil {
+		if err := a.store.SaveKVBlock(sets, deletes, root); err != nil {
+			return root, err
+		}
+	}
+	a.last = root
+	return root, nil
+}
+
+func (a *KVStoreApp) StateRoot(height int) (storage.StateRootRecord, error) {
+	if a.store == nil {
+		return storage.StateRootRecord{}, goleveldb.ErrNotFound
+	}
+	return a.store.LoadStateRoot(height)
+}
+
+func (a *KVStoreApp) ExportState() (json.RawMessage, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return json.Marshal(a.entries)
+}
+
+// Query 支持 key（data 为键名），返回 {"key","value","found"}。
+func (a *KVStoreApp) Query(path, data string) (json.RawMessage, error) {
+	if path != "key" {
+		return nil, ErrUnknownQuery
+	}
+	a.mu.RLock()
+	value, found := a.entries[data]
+	a.mu.RUnlock()
+	return json.Marshal(struct {
+		Key   string `json:"key"`
+		Value string `json:"value,omitempty"`
+		Found bool   `json:"found"`
+	}{data, value, found})
+}
+
+func (a *KVStoreApp) RestoreState(height int, blockID string, raw json.RawMessage, appHash string) error {
+	entries := map[string]string{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+	root := storage.StateRootRecord{Height: height, BlockID: blockID, Root: kvHash(entries), CreatedAt: time.Now().UnixNano()}
+	if appHash != "" && root.Root != appHash {
+		return fmt.Errorf("state root mismatch at height %d: got %s want %s", height, root.Root, appHash)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.store != nil {
+		if err := a.store.ReplaceKV(entries, root); err != nil {
+			return err
+		}
+	}
+	a.entries = entries
+	a.last = root
+	a.staged = nil
+	return nil
+}
+
+// GenerateTxs 由负载生成器按访问分布生成键值写入。
+func (a *KVStoreApp) GenerateTxs(height, count int, _ [][]string) []string {
+	return a.gen.KVWrites(height, count)
+}
+
+// app hash：按键排序后对 key=value 序列做 SHA-256。
+func kvHash(entries map[string]string) string {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, entries[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"mybft/internal/ledger"
+	"mybft/internal/storage"
+	"mybft/internal/workload"
+)
+
+// TransferApp 是基于持久化账本的模拟转账应用（默认应用）。
+type TransferApp struct {
+	ledger *ledger.Ledger
+	gen    *workload.Generator
+	staged *stagedTransfer
+}
+
+type stagedTransfer struct {
+	height  int
+	blockID string
+	overlay *ledger.Overlay
+	result  FinalizeBlockResult
+}
+
+type transferState struct {
+	Balances map[int]int `json:"balances"`
+	Nonces   map[int]int `json:"nonces"`
+}
+
+func NewTransferApp(store storage.LedgerStore, gen *workload.Generator) (*TransferApp, error) {
+	lg, err := ledger.Open(store)
+	if err != nil {
+		return nil, err
+	}
+	if gen == nil {
+		gen = workload.New(workload.DefaultConfig())
+	}
+	return &TransferApp{ledger: lg, gen: gen}, nil
+}
+
+func (a *TransferApp) Info() Info {
+	last := a.ledger.LastRoot()
+	return Info{Name: "transfer", LastHeight: last.Height, AppHash: last.Root}
+}
+
+// CheckTx 检查字段合法、nonce 未被已提交状态使用、余额足以支付 amount+fee。
+func (a *TransferApp) CheckTx(line string) CheckTxResult {
+	tx, ok := ledger.ParseTx(line)
+	if !ok {
+		return CheckTxResult{Code: CodeMalformed, Log: ledger.ErrMalformedTx.Error()}
+	}
+	res := CheckTxResult{Sender: fmt.Sprintf("%d", tx.From), Nonce: tx.Nonce, Priority: tx.Fee}
+	balance, nonce, err := a.ledger.CheckCommitted(tx)
+	if err != nil {
+		res.Code, res.Log = CodeRejected, err.Error()
+		return res
+	}
+	if tx.Nonce <= nonce {
+		res.Code, res.Log = CodeRejected, ledger.ErrBadNonce.Error()
+		return res
+	}
+	if balance < tx.Amount+tx.Fee {
+		res.Code, res.Log = CodeRejected, ledger.ErrInsufficientBalance.Error()
+	}
+	return res
+}
+
+func (a *TransferApp) pendingOverlay(pending [][]string) *ledger.Overlay {
+	ov := a.ledger.NewOverlay()
+	for _, txs := range pending {
+		ov.ApplyBlock(txs)
+	}
+	return ov
+}
+
+// PrepareProposal 在待提交状态上依次试执行候选交易，保留可执行的部分。
+func (a *TransferApp) PrepareProposal(req ProposalRequest) []string {
+	ov := a.pendingOverlay(req.Pending)
+	out := make([]string, 0, len(req.Txs))
+	for _, line := range req.Txs {
+		if req.MaxTxs > 0 && len(out) >= req.MaxTxs {
+			break
+		}
+		if ov.ExecLine(line) == nil {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func (a *TransferApp) ProcessProposal(req ProposalRequest) bool {
+	return a.pendingOverlay(req.Pending).ExecBatch(req.Txs) == nil
+}
+
+// transferSpec 是转账应用的推测状态：叠加在父块视图或已提交账本上的写时复制视图。
+type transferSpec struct {
+	ledger  *ledger.Ledger
+	overlay *ledger.Overlay
+}
+
+func (st *transferSpec) Rebase() { st.overlay.Rebase(st.ledger) }
+
+func (a *TransferApp) Speculate(parent SpecState, txs []string) (SpecState, bool) {
+	var ov *ledger.Overlay
+	if p, ok := parent.(*transferSpec); ok && p != nil {
+		ov = p.overlay.Fork()
+	} else {
+		ov = a.ledger.NewOverlay()
+	}
+	if ov.ExecBatch(txs) != nil {
+		return nil, false
+	}
+	return &transferSpec{ledger: a.ledger, overlay: ov}, true
+}
+
+// FinalizeSpeculative 推测执行已严格校验整批交易，结果码全部为成功。
+func (a *TransferApp) FinalizeSpeculative(req FinalizeBlockRequest, st SpecState) (FinalizeBlockResult, error) {
+	spec, ok := st.(*transferSpec)
+	if !ok {
+		return FinalizeBlockResult{}, fmt.Errorf("unexpected speculative state %T", st)
+	}
+	if last := a.ledger.LastRoot(); req.Height != last.Height+1 {
+		return FinalizeBlockResult{}, fmt.Errorf("height %d does not follow last committed height %d", req.Height, last.Height)
+	}
+	result := FinalizeBlockResult{TxResults: make([]TxResult, len(req.Txs)), Applied: len(req.Txs)}
+	a.staged = &stagedTransfer{height: req.Height, blockID: req.BlockID, overlay: spec.overlay, result: result}
+	return result, nil
+}
+
+// FinalizeBlock 在已提交状态上宽松执行区块：非法交易跳过并记录结果码。
+func (a *TransferApp) FinalizeBlock(req FinalizeBlockRequest) (FinalizeBlockResult, error) {
+	if last := a.ledger.LastRoot(); req.Height != last.Height+1 {
+		return FinalizeBlockResult{}, fmt.Errorf("height %d does not follow last committed height %d", req.Height, last.Height)
+	}
+	ov := a.ledger.NewOverlay()
+	result := FinalizeBlockResult{TxResults: make([]TxResult, 0, len(req.Txs))}
+	for _, line := range req.Txs {
+		if err := ov.ExecLine(line); err != nil {
+			result.TxResults = append(result.TxResults, TxResult{Code: CodeRejected, Log: err.Error()})
+			result.Skipped++
+			continue
+		}
+		result.TxResults = append(result.TxResults, TxResult{Code: CodeOK})
+		result.Applied++
+	}
+	a.staged = &stagedTransfer{height: req.Height, blockID: req.BlockID, overlay: ov, result: result}
+	return result, nil
+}
+
+func (a *TransferApp) Commit() (storage.StateRootRecord, error) {
+	if a.staged == nil {
+		return a.ledger.LastRoot(), nil
+	}
+	st := a.staged
+	a.staged = nil
+	return a.ledger.Commit(st.height, st.blockID, st.overlay, st.result.Applied, st.result.Skipped)
+}
+
+func (a *TransferApp) StateRoot(height int) (storage.StateRootRecord, error) {
+	return a.ledger.Root(height)
+}
+
+func (a *TransferApp) ExportState() (json.RawMessage, error) {
+	balances, nonces := a.ledger.Export()
+	return json.Marshal(transferState{Balances: balances, Nonces: nonces})
+}
+
+func (a *TransferApp) RestoreState(height int, blockID string, raw json.RawMessage, appHash string) error {
+	var state transferState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	a.staged = nil
+	_, err := a.ledger.Restore(height, blockID, state.Balances, state.Nonces, appHash)
+	return err
+}
+
+// Query 支持 account（data 为账户编号）与 accounts（全部非创世账户的余额与 nonce）。
+func (a *TransferApp) Query(path, data string) (json.RawMessage, error) {
+	switch path {
+	case "account":
+		id, err := strconv.Atoi(data)
+		if err != nil || id < 1 || id > ledger.AccountCount {
+			return nil, fmt.Errorf("invalid account id: %q", data)
+		}
+		return json.Marshal(a.ledger.Account(id))
+	case "accounts":
+		return a.ExportState()
+	}
+	return nil, ErrUnknownQuery
+}
+
+// GenerateTxs 由负载生成器在待提交状态上续写转账，保证可被账本执行且同一种子可复现。
+func (a *TransferApp) GenerateTxs(height, count int, pending [][]string) []string {
+	return a.gen.Transfers(height, count, a.pendingOverlay(pending))
+}
@@ -0,0 +1,158 @@
+package batching
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"mybft/internal/common"
+)
+
+const (
+	ModeFixed    = "fixed"
+	ModeAdaptive = "adaptive"
+)
+
+type Config struct {
+	Mode          string
+	MaxTxs        int
+	MinTxs        int
+	MaxBytes      int
+	MaxWait       time.Duration
+	TargetLatency time.Duration
+}
+
+// Limits 为某次提案实际生效的上限。
+type Limits struct {
+	MaxTxs   int
+	MaxBytes int
+	MaxWait  time.Duration
+}
+
+// ConfigFromEnv 读取批策略配置：
+// MYBFT_BATCH_MODE=fixed|adaptive、MYBFT_BATCH_MAX_TX、MYBFT_BATCH_MIN_TX、
+// MYBFT_BATCH_MAX_BYTES、MYBFT_BATCH_MAX_WAIT_MS、MYBFT_BATCH_TARGET_LATENCY_MS。
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Mode:          ModeFixed,
+		MaxTxs:        1000,
+		MinTxs:        10,
+		MaxBytes:      1 << 20,
+		TargetLatency: 500 * time.Millisecond,
+	}
+	if mode := os.Getenv("MYBFT_BATCH_MODE"); mode == ModeAdaptive {
+		cfg.Mode = ModeAdaptive
+	}
+	cfg.MaxTxs = envInt("MYBFT_BATCH_MAX_TX", cfg.MaxTxs)
+	cfg.MinTxs = envInt("MYBFT_BATCH_MIN_TX", cfg.MinTxs)
+	cfg.MaxBytes = envInt("MYBFT_BATCH_MAX_BYTES", cfg.MaxBytes)
+	cfg.MaxWait = time.Duration(envInt("MYBFT_BATCH_MAX_WAIT_MS", 0)) * time.Millisecond
+	cfg.TargetLatency = time.Duration(envInt("MYBFT_BATCH_TARGET_LATENCY_MS", int(cfg.TargetLatency/time.Millisecond))) * time.Millisecond
+	if cfg.MinTxs > cfg.MaxTxs {
+		cfg.MinTxs = cfg.MaxTxs
+	}
+	return cfg
+}
+
+func envInt(key string, def int) int {
+	if raw := os.Getenv(key); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return def
+}
+
+// Policy 决定提案批大小。fixed 模式始终使用 MaxTxs；adaptive 模式按观测到的
+// 提案到提交时延做加性增、乘性减（AIMD），使时延逼近 TargetLatency。
+type Policy struct {
+	mu      sync.Mutex
+	cfg     Config
+	current int
+}
+
+func NewPolicy(cfg Config) *Policy {
+	current := cfg.MaxTxs
+	if cfg.Mode == ModeAdaptive {
+		current = cfg.MinTxs
+		if current < 1 {
+			current = 1
+		}
+	}
+	return &Policy{cfg: cfg, current: current}
+}
+
+func (p *Policy) Limits() Limits {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Limits{MaxTxs: p.current, MaxBytes: p.cfg.MaxBytes, MaxWait: p.cfg.MaxWait}
+}
+
+// Observe 记录一次本节点提案从发出到提交的时延，adaptive 模式下据此调整批大小。
+func (p *Policy) Observe(latency time.Duration) {
+	if p.cfg.Mode != ModeAdaptive {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if latency <= p.cfg.TargetLatency {
+		step := p.current / 10
+		if step < 1 {
+			step = 1
+		}
+		p.current += step
+		if p.current > p.cfg.MaxTxs {
+			p.current = p.cfg.MaxTxs
+		}
+		return
+	}
+	p.current /= 2
+	if p.current < p.cfg.MinTxs {
+		p.current = p.cfg.MinTxs
+	}
+	if p.current < 1 {
+		p.current = 1
+	}
+}
+
+// Info 生成随 /start 上报的批信息；limits 为构造该提案时取得的上限，
+// adaptive 模式下此后的 Observe 不影响上报值。
+func (p *Policy) Info(limits Limits, txs []string) common.BatchInfo {
+	return common.BatchInfo{
+		Size:            len(txs),
+		Bytes:           Bytes(txs),
+		Mode:            p.cfg.Mode,
+		MaxTxs:          limits.MaxTxs,
+		MaxBytes:        limits.MaxBytes,
+		MaxWaitMs:       limits.MaxWait.Milliseconds(),
+		TargetLatencyMs: p.cfg.TargetLatency.Milliseconds(),
+	}
+}
+
+// Truncate 按笔数与字节数上限截断交易列表（每笔按文本长度加换行计）。
+func Truncate(txs []string, maxTxs, maxBytes int) []string {
+	if maxTxs > 0 && len(txs) > maxTxs {
+		txs = txs[:maxTxs]
+	}
+	if maxBytes <= 0 {
+		return txs
+	}
+	total := 0
+	for i, tx := range txs {
+		total += len(tx) + 1
+		if total > maxBytes {
+			return txs[:i]
+		}
+	}
+	return txs
+}
+
+// Bytes 统计交易列表的字节数。
+func Bytes(txs []string) int {
+	total := 0
+	for _, tx := range txs {
+		total += len(tx) + 1
+	}
+	return total
+}
@@ -0,0 +1,157 @@
+package bench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"mybft/internal/histogram"
+	leveldbstore "mybft/internal/storage/leveldb"
+)
+
+// Row 是比较表中的一行：同一 (算法, N, 批大小, 时延) 配置在多次运行上的汇总。
+type Row struct {
+	Alg               string
+	N                 int
+	Batch             int
+	DelayMs           int
+	Runs              int
+	Failed            int
+	Heights           int
+	MeanLatency       float64
+	P99Latency        float64
+	Throughput        float64
+	MessagesPerCommit float64
+	BytesPerCommit    float64
+}
+
+type rowKey struct {
+	alg             string
+	n, batch, delay int
+}
+
+type rowAcc struct {
+	row      Row
+	latency  *histogram.Histogram
+	txs      int
+	span     float64
+	messages int64
+	bytes    int64
+}
+
+// Compare 按配置分组汇总结果。时延分位数由各次运行 client MetricsStore 中的逐高度记录合并计算，
+// 吞吐量为总交易数除以总提交时长，消息与字节数按提交高度平均。
+func Compare(results []Result) []Row {
+	groups := map[rowKey]*rowAcc{}
+	var keys []rowKey
+	for _, r := range results {
+		key := rowKey{alg: r.Alg, n: r.N, batch: r.Batch, delay: r.DelayMs}
+		acc, ok := groups[key]
+		if !ok {
+			acc = &rowAcc{row: Row{Alg: r.Alg, N: r.N, Batch: r.Batch, DelayMs: r.DelayMs}, latency: histogram.New()}
+			groups[key] = acc
+			keys = append(keys, key)
+		}
+		acc.row.Runs++
+		if r.Error != "" || r.Heights == 0 {
+			acc.row.Failed++
+			continue
+		}
+		if err := loadLatencies(r.WorkDir, r.RunID, acc.latency); err != nil {
+			log.Printf("bench report workdir=%s: %v", r.WorkDir, err)
+		}
+		acc.row.Heights += r.Heights
+		acc.txs += r.Txs
+		acc.span += r.Span
+		acc.messages += r.Messages
+		acc.bytes += r.Bytes
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.n != b.n {
+			return a.n < b.n
+		}
+		if a.batch != b.batch {
+			return a.batch < b.batch
+		}
+		if a.delay != b.delay {
+			return a.delay < b.delay
+		}
+		return a.alg < b.alg
+	})
+	rows := make([]Row, 0, len(keys))
+	for _, key := range keys {
+		acc := groups[key]
+		row := acc.row
+		sum := acc.latency.Summary()
+		row.MeanLatency, row.P99Latency = sum.Mean, sum.P99
+		if acc.span > 0 {
+			row.Throughput = float64(acc.txs) / acc.span
+		}
+		if row.Heights > 0 {
+			row.MessagesPerCommit = float64(acc.messages) / float64(row.Heights)
+			row.BytesPerCommit = float64(acc.bytes) / float64(row.Heights)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func loadLatencies(workDir, runID string, h *histogram.Histogram) error {
+	stores, err := leveldbstore.OpenClientStores(filepath.Join(workDir, "data"))
+	if err != nil {
+		return err
+	}
+	defer stores.Close()
+	records, err := stores.Metrics(runID).ListMetrics()
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		h.RecordDuration(time.Duration(r.Latency * float64(time.Second)))
+	}
+	return nil
+}
+
+func batchLabel(batch int) string {
+	if batch == 0 {
+		return "default"
+	}
+	return strconv.Itoa(batch)
+}
+
+// WriteMarkdown 写出 Markdown 比较表，时延单位为毫秒。
+func WriteMarkdown(w io.Writer, rows []Row) error {
+	if _, err := fmt.Fprintln(w, "| alg | N | batch | delay (ms) | runs | failed | heights | mean latency (ms) | p99 latency (ms) | throughput (tx/s) | msgs/commit | bytes/commit |"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|"); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if _, err := fmt.Fprintf(w, "| %s | %d | %s | %d | %d | %d | %d | %.1f | %.1f | %.1f | %.1f | %.0f |\n",
+			r.Alg, r.N, batchLabel(r.Batch), r.DelayMs, r.Runs, r.Failed, r.Heights,
+			r.MeanLatency*1000, r.P99Latency*1000, r.Throughput, r.MessagesPerCommit, r.BytesPerCommit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRowsCSV 写出与 Markdown 相同列的 CSV，时延单位为秒。
+func WriteRowsCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"alg", "n", "batch", "delay_ms", "runs", "failed", "heights", "mean_latency", "p99_latency", "throughput", "messages_per_commit", "bytes_per_commit"})
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	for _, r := range rows {
+		_ = cw.Write([]string{r.Alg, strconv.Itoa(r.N), strconv.Itoa(r.Batch), strconv.Itoa(r.DelayMs), strconv.Itoa(r.Runs), strconv.Itoa(r.Failed), strconv.Itoa(r.Heights),
+			f(r.MeanLatency), f(r.P99Latency), f(r.Throughput), f(r.MessagesPerCommit), f(r.BytesPerCommit)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,104 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mybft/internal/storage"
+)
+
+// Result 是一次运行的汇总，时延单位为秒。
+type Result struct {
+	Label      string                 `json:"label,omitempty"`
+	Alg        string                 `json:"alg"`
+	N          int                    `json:"n"`
+	Batch      int                    `json:"batch,omitempty"`
+	DelayMs    int                    `json:"delay_ms,omitempty"`
+	Repeat     int                    `json:"repeat,omitempty"`
+	Duration   float64                `json:"duration"`
+	Faults     string                 `json:"faults,omitempty"`
+	Env        map[string]string      `json:"env,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	Heights    int                    `json:"heights"`
+	Txs        int                    `json:"txs"`
+	Span       float64                `json:"span"`
+	Throughput float64                `json:"throughput"`
+	Latency    storage.LatencySummary `json:"latency"`
+	// Messages/Bytes 为各节点发送的共识消息数与字节数之和：存活节点在结束时抓取，崩溃节点在注入故障前抓取。
+	// TrafficPartial 表示有节点的计数未能抓取，两者偏小。
+	Messages          int64   `json:"messages"`
+	Bytes             int64   `json:"bytes"`
+	TrafficPartial    bool    `json:"traffic_partial,omitempty"`
+	MessagesPerCommit float64 `json:"messages_per_commit"`
+	BytesPerCommit    float64 `json:"bytes_per_commit"`
+	WorkDir           string  `json:"work_dir"`
+	RunID             string  `json:"run_id"`
+	Error             string  `json:"error,omitempty"`
+}
+
+var csvHeader = []string{"label", "alg", "n", "batch", "delay_ms", "repeat", "duration", "faults", "env", "heights", "txs", "span", "throughput",
+	"latency_min", "latency_mean", "latency_p50", "latency_p90", "latency_p99", "latency_p999", "latency_max", "messages", "bytes", "messages_per_commit", "bytes_per_commit", "error"}
+
+func (r Result) csvRow() []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	keys := make([]string, 0, len(r.Env))
+	for k := range r.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make([]string, len(keys))
+	for i, k := range keys {
+		env[i] = k + "=" + r.Env[k]
+	}
+	return []string{r.Label, r.Alg, strconv.Itoa(r.N), strconv.Itoa(r.Batch), strconv.Itoa(r.DelayMs), strconv.Itoa(r.Repeat), f(r.Duration), r.Faults, strings.Join(env, " "),
+		strconv.Itoa(r.Heights), strconv.Itoa(r.Txs), f(r.Span), f(r.Throughput),
+		f(r.Latency.Min), f(r.Latency.Mean), f(r.Latency.P50), f(r.Latency.P90), f(r.Latency.P99), f(r.Latency.P999), f(r.Latency.Max),
+		strconv.FormatInt(r.Messages, 10), strconv.FormatInt(r.Bytes, 10), f(r.MessagesPerCommit), f(r.BytesPerCommit), r.Error}
+}
+
+// ReadResults 读取 WriteResults 写出的 JSON 结果文件。
+func ReadResults(path string) ([]Result, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// WriteResults 按扩展名写出结果：.csv 为带表头的 CSV，其余为 JSON 数组。
+func WriteResults(path string, results []Result) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		w := csv.NewWriter(f)
+		if err := w.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, r := range results {
+			if err := w.Write(r.csvRow()); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
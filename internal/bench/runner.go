@@ -0,0 +1,341 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"mybft/internal/analysis"
+	"mybft/internal/redisx"
+	leveldbstore "mybft/internal/storage/leveldb"
+	"mybft/internal/tlsx"
+)
+
+const (
+	clientAddr   = "127.0.0.1:8000"
+	nodeBasePort = 9000
+)
+
+// Build 把 genkey/client/node 编译到 binDir，需在仓库根目录执行。
+func Build(binDir string) error {
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		return err
+	}
+	out, err := exec.Command("go", "build", "-o", binDir+string(os.PathSeparator), "./cmd/genkey", "./cmd/client", "./cmd/node").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go build: %w: %s", err, out)
+	}
+	return nil
+}
+
+func binary(dir, name string) string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(dir, name)
+}
+
+type cluster struct {
+	spec  Spec
+	mu    sync.Mutex
+	nodes map[int]*exec.Cmd
+	// crashed 为崩溃注入前抓取到的发送计数，结束时与存活节点的计数相加；trafficPartial 表示有节点未能抓取。
+	crashed        traffic
+	trafficPartial bool
+	// http 用于读取 client 与节点的 /metrics，开启 TLS 时出示 client 证书。
+	http *http.Client
+}
+
+// Run 在本机启动 genkey、client 与 N 个节点，运行到时长或目标高度后回收进程并汇总结果。
+func Run(spec Spec) (Result, error) {
+	result := Result{
+		Label:    spec.Label,
+		Alg:      spec.Alg,
+		N:        spec.N,
+		Batch:    spec.Batch,
+		DelayMs:  int(spec.Delay / time.Millisecond),
+		Repeat:   spec.Repeat,
+		Duration: spec.Duration.Seconds(),
+		Env:      spec.Env,
+		WorkDir:  spec.WorkDir,
+		RunID:    spec.runID(),
+	}
+	faults := make([]string, len(spec.Faults))
+	for i, f := range spec.Faults {
+		faults[i] = f.String()
+	}
+	result.Faults = strings.Join(faults, ",")
+	if err := spec.validate(); err != nil {
+		return result, err
+	}
+	if err := os.MkdirAll(filepath.Join(spec.WorkDir, "logs"), 0o755); err != nil {
+		return result, err
+	}
+	if err := redisx.NewClient().Ping(); err != nil {
+		return result, fmt.Errorf("redis unreachable: %w", err)
+	}
+	c := &cluster{spec: spec, nodes: map[int]*exec.Cmd{}}
+	if out, err := c.command("genkey", strconv.Itoa(spec.N)).CombinedOutput(); err != nil {
+		return result, fmt.Errorf("genkey: %w: %s", err, out)
+	}
+	var err error
+	if c.http, err = tlsx.NewHTTPClient(redisx.NewClient(), spec.tls(), 2*time.Second); err != nil {
+		return result, err
+	}
+	client := c.command("client", strconv.Itoa(spec.N))
+	client.Env = append(client.Env, "MYBFT_RUN_ID="+result.RunID)
+	if err := c.start(client, "client.log"); err != nil {
+		return result, err
+	}
+	clientDone := make(chan error, 1)
+	go func() { clientDone <- client.Wait() }()
+	time.Sleep(500 * time.Millisecond)
+
+	result.StartedAt = time.Now()
+	for i := 2; i <= spec.N; i++ {
+		c.startNode(i, false)
+	}
+	c.startNode(1, false)
+	for _, f := range spec.Faults {
+		f := f
+		time.AfterFunc(f.At, func() { c.inject(f) })
+	}
+
+	err = c.wait(clientDone)
+	result.Messages, result.Bytes, result.TrafficPartial = c.scrapeTraffic()
+	c.stopNodes()
+	stop(client.Process)
+	select {
+	case <-clientDone:
+	case <-time.After(5 * time.Second):
+		_ = client.Process.Kill()
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	stores, openErr := leveldbstore.OpenClientStores(filepath.Join(spec.WorkDir, "data"))
+	if openErr != nil {
+		return result, openErr
+	}
+	defer stores.Close()
+	records, listErr := stores.Metrics(result.RunID).ListMetrics()
+	if listErr != nil {
+		return result, listErr
+	}
+	sum := analysis.Summarize(records)
+	result.Heights, result.Txs, result.Span, result.Throughput, result.Latency = sum.Records, sum.Txs, sum.Span, sum.Throughput, sum.Latency
+	if result.Heights > 0 {
+		result.MessagesPerCommit = float64(result.Messages) / float64(result.Heights)
+		result.BytesPerCommit = float64(result.Bytes) / float64(result.Heights)
+	}
+	return result, err
+}
+
+func (c *cluster) command(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(binary(c.spec.BinDir, name), args...)
+	cmd.Dir = c.spec.WorkDir
+	cmd.Env = append(os.Environ(), "MYBFT_DATA_DIR="+filepath.Join(c.spec.WorkDir, "data"))
+	if c.spec.Batch > 0 {
+		cmd.Env = append(cmd.Env, "MYBFT_BATCH_MAX_TX="+strconv.Itoa(c.spec.Batch))
+	}
+	if c.spec.Delay > 0 {
+		cmd.Env = append(cmd.Env, "MYBFT_NET_DELAY_MS="+strconv.Itoa(int(c.spec.Delay/time.Millisecond)))
+	}
+	for k, v := range c.spec.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	return cmd
+}
+
+func (c *cluster) start(cmd *exec.Cmd, logName string) error {
+	f, err := os.OpenFile(filepath.Join(c.spec.WorkDir, "logs", logName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = f
+	cmd.Stderr = f
+	if err := cmd.Start(); err != nil {
+		f.Close()
+		return err
+	}
+	// 子进程持有自己的文件描述符副本，父进程这一侧可以立即关闭。
+	f.Close()
+	return nil
+}
+
+func (c *cluster) startNode(id int, bootstrap bool) {
+	args := []string{strconv.Itoa(id), c.spec.Alg}
+	if bootstrap {
+		args = append(args, "--bootstrap")
+	}
+	cmd := c.command("node", args...)
+	if err := c.start(cmd, fmt.Sprintf("node%d.log", id)); err != nil {
+		log.Printf("bench start node=%d: %v", id, err)
+		return
+	}
+	c.mu.Lock()
+	c.nodes[id] = cmd
+	c.mu.Unlock()
+	go func() { _ = cmd.Wait() }()
+}
+
+func (c *cluster) inject(f Fault) {
+	log.Printf("bench fault=%s", f)
+	switch f.Kind {
+	case FaultCrash:
+		// 先抓取该节点的发送计数再结束进程，否则结束时它的流量不会计入结果。
+		t, ok := c.scrapeNode(f.Node)
+		c.mu.Lock()
+		cmd := c.nodes[f.Node]
+		delete(c.nodes, f.Node)
+		if cmd != nil {
+			c.crashed.add(t)
+			c.trafficPartial = c.trafficPartial || !ok
+		}
+		c.mu.Unlock()
+		if cmd != nil {
+			_ = cmd.Process.Kill()
+		}
+	case FaultRestart, FaultBootstrap:
+		c.mu.Lock()
+		_, running := c.nodes[f.Node]
+		c.mu.Unlock()
+		if running {
+			log.Printf("bench fault=%s skipped: node still running", f)
+			return
+		}
+		c.startNode(f.Node, f.Kind == FaultBootstrap)
+	}
+}
+
+// wait 直到时长耗尽、client 确认目标高度或 client 意外退出。
+func (c *cluster) wait(clientDone <-chan error) error {
+	deadline := time.NewTimer(c.spec.Duration)
+	defer deadline.Stop()
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-deadline.C:
+			return nil
+		case err := <-clientDone:
+			return fmt.Errorf("client exited early: %v", err)
+		case <-tick.C:
+			if c.spec.Heights > 0 && c.clientLastHeight() >= c.spec.Heights {
+				return nil
+			}
+		}
+	}
+}
+
+func (c *cluster) stopNodes() {
+	c.mu.Lock()
+	nodes := c.nodes
+	c.nodes = map[int]*exec.Cmd{}
+	c.mu.Unlock()
+	for _, cmd := range nodes {
+		stop(cmd.Process)
+	}
+}
+
+// stop 先发送 SIGTERM 让进程打印汇总，不支持信号的平台直接结束进程。
+func stop(p *os.Process) {
+	if p == nil {
+		return
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		_ = p.Kill()
+	}
+}
+
+// traffic 为节点 /metrics 中上报的发送消息数与字节数。
+type traffic struct {
+	messages int64
+	bytes    int64
+}
+
+func (t *traffic) add(o traffic) {
+	t.messages += o.messages
+	t.bytes += o.bytes
+}
+
+// scrapeTraffic 汇总仍在运行的节点与已崩溃节点（注入前抓取）的发送消息数与字节数；
+// partial 表示有节点的计数未能抓取，结果偏小。
+func (c *cluster) scrapeTraffic() (messages, bytes int64, partial bool) {
+	c.mu.Lock()
+	ids := make([]int, 0, len(c.nodes))
+	for id := range c.nodes {
+		ids = append(ids, id)
+	}
+	total, partial := c.crashed, c.trafficPartial
+	c.mu.Unlock()
+	for _, id := range ids {
+		t, ok := c.scrapeNode(id)
+		total.add(t)
+		partial = partial || !ok
+	}
+	return total.messages, total.bytes, partial
+}
+
+// scrapeNode 读取单个节点的发送计数，节点无响应时返回 false。
+func (c *cluster) scrapeNode(id int) (traffic, bool) {
+	samples := c.scrape(fmt.Sprintf("127.0.0.1:%d", nodeBasePort+id))
+	if len(samples) == 0 {
+		return traffic{}, false
+	}
+	return traffic{
+		messages: int64(sumPrefix(samples, "mybft_node_messages_sent_total{")),
+		bytes:    int64(sumPrefix(samples, "mybft_node_message_bytes_sent_total{")),
+	}, true
+}
+
+// clientLastHeight 从 client 的 /metrics 读取最近确认的高度。
+func (c *cluster) clientLastHeight() int {
+	return int(c.scrape(clientAddr)["mybft_client_last_height"])
+}
+
+// scrape 读取 addr 上 /metrics 的 Prometheus 文本格式样本，键为指标名加标签。
+func (c *cluster) scrape(addr string) map[string]float64 {
+	samples := map[string]float64{}
+	resp, err := c.http.Get(tlsx.Scheme(c.spec.tls()) + "://" + addr + "/metrics")
+	if err != nil {
+		return samples
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.LastIndexByte(line, ' ')
+		if idx < 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(line[idx+1:], 64); err == nil {
+			samples[line[:idx]] = v
+		}
+	}
+	return samples
+}
+
+func sumPrefix(samples map[string]float64, prefix string) float64 {
+	total := 0.0
+	for k, v := range samples {
+		if strings.HasPrefix(k, prefix) {
+			total += v
+		}
+	}
+	return total
+}
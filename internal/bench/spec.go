@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"mybft/internal/tlsx"
+)
+
+// Spec 描述一次基准实验：算法、规模、时长、负载环境变量与故障注入计划。
+type Spec struct {
+	Label    string
+	Alg      string
+	N        int
+	Duration time.Duration
+	// Heights 大于 0 时，client 确认该高度后提前结束。
+	Heights int
+	// Batch 大于 0 时设置 MYBFT_BATCH_MAX_TX；Delay 大于 0 时设置节点注入时延 MYBFT_NET_DELAY_MS。
+	Batch int
+	Delay time.Duration
+	// Repeat 为扫参中同一配置的第几次运行（从 1 开始）。
+	Repeat int
+	// Env 追加到 client 与各节点进程的环境变量，如 MYBFT_WORKLOAD_SEED、MYBFT_BATCH_MODE。
+	Env    map[string]string
+	Faults []Fault
+	// BinDir 存放 genkey/client/node 可执行文件；WorkDir 存放本次运行的数据与日志。
+	BinDir  string
+	WorkDir string
+}
+
+const (
+	FaultCrash     = "crash"
+	FaultRestart   = "restart"
+	FaultBootstrap = "bootstrap"
+)
+
+// Fault 在运行开始后 At 时刻对节点 Node 执行动作：crash 终止进程，restart 原样重启，bootstrap 以 --bootstrap 重启。
+type Fault struct {
+	Kind string
+	Node int
+	At   time.Duration
+}
+
+func (f Fault) String() string {
+	return fmt.Sprintf("%s:%d@%s", f.Kind, f.Node, f.At)
+}
+
+// ParseFaults 解析逗号分隔的故障计划，例如 "crash:3@10s,bootstrap:3@20s"。
+func ParseFaults(raw string) ([]Fault, error) {
+	var faults []Fault
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kind, rest, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid fault %q: want kind:node@time", item)
+		}
+		switch kind {
+		case FaultCrash, FaultRestart, FaultBootstrap:
+		default:
+			return nil, fmt.Errorf("invalid fault %q: unknown kind %q", item, kind)
+		}
+		nodeRaw, atRaw, ok := strings.Cut(rest, "@")
+		if !ok {
+			return nil, fmt.Errorf("invalid fault %q: want kind:node@time", item)
+		}
+		node, err := strconv.Atoi(nodeRaw)
+		if err != nil || node < 1 {
+			return nil, fmt.Errorf("invalid fault %q: bad node id", item)
+		}
+		at, err := time.ParseDuration(atRaw)
+		if err != nil || at < 0 {
+			return nil, fmt.Errorf("invalid fault %q: bad time", item)
+		}
+		faults = append(faults, Fault{Kind: kind, Node: node, At: at})
+	}
+	return faults, nil
+}
+
+// runID 为 client 的 MYBFT_RUN_ID：优先使用 Env 中给出的值，否则取工作目录名加时间戳。
+func (s Spec) runID() string {
+	if id := s.Env["MYBFT_RUN_ID"]; id != "" {
+		return id
+	}
+	return filepath.Base(s.WorkDir) + "-" + time.Now().Format("20060102-150405")
+}
+
+// tls 判断本次运行的 client 与节点是否启用双向 TLS：Env 中的 MYBFT_TLS 优先于当前进程环境变量。
+func (s Spec) tls() bool {
+	if v, ok := s.Env["MYBFT_TLS"]; ok {
+		return v == "1"
+	}
+	return tlsx.Enabled()
+}
+
+func (s Spec) validate() error {
+	switch s.Alg {
+	case "sbft", "hotstuff", "fast-hotstuff", "hpbft":
+	default:
+		return fmt.Errorf("invalid alg: %s", s.Alg)
+	}
+	if s.N < 1 {
+		return fmt.Errorf("invalid N: %d", s.N)
+	}
+	if s.Duration <= 0 {
+		return fmt.Errorf("duration must be > 0")
+	}
+	for _, f := range s.Faults {
+		if f.Node > s.N {
+			return fmt.Errorf("fault %s: node out of range", f)
+		}
+	}
+	return nil
+}
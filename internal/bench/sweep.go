@@ -0,0 +1,68 @@
+package bench
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// Sweep 在 Base 之上枚举 算法 × N × 批大小 × 注入时延，每个配置运行 Repeats 次。
+type Sweep struct {
+	Base    Spec
+	Algs    []string
+	Ns      []int
+	Batches []int
+	Delays  []time.Duration
+	Repeats int
+}
+
+// Specs 展开为按顺序执行的运行列表，每次运行使用 Base.WorkDir 下独立的子目录。
+func (w Sweep) Specs() []Spec {
+	batches := w.Batches
+	if len(batches) == 0 {
+		batches = []int{0}
+	}
+	delays := w.Delays
+	if len(delays) == 0 {
+		delays = []time.Duration{0}
+	}
+	repeats := w.Repeats
+	if repeats < 1 {
+		repeats = 1
+	}
+	var specs []Spec
+	for _, n := range w.Ns {
+		for _, batch := range batches {
+			for _, delay := range delays {
+				for _, alg := range w.Algs {
+					for r := 1; r <= repeats; r++ {
+						spec := w.Base
+						spec.Alg, spec.N, spec.Batch, spec.Delay, spec.Repeat = alg, n, batch, delay, r
+						spec.WorkDir = filepath.Join(w.Base.WorkDir, fmt.Sprintf("%s-n%d-b%d-d%d-r%d", alg, n, batch, delay/time.Millisecond, r))
+						specs = append(specs, spec)
+					}
+				}
+			}
+		}
+	}
+	return specs
+}
+
+// RunSweep 依次执行所有配置；单次运行失败只记录在结果中，不中断扫参。
+func RunSweep(w Sweep, done func(Result)) []Result {
+	specs := w.Specs()
+	results := make([]Result, 0, len(specs))
+	for i, spec := range specs {
+		log.Printf("bench sweep run=%d/%d alg=%s n=%d batch=%d delay=%s repeat=%d", i+1, len(specs), spec.Alg, spec.N, spec.Batch, spec.Delay, spec.Repeat)
+		result, err := Run(spec)
+		if err != nil && result.Error == "" {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+		if done != nil {
+			done(result)
+		}
+	}
+	return results
+}
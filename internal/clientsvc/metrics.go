@@ -0,0 +1,60 @@
+package clientsvc
+
+import (
+	"mybft/internal/metrics"
+	"mybft/internal/storage"
+)
+
+// clientMetrics 汇总 client 对外暴露在 /metrics 的指标。
+type clientMetrics struct {
+	registry      *metrics.Registry
+	lastHeight    *metrics.Gauge
+	lastLatency   *metrics.Gauge
+	lastBatch     *metrics.Gauge
+	throughput    *metrics.Gauge
+	committedTxs  *metrics.Counter
+	replies       *metrics.CounterVec
+	divergences   *metrics.Counter
+	commitLatency *metrics.Histogram
+	// phaseDurations 按算法与阶段统计分阶段耗时。
+	phaseDurations *metrics.HistogramVec
+	// authRejected 统计开启 TLS 时因证书缺失或与上报节点不符而拒绝的请求。
+	authRejected *metrics.CounterVec
+}
+
+func newClientMetrics(s *Service) *clientMetrics {
+	r := metrics.NewRegistry()
+	m := &clientMetrics{
+		registry:      r,
+		lastHeight:    r.NewGauge("mybft_client_last_height", "Most recent height that reached q end replies."),
+		lastLatency:   r.NewGauge("mybft_client_last_latency_seconds", "Commit latency of the most recent height."),
+		lastBatch:     r.NewGauge("mybft_client_last_batch_txs", "Transaction count of the most recent height."),
+		throughput:    r.NewGauge("mybft_client_throughput_tx_per_second", "Throughput over the sliding window."),
+		committedTxs:  r.NewCounter("mybft_client_committed_txs_total", "Transactions in heights that reached q end replies."),
+		replies:       r.NewCounterVec("mybft_client_end_replies_total", "End reports from nodes, by outcome.", "result"),
+		divergences:   r.NewCounter("mybft_client_state_root_divergences_total", "End reports whose state root differs from the first report for the height."),
+		commitLatency: r.NewHistogram("mybft_client_commit_latency_seconds", "Per-height commit latency from start to the q-th end reply.", metrics.DefaultLatencyBuckets),
+		phaseDurations: r.NewHistogramVec("mybft_client_phase_duration_seconds", "Per-height consensus phase durations computed from node phase reports.",
+			metrics.DefaultLatencyBuckets, "alg", "phase"),
+		authRejected: r.NewCounterVec("mybft_client_peer_auth_rejected_total", "Node reports rejected because the TLS peer certificate is missing or does not match the reporting node, by endpoint.", "endpoint"),
+	}
+	r.NewSummaryFunc("mybft_client_latency_seconds", "Whole-run commit latency quantiles.", func() metrics.SummaryValue {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return summaryValue(s.runLatency.Summary())
+	})
+	r.NewSummaryFunc("mybft_client_window_latency_seconds", "Commit latency quantiles over the sliding window.", func() metrics.SummaryValue {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return summaryValue(s.windowLatency)
+	})
+	return m
+}
+
+func summaryValue(sum storage.LatencySummary) metrics.SummaryValue {
+	return metrics.SummaryValue{
+		Quantiles: map[float64]float64{0.5: sum.P50, 0.9: sum.P90, 0.99: sum.P99, 0.999: sum.P999},
+		Sum:       sum.Mean * float64(sum.Count),
+		Count:     sum.Count,
+	}
+}
@@ -0,0 +1,131 @@
+package clientsvc
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"mybft/internal/common"
+	"mybft/internal/storage"
+)
+
+// phaseCollector 收集某高度各节点的阶段上报。
+type phaseCollector struct {
+	reports  map[int]common.PhaseReport
+	observed bool
+}
+
+// 处理 /phase：收集各节点的阶段时间戳，凑齐 q 份且含 leader 上报后计算并持久化分阶段耗时。
+func (s *Service) handlePhase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req common.PhaseReport
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.authenticNode(r, "phase", req.From) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pc, ok := s.phaseReports[req.Height]
+	if !ok {
+		pc = &phaseCollector{reports: map[int]common.PhaseReport{}}
+		s.phaseReports[req.Height] = pc
+		delete(s.phaseReports, req.Height-stateRootRetention)
+	}
+	pc.reports[req.From] = req
+	if len(pc.reports) < s.q {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	record, ok := phaseDurations(req.Height, pc.reports)
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if startRaw, err := s.rdb.HGet(s.key("start"), strconv.Itoa(req.Height)); err == nil {
+		if start, err := strconv.ParseInt(startRaw, 10, 64); err == nil {
+			if sent := pc.reports[record.Leader].Phases[common.PhaseProposalSent]; sent > 0 {
+				record.Durations[storage.PhaseProposal] = float64(sent-start) / 1e9
+			}
+		}
+	}
+	// 后到的上报会让中位数更准确，持久化记录随之更新；Prometheus 直方图每个高度只记一次。
+	if err := s.metricsStore.SavePhases(record); err != nil {
+		log.Printf("client save phases height=%d: %v", req.Height, err)
+	}
+	if !pc.observed {
+		pc.observed = true
+		for phase, d := range record.Durations {
+			s.metrics.phaseDurations.Observe(d, record.Alg, phase)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// phaseDurations 以 leader 的时间戳为起点、其余节点时间戳的中位数为终点计算各阶段耗时；缺少 leader 上报时返回 false。
+func phaseDurations(height int, reports map[int]common.PhaseReport) (storage.PhaseRecord, bool) {
+	var leader common.PhaseReport
+	found := false
+	for _, rep := range reports {
+		if rep.From == rep.Leader {
+			leader, found = rep, true
+			break
+		}
+	}
+	if !found {
+		return storage.PhaseRecord{}, false
+	}
+	followers := func(phase string) int64 {
+		var ts []int64
+		for from, rep := range reports {
+			if from == leader.From {
+				continue
+			}
+			if at, ok := rep.Phases[phase]; ok {
+				ts = append(ts, at)
+			}
+		}
+		return median(ts)
+	}
+	var executed []int64
+	for _, rep := range reports {
+		if at, ok := rep.Phases[common.PhaseExecuted]; ok {
+			executed = append(executed, at)
+		}
+	}
+	sent := leader.Phases[common.PhaseProposalSent]
+	received := followers(common.PhaseProposalReceived)
+	quorum := leader.Phases[common.PhaseQuorumReached]
+	qc := followers(common.PhaseQCReceived)
+	exec := median(executed)
+
+	record := storage.PhaseRecord{Height: height, Alg: leader.Alg, Leader: leader.From, Reports: len(reports), Durations: map[string]float64{}, RecordedAt: time.Now().UnixNano()}
+	span := func(name string, from, to int64) {
+		if from > 0 && to > 0 {
+			record.Durations[name] = float64(to-from) / 1e9
+		}
+	}
+	span(storage.PhaseDissemination, sent, received)
+	span(storage.PhaseVoteCollection, received, quorum)
+	span(storage.PhaseQCBroadcast, quorum, qc)
+	span(storage.PhaseExecution, qc, exec)
+	span(storage.PhaseTotal, sent, exec)
+	return record, true
+}
+
+func median(ts []int64) int64 {
+	if len(ts) == 0 {
+		return 0
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	return ts[len(ts)/2]
+}
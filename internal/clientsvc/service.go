@@ -1,19 +1,30 @@
 package clientsvc
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
 	"mybft/internal/common"
+	"mybft/internal/histogram"
 	"mybft/internal/redisx"
 	"mybft/internal/storage"
 	leveldbstore "mybft/internal/storage/leveldb"
+	"mybft/internal/tlsx"
 )
 
 type Service struct {
@@ -24,8 +35,36 @@ type Service struct {
 	windowSeconds     int
 	throughputSamples []throughputSample
 	stores            *leveldbstore.ClientStores
+	run               storage.RunRecord
+	metricsStore      storage.MetricsStore
+	stateRoots        map[int]stateRootReport
+	stateRootsHigh    int
+	phaseReports      map[int]*phaseCollector
+	runLatency        *histogram.Histogram
+	windowLatency     storage.LatencySummary
+	latencySamples    []latencySample
+	metrics           *clientMetrics
+	// tls 为 true 时 /start、/end、/phase 只接受节点证书，/end 与 /phase 的 from 须与证书一致。
+	tls bool
+}
+
+// latencySample 用于按与吞吐量相同的窗口重建滑动窗口直方图。
+type latencySample struct {
+	recordedAt time.Time
+	latency    time.Duration
 }
 
+// 全程时延直方图在 metrics 库中的名称。
+const runLatencyHistogram = "latency:run"
+
+type stateRootReport struct {
+	root string
+	from int
+}
+
+// 保留最近若干高度的状态根用于分叉比对。
+const stateRootRetention = 256
+
 type throughputSample struct {
 	recordedAt time.Time
 	txCount    int
@@ -47,11 +86,77 @@ func New(n int) (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := &Service{rdb: redisx.NewClient(), n: n, q: n/3 + 1, windowSeconds: windowSeconds, stores: stores}
+	run, err := openRun(stores, os.Getenv("MYBFT_RUN_ID"), n)
+	if err != nil {
+		_ = stores.Close()
+		return nil, err
+	}
+	s := &Service{
+		rdb:           redisx.NewClient(),
+		n:             n,
+		q:             n/3 + 1,
+		windowSeconds: windowSeconds,
+		stores:        stores,
+		run:           run,
+		metricsStore:  stores.Metrics(run.ID),
+		stateRoots:    map[int]stateRootReport{},
+		phaseReports:  map[int]*phaseCollector{},
+		runLatency:    histogram.New(),
+		tls:           tlsx.Enabled(),
+	}
+	s.metrics = newClientMetrics(s)
 	s.loadRecentThroughputSamples()
+	s.loadLatencyHistograms()
 	return s, nil
 }
 
+// openRun 续用 MYBFT_RUN_ID 指定的运行，或新建一次运行并记录 N、开始时间与 MYBFT_* 配置。
+func openRun(stores *leveldbstore.ClientStores, id string, n int) (storage.RunRecord, error) {
+	if id != "" {
+		run, err := stores.Runs.LoadRun(id)
+		if err == nil {
+			return run, nil
+		}
+		if !errors.Is(err, goleveldb.ErrNotFound) {
+			return run, err
+		}
+	} else {
+		id = newRunID()
+	}
+	run := storage.RunRecord{ID: id, N: n, StartedAt: time.Now().UnixNano(), Config: map[string]string{}}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, "MYBFT_") && k != "MYBFT_RUN_ID" {
+			run.Config[k] = v
+		}
+	}
+	return run, stores.Runs.SaveRun(run)
+}
+
+// newRunID 生成形如 20060102-150405-1a2b3c4d 的运行 ID。
+func newRunID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// key 返回本次运行在 Redis 中的时延键，不同运行互不干扰。
+func (s *Service) key(name string) string {
+	return "latency:" + s.run.ID + ":" + name
+}
+
+// recordRunAlg 用首个 /start 上报的算法与批策略补全运行元数据。
+func (s *Service) recordRunAlg(req common.StartRequest) {
+	if s.run.Alg != "" || req.Alg == "" {
+		return
+	}
+	s.run.Alg = req.Alg
+	s.run.Config["batch_mode"] = req.Batch.Mode
+	s.run.Config["batch_max_txs"] = strconv.Itoa(req.Batch.MaxTxs)
+	if err := s.stores.Runs.SaveRun(s.run); err != nil {
+		log.Printf("client save run %s: %v", s.run.ID, err)
+	}
+}
+
 // 处理 /start：按 height 独立记录起始时间，支持流水线下的乱序 commit。
 func (s *Service) handleStart(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -63,18 +168,24 @@ func (s *Service) handleStart(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if !s.authenticNode(r, "start", 0) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
 	now := time.Now().UnixNano()
 	h := strconv.Itoa(req.Height)
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if ok, _ := s.rdb.HExists("latency:start", h); !ok {
-		s.rdb.HSet("latency:start", map[string]string{h: strconv.FormatInt(now, 10)})
-		s.rdb.HSet("latency:batch", map[string]string{h: strconv.Itoa(req.Batch)})
-		s.rdb.HSet("latency:reply", map[string]string{h: "0"})
-		s.rdb.Del("latency:dedup:" + h)
-		s.rdb.HDel("latency:end", h)
-		s.rdb.HDel("latency:printed", h)
-		log.Printf("ts=%d role=client id=0 event=start_recorded height=%d reset=end,printed", now, req.Height)
+	s.recordRunAlg(req)
+	if ok, _ := s.rdb.HExists(s.key("start"), h); !ok {
+		s.rdb.HSet(s.key("start"), map[string]string{h: strconv.FormatInt(now, 10)})
+		s.rdb.HSet(s.key("batch"), map[string]string{h: strconv.Itoa(req.Batch.Size)})
+		s.rdb.HSet(s.key("reply"), map[string]string{h: "0"})
+		s.rdb.Del(s.key("dedup:" + h))
+		s.rdb.HDel(s.key("end"), h)
+		s.rdb.HDel(s.key("printed"), h)
+		log.Printf("ts=%d role=client id=0 event=start_recorded height=%d reset=end,printed batch=%d bytes=%d mode=%s max_txs=%d max_bytes=%d max_wait_ms=%d",
+			now, req.Height, req.Batch.Size, req.Batch.Bytes, req.Batch.Mode, req.Batch.MaxTxs, req.Batch.MaxBytes, req.Batch.MaxWaitMs)
 	} else {
 		log.Printf("ts=%d role=client id=0 event=duplicate_start_ignored height=%d", now, req.Height)
 	}
@@ -92,44 +203,82 @@ func (s *Service) handleEnd(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if !s.authenticNode(r, "end", req.From) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
 	now := time.Now().UnixNano()
 	h := strconv.Itoa(req.Height)
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if ok, _ := s.rdb.HExists("latency:start", h); !ok {
+	if ok, _ := s.rdb.HExists(s.key("start"), h); !ok {
+		s.metrics.replies.Inc("without_start")
 		log.Printf("ts=%d role=client id=0 event=end_without_start_dropped height=%d from=%d", now, req.Height, req.From)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
-	if printed, _ := s.rdb.HGet("latency:printed", h); printed == "1" {
+	s.checkStateRoot(req)
+	if printed, _ := s.rdb.HGet(s.key("printed"), h); printed == "1" {
+		s.metrics.replies.Inc("late")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
-	added, _ := s.rdb.SAdd("latency:dedup:"+h, strconv.Itoa(req.From))
+	added, _ := s.rdb.SAdd(s.key("dedup:"+h), strconv.Itoa(req.From))
 	if added == 0 {
+		s.metrics.replies.Inc("duplicate")
 		log.Printf("ts=%d role=client id=0 event=end_duplicate_ignored height=%d from=%d", now, req.Height, req.From)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
-	replies, _ := s.rdb.HIncrBy("latency:reply", h, 1)
+	replies, _ := s.rdb.HIncrBy(s.key("reply"), h, 1)
+	s.metrics.replies.Inc("accepted")
 	log.Printf("ts=%d role=client id=0 event=end_accepted height=%d from=%d reply=%d", now, req.Height, req.From, replies)
 	if int(replies) == s.q {
-		s.rdb.HSet("latency:end", map[string]string{h: strconv.FormatInt(now, 10)})
-		startRaw, _ := s.rdb.HGet("latency:start", h)
+		s.rdb.HSet(s.key("end"), map[string]string{h: strconv.FormatInt(now, 10)})
+		startRaw, _ := s.rdb.HGet(s.key("start"), h)
 		start, _ := strconv.ParseInt(startRaw, 10, 64)
 		latency := float64(now-start) / 1e9
-		batch := txCountForHeight(s.rdb, h, req.Height)
+		batch := txCountForHeight(s.rdb, s.key("batch"), h, req.Height)
 		recordedAt := time.Unix(0, now)
 		throughput := s.recordThroughputSample(batch, recordedAt)
-		s.persistMetric(req.Height, latency, batch, throughput, recordedAt)
-		fmt.Printf("height %d latency is %f batch is %d throughput is %f tx/s\n", req.Height, latency, batch, throughput)
-		s.rdb.HSet("latency:printed", map[string]string{h: "1"})
+		run, window := s.recordLatencySample(time.Duration(now-start), recordedAt)
+		s.persistMetric(req.Height, latency, batch, throughput, recordedAt, run, window)
+		s.observeHeight(req.Height, latency, batch, throughput)
+		fmt.Printf("height %d latency is %f batch is %d throughput is %f tx/s window p50=%f p99=%f max=%f\n",
+			req.Height, latency, batch, throughput, window.P50, window.P99, window.Max)
+		s.rdb.HSet(s.key("printed"), map[string]string{h: "1"})
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func txCountForHeight(rdb *redisx.Client, heightKey string, height int) int {
-	raw, err := rdb.HGet("latency:batch", heightKey)
+// 比对各节点上报的同一高度状态根，不一致即说明执行结果出现分叉。只保留最高已见高度之下
+// stateRootRetention 个高度内的记录：高度跳跃或乱序到达时，更早的记录在最高高度前移时一并淘汰。
+func (s *Service) checkStateRoot(req common.EndRequest) {
+	if req.StateRoot == "" || req.Height <= s.stateRootsHigh-stateRootRetention {
+		return
+	}
+	first, ok := s.stateRoots[req.Height]
+	if !ok {
+		s.stateRoots[req.Height] = stateRootReport{root: req.StateRoot, from: req.From}
+		if req.Height > s.stateRootsHigh {
+			s.stateRootsHigh = req.Height
+			for h := range s.stateRoots {
+				if h <= s.stateRootsHigh-stateRootRetention {
+					delete(s.stateRoots, h)
+				}
+			}
+		}
+		return
+	}
+	if first.root != req.StateRoot {
+		s.metrics.divergences.Inc()
+		log.Printf("ts=%d role=client id=0 event=state_root_divergence height=%d from=%d root=%s first_from=%d first_root=%s",
+			time.Now().UnixNano(), req.Height, req.From, req.StateRoot, first.from, first.root)
+	}
+}
+
+func txCountForHeight(rdb *redisx.Client, batchKey, heightKey string, height int) int {
+	raw, err := rdb.HGet(batchKey, heightKey)
 	if err == nil {
 		if batch, parseErr := strconv.Atoi(raw); parseErr == nil && batch >= 0 {
 			return batch
@@ -141,7 +290,7 @@ func txCountForHeight(rdb *redisx.Client, heightKey string, height int) int {
 func (s *Service) recordThroughputSample(txCount int, recordedAt time.Time) float64 {
 	s.throughputSamples = append(s.throughputSamples, throughputSample{recordedAt: recordedAt, txCount: txCount})
 	if s.stores != nil {
-		if err := s.stores.Metrics.AppendThroughputSample(storage.ThroughputSampleRecord{
+		if err := s.metricsStore.AppendThroughputSample(storage.ThroughputSampleRecord{
 			RecordedAt: recordedAt.UnixNano(),
 			TxCount:    txCount,
 		}); err != nil {
@@ -167,7 +316,7 @@ func (s *Service) loadRecentThroughputSamples() {
 		return
 	}
 	since := time.Now().Add(-time.Duration(s.windowSeconds) * time.Second).UnixNano()
-	records, err := s.stores.Metrics.LoadThroughputSamplesSince(since)
+	records, err := s.metricsStore.LoadThroughputSamplesSince(since)
 	if err != nil {
 		log.Printf("client load throughput samples: %v", err)
 		return
@@ -180,7 +329,70 @@ func (s *Service) loadRecentThroughputSamples() {
 	}
 }
 
-func (s *Service) persistMetric(height int, latency float64, batch int, throughput float64, recordedAt time.Time) {
+// recordLatencySample 记入全程直方图，并返回全程与最近 windowSeconds 秒内的时延统计。
+func (s *Service) recordLatencySample(latency time.Duration, recordedAt time.Time) (storage.LatencySummary, storage.LatencySummary) {
+	s.runLatency.RecordDuration(latency)
+	s.latencySamples = append(s.latencySamples, latencySample{recordedAt: recordedAt, latency: latency})
+	windowStart := recordedAt.Add(-time.Duration(s.windowSeconds) * time.Second)
+	window := histogram.New()
+	pruned := s.latencySamples[:0]
+	for _, sample := range s.latencySamples {
+		if sample.recordedAt.Before(windowStart) {
+			continue
+		}
+		pruned = append(pruned, sample)
+		window.RecordDuration(sample.latency)
+	}
+	s.latencySamples = pruned
+	s.windowLatency = window.Summary()
+	if s.stores != nil {
+		if err := s.metricsStore.SaveHistogram(runLatencyHistogram, s.runLatency.Export()); err != nil {
+			log.Printf("client save latency histogram: %v", err)
+		}
+	}
+	return s.runLatency.Summary(), s.windowLatency
+}
+
+// observeHeight 更新某高度达到 q 个回复后的指标。
+func (s *Service) observeHeight(height int, latency float64, batch int, throughput float64) {
+	s.metrics.lastHeight.Set(float64(height))
+	s.metrics.lastLatency.Set(latency)
+	s.metrics.lastBatch.Set(float64(batch))
+	s.metrics.throughput.Set(throughput)
+	s.metrics.committedTxs.Add(float64(batch))
+	s.metrics.commitLatency.Observe(latency)
+}
+
+// loadLatencyHistograms 在重启后恢复全程直方图与窗口内的时延样本。
+func (s *Service) loadLatencyHistograms() {
+	if s.stores == nil {
+		return
+	}
+	record, err := s.metricsStore.LoadHistogram(runLatencyHistogram)
+	if err == nil {
+		s.runLatency = histogram.Import(record)
+	} else if !errors.Is(err, goleveldb.ErrNotFound) {
+		log.Printf("client load latency histogram: %v", err)
+	}
+	metrics, err := s.metricsStore.ListMetrics()
+	if err != nil {
+		log.Printf("client load latency samples: %v", err)
+		return
+	}
+	since := time.Now().Add(-time.Duration(s.windowSeconds) * time.Second)
+	for _, metric := range metrics {
+		recordedAt := time.Unix(0, metric.RecordedAt)
+		if recordedAt.Before(since) {
+			continue
+		}
+		s.latencySamples = append(s.latencySamples, latencySample{
+			recordedAt: recordedAt,
+			latency:    time.Duration(metric.Latency * float64(time.Second)),
+		})
+	}
+}
+
+func (s *Service) persistMetric(height int, latency float64, batch int, throughput float64, recordedAt time.Time, run, window storage.LatencySummary) {
 	if s.stores == nil {
 		return
 	}
@@ -191,12 +403,23 @@ func (s *Service) persistMetric(height int, latency float64, batch int, throughp
 		Throughput:    throughput,
 		RecordedAt:    recordedAt.UnixNano(),
 		WindowSeconds: s.windowSeconds,
+		RunLatency:    &run,
+		WindowLatency: &window,
 	}
-	if err := s.stores.Metrics.SaveMetric(record); err != nil {
+	if err := s.metricsStore.SaveMetric(record); err != nil {
 		log.Printf("client save metric height=%d: %v", height, err)
 	}
 }
 
+// printSummary 在退出时打印全程时延统计。
+func (s *Service) printSummary() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum := s.runLatency.Summary()
+	fmt.Printf("latency summary count=%d min=%f mean=%f p50=%f p90=%f p99=%f p999=%f max=%f\n",
+		sum.Count, sum.Min, sum.Mean, sum.P50, sum.P90, sum.P99, sum.P999, sum.Max)
+}
+
 // 启动 HTTP 服务，暴露 /start 与 /end 供节点上报。
 func Run(n int) error {
 	rdb := redisx.NewClient()
@@ -209,6 +432,49 @@ func Run(n int) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/start", s.handleStart)
 	mux.HandleFunc("/end", s.handleEnd)
-	log.Printf("client listen=127.0.0.1:8000 n=%d q=%d", n, n/3+1)
-	return http.ListenAndServe("127.0.0.1:8000", mux)
+	mux.HandleFunc("/phase", s.handlePhase)
+	mux.HandleFunc("/metrics", s.metrics.registry.Handler())
+	srv := &http.Server{Addr: "127.0.0.1:8000", Handler: mux}
+	if s.tls {
+		id, err := tlsx.LoadClient(rdb)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = id.ServerConfig()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("client listen=127.0.0.1:8000 n=%d q=%d run=%s tls=%t", n, n/3+1, s.run.ID, s.tls)
+	if err := s.serve(srv); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	s.printSummary()
+	return nil
+}
+
+func (s *Service) serve(srv *http.Server) error {
+	if s.tls {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// authenticNode 在开启 TLS 时检查上报方出示的节点证书：from>0 时须为节点 from 的证书。
+func (s *Service) authenticNode(r *http.Request, endpoint string, from int) bool {
+	if !s.tls {
+		return true
+	}
+	id, ok := tlsx.PeerNode(r)
+	if ok && (from <= 0 || id == from) {
+		return true
+	}
+	s.metrics.authRejected.Inc(endpoint)
+	log.Printf("client event=peer_auth_rejected endpoint=%s claimed=%d cert_node=%d remote=%s", endpoint, from, id, r.RemoteAddr)
+	return false
 }
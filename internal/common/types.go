@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -16,22 +17,99 @@ type Thresholds struct {
 }
 
 // 计算 BFT 门限：t=⌊2N/3⌋+1，q=⌊N/3⌋+1。
+// 带权重的验证者集合中 N 为总投票权重，t 即“超过 2/3 总权重”，q 即“超过 1/3 总权重”。
 func CalcThresholds(n int) Thresholds {
 	return Thresholds{N: n, T: (2*n)/3 + 1, Q: n/3 + 1}
 }
 
+// ParseWeights 解析按节点编号 1,2,... 顺序逗号分隔的投票权重，如 "3,1,1,1"；权重须为正整数。
+func ParseWeights(raw string) (map[int]int, error) {
+	weights := map[int]int{}
+	for i, part := range strings.Split(raw, ",") {
+		w, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || w < 1 {
+			return nil, fmt.Errorf("weight of node %d must be a positive integer, got %q", i+1, part)
+		}
+		weights[i+1] = w
+	}
+	return weights, nil
+}
+
+// TotalPower 汇总一组节点的投票权重，weights 中未出现的节点权重为 1。
+func TotalPower(weights map[int]int, ids []int) int {
+	total := 0
+	for _, id := range ids {
+		if w, ok := weights[id]; ok {
+			total += w
+		} else {
+			total++
+		}
+	}
+	return total
+}
+
 type StartRequest struct {
-	Height int   `json:"height"`
-	Start  int64 `json:"start"`
-	View   int   `json:"view,omitempty"`
-	Batch  int   `json:"batch,omitempty"`
+	Height int       `json:"height"`
+	Start  int64     `json:"start"`
+	View   int       `json:"view,omitempty"`
+	Batch  BatchInfo `json:"batch"`
+	// Alg 为 leader 运行的共识算法，client 用于记录运行元数据。
+	Alg string `json:"alg,omitempty"`
+}
+
+// BatchInfo 描述本次提案的实际批大小与 leader 使用的批策略。
+type BatchInfo struct {
+	Size            int    `json:"size"`
+	Bytes           int    `json:"bytes,omitempty"`
+	Mode            string `json:"mode,omitempty"`
+	MaxTxs          int    `json:"max_txs,omitempty"`
+	MaxBytes        int    `json:"max_bytes,omitempty"`
+	MaxWaitMs       int64  `json:"max_wait_ms,omitempty"`
+	TargetLatencyMs int64  `json:"target_latency_ms,omitempty"`
+}
+
+// 兼容旧格式：batch 字段为纯数字时视为批大小。
+func (b *BatchInfo) UnmarshalJSON(raw []byte) error {
+	var size int
+	if err := json.Unmarshal(raw, &size); err == nil {
+		*b = BatchInfo{Size: size}
+		return nil
+	}
+	type plain BatchInfo
+	var v plain
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	*b = BatchInfo(v)
+	return nil
 }
 
 type EndRequest struct {
-	Height int   `json:"height"`
-	End    int64 `json:"end,omitempty"`
-	From   int   `json:"from"`
-	View   int   `json:"view,omitempty"`
+	Height    int    `json:"height"`
+	End       int64  `json:"end,omitempty"`
+	From      int    `json:"from"`
+	View      int    `json:"view,omitempty"`
+	StateRoot string `json:"state_root,omitempty"`
+}
+
+// 节点上报给 client 的流水线阶段名称。
+const (
+	PhaseProposalSent     = "proposal_sent"
+	PhaseProposalReceived = "proposal_received"
+	PhaseVoteSent         = "vote_sent"
+	PhaseQuorumReached    = "quorum_reached"
+	PhaseQCReceived       = "qc_received"
+	PhaseExecuted         = "executed"
+)
+
+// PhaseReport 是节点在某高度执行完成后上报的各阶段时间戳（unix 纳秒），Leader 为提出该高度的节点。
+type PhaseReport struct {
+	Height int              `json:"height"`
+	View   int              `json:"view"`
+	From   int              `json:"from"`
+	Leader int              `json:"leader"`
+	Alg    string           `json:"alg"`
+	Phases map[string]int64 `json:"phases"`
 }
 
 type QuorumCert struct {
@@ -40,18 +118,28 @@ type QuorumCert struct {
 	View    int    `json:"view"`
 	Height  int    `json:"height"`
 	QC      string `json:"qc"`
+	// Signers 为聚合进 QC 的签名份额所属节点（升序），选主策略据此评估节点活跃度。
+	Signers []int `json:"signers,omitempty"`
 }
 
 type Block struct {
-	BlockID        string   `json:"block_id"`
-	ParentBlockID  string   `json:"parent_block_id,omitempty"`
-	JustifyBlockID string   `json:"justify_block_id,omitempty"`
-	JustifyView    int      `json:"justify_view,omitempty"`
+	BlockID        string `json:"block_id"`
+	ParentBlockID  string `json:"parent_block_id,omitempty"`
+	JustifyBlockID string `json:"justify_block_id,omitempty"`
+	JustifyView    int    `json:"justify_view,omitempty"`
+	// JustifyQC/JustifySigners 为 HotStuff 提案所附的 justify QC，随区块保存与同步，
+	// 各节点据此对同一区块得到一致的签名者。
+	JustifyQC      string   `json:"justify_qc,omitempty"`
+	JustifySigners []int    `json:"justify_signers,omitempty"`
 	Digest         string   `json:"digest"`
 	View           int      `json:"view"`
 	Height         int      `json:"height"`
 	Proposer       int      `json:"proposer"`
 	Tx             []string `json:"tx,omitempty"`
+	// Evidence 为 leader 打包进区块的作恶证据。
+	Evidence []Evidence `json:"evidence,omitempty"`
+	// Reconfig 为 leader 打包进区块的成员变更交易，提交后按固定延迟生效。
+	Reconfig []string `json:"reconfig,omitempty"`
 }
 
 type ConsensusMessage struct {
@@ -71,6 +159,30 @@ type ConsensusMessage struct {
 	QC          string   `json:"qc,omitempty"`
 	SigAgg      string   `json:"sig_agg,omitempty"`
 	SigAggFull  string   `json:"sig_agg_full,omitempty"`
+	// Signers 为 QC 消息中聚合签名的签名者；JustifySigners 为提案所附 justify QC 的签名者。
+	Signers        []int `json:"signers,omitempty"`
+	JustifySigners []int `json:"justify_signers,omitempty"`
+	// Evidence 为提案打包的作恶证据，其 ID 计入区块摘要。
+	Evidence []Evidence `json:"evidence,omitempty"`
+	// Reconfig 为提案打包的成员变更交易，计入区块摘要。
+	Reconfig []string `json:"reconfig,omitempty"`
+}
+
+// Evidence 是一条可独立验证的节点作恶证据：First/Second 为作恶节点签名的消息原文
+// （invalid_qc 只有 First）。Reporter 为最先发现的节点。
+type Evidence struct {
+	Kind     string          `json:"kind"`
+	Offender int             `json:"offender"`
+	View     int             `json:"view"`
+	Height   int             `json:"height"`
+	Reporter int             `json:"reporter,omitempty"`
+	First    json.RawMessage `json:"first"`
+	Second   json.RawMessage `json:"second,omitempty"`
+}
+
+// ID 由类型、view 与作恶节点确定：同一次作恶被不同节点发现时 ID 相同。
+func (e Evidence) ID() string {
+	return fmt.Sprintf("%s:%d:%d", e.Kind, e.View, e.Offender)
 }
 
 // 生成共识消息摘要：基于 view/height 与交易内容的双重哈希。
@@ -82,6 +194,24 @@ func Digest(view, height int, tx []string) string {
 	return hex.EncodeToString(d[:])
 }
 
+// 生成区块摘要：不含证据与成员变更时与 Digest 相同，否则再覆盖所含证据的 ID 与成员变更交易。
+func BlockDigest(view, height int, tx []string, evidence []Evidence, reconfig []string) string {
+	digest := Digest(view, height, tx)
+	if len(evidence) > 0 {
+		ids := make([]string, len(evidence))
+		for i, e := range evidence {
+			ids[i] = e.ID()
+		}
+		d := sha256.Sum256([]byte(digest + "|evidence=" + strings.Join(ids, ",")))
+		digest = hex.EncodeToString(d[:])
+	}
+	if len(reconfig) > 0 {
+		d := sha256.Sum256([]byte(digest + "|reconfig=" + strings.Join(reconfig, "\n")))
+		digest = hex.EncodeToString(d[:])
+	}
+	return digest
+}
+
 // 生成稳定顺序的 JSON（用于签名/验签的一致性输入）。
 func CanonicalJSON(v map[string]any) []byte {
 	keys := make([]string, 0, len(v))
@@ -97,6 +227,55 @@ func CanonicalJSON(v map[string]any) []byte {
 	return []byte("{" + strings.Join(parts, ",") + "}")
 }
 
+type TxSubmitRequest struct {
+	Tx  string   `json:"tx,omitempty"`
+	Txs []string `json:"txs,omitempty"`
+	// From 为转发交易的节点编号，仅节点间转发时填写。
+	From int `json:"from,omitempty"`
+}
+
+type TxSubmitResult struct {
+	ID   string `json:"id"`
+	Code int    `json:"code"`
+	Log  string `json:"log,omitempty"`
+}
+
+type TxSubmitResponse struct {
+	Results []TxSubmitResult `json:"results"`
+}
+
+// CommittedTx 是已提交区块中的一笔交易及其执行结果码。
+type CommittedTx struct {
+	ID   string `json:"id"`
+	Tx   string `json:"tx"`
+	Code int    `json:"code"`
+}
+
+// CommitEntry 是节点执行并提交一个区块后的通知，携带提交证明；CommittedAt 为 Unix 纳秒。
+type CommitEntry struct {
+	Height   int        `json:"height"`
+	BlockID  string     `json:"block_id"`
+	Proposer int        `json:"proposer,omitempty"`
+	QC       QuorumCert `json:"qc"`
+	// Justify 为 HotStuff 区块自身携带的 justify QC（链上数据），选主按它统计签名者。
+	Justify     *QuorumCert   `json:"justify,omitempty"`
+	CommittedAt int64         `json:"committed_at"`
+	Txs         []CommittedTx `json:"txs"`
+}
+
+// CommitsResponse 为 /commits 的返回：Oldest/Latest 为节点可提供的最早与最新高度。
+type CommitsResponse struct {
+	Oldest  int           `json:"oldest"`
+	Latest  int           `json:"latest"`
+	Entries []CommitEntry `json:"entries"`
+}
+
+// 交易 ID：交易文本的 SHA-256。
+func TxID(tx string) string {
+	h := sha256.Sum256([]byte(tx))
+	return hex.EncodeToString(h[:])
+}
+
 // 生成消息去重键，避免同一 view/height/digest 重复处理。
 func DedupKey(msg ConsensusMessage) string {
 	return fmt.Sprintf("%d:%d:%s:%d:%s", msg.View, msg.Height, msg.Digest, msg.From, msg.Type)
@@ -39,3 +39,23 @@ func VerifyAggregate(shares []string, full string) bool {
 func VoteMessage(msgType string, view, height int, digest string, from int) []byte {
 	return []byte(fmt.Sprintf("{\"digest\":\"%s\",\"from\":%d,\"height\":%d,\"type\":\"%s\",\"view\":%d}", digest, from, height, msgType, view))
 }
+
+// 生成提案的规范化字节序列（用于 leader 签名）：除摘要外还覆盖 HotStuff 的父块与 justify，
+// 使转发者无法改动提案在链上的位置。
+func ProposalMessage(msgType string, view, height int, digest, parentID, justifyID string, from int) []byte {
+	return []byte(fmt.Sprintf("{\"digest\":\"%s\",\"from\":%d,\"height\":%d,\"justify\":\"%s\",\"parent\":\"%s\",\"type\":\"%s\",\"view\":%d}", digest, from, height, justifyID, parentID, msgType, view))
+}
+
+// 生成 QC 消息的规范化字节序列（用于组成 QC 的 leader 签名）：覆盖聚合值与签名者，
+// 聚合值与签名者不符时该签名即为 leader 广播无效 QC 的证据。
+func QCMessage(msgType string, view, height int, blockID, qc string, signers []int, from int) []byte {
+	return []byte(fmt.Sprintf("{\"block\":\"%s\",\"from\":%d,\"height\":%d,\"qc\":\"%s\",\"signers\":%s,\"type\":\"%s\",\"view\":%d}", blockID, from, height, qc, intList(signers), msgType, view))
+}
+
+func intList(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
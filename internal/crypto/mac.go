@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// NewDHKey 生成 X25519 密钥对（base64），用于协商节点间的成对 MAC 密钥。
+func NewDHKey() (sk, pk string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.Bytes()), base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// PairwiseKey 由本方 X25519 私钥与对方公钥协商出共享秘密，再与双方编号一起哈希为 MAC 密钥；
+// a、b 为通信双方的编号，两端算出的密钥相同。
+func PairwiseKey(sk, peerPK string, a, b int) ([]byte, error) {
+	rawSK, err := base64.StdEncoding.DecodeString(sk)
+	if err != nil {
+		return nil, fmt.Errorf("decode dh private key: %w", err)
+	}
+	rawPK, err := base64.StdEncoding.DecodeString(peerPK)
+	if err != nil {
+		return nil, fmt.Errorf("decode dh public key: %w", err)
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(rawSK)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := ecdh.X25519().NewPublicKey(rawPK)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	if a > b {
+		a, b = b, a
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "mybft-mac|%d|%d|", a, b)
+	h.Write(shared)
+	return h.Sum(nil), nil
+}
+
+// MAC 用成对密钥计算消息认证码（HMAC-SHA256）。
+func MAC(key, msg []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write(msg)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// VerifyMAC 以常量时间比较重算的认证码。
+func VerifyMAC(key, msg []byte, mac string) bool {
+	return hmac.Equal([]byte(MAC(key, msg)), []byte(mac))
+}
@@ -0,0 +1,257 @@
+package election
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	PolicyRoundRobin = "round-robin"
+	PolicyReputation = "reputation"
+	PolicyStable     = "stable"
+	PolicyRandom     = "random"
+)
+
+const defaultWindow = 20
+
+// Config 为选主策略配置。Window 为一个选主周期包含的 view 数：周期 e 的 leader
+// 只依赖周期 e-2 及更早的已提交历史，因此 Window 必须大于节点间提交进度的最大差距。
+type Config struct {
+	Policy string
+	Window int
+	Seed   string
+}
+
+// ConfigFromEnv 读取选主配置：MYBFT_LEADER_POLICY=round-robin|reputation|stable|random、
+// MYBFT_LEADER_WINDOW（默认 20）、MYBFT_LEADER_SEED（random 策略的种子）。
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Policy: PolicyRoundRobin, Window: defaultWindow, Seed: "mybft"}
+	if raw := os.Getenv("MYBFT_LEADER_POLICY"); raw != "" {
+		cfg.Policy = raw
+	}
+	if raw := os.Getenv("MYBFT_LEADER_WINDOW"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			return cfg, fmt.Errorf("invalid MYBFT_LEADER_WINDOW: %q", raw)
+		}
+		cfg.Window = v
+	}
+	if raw := os.Getenv("MYBFT_LEADER_SEED"); raw != "" {
+		cfg.Seed = raw
+	}
+	return cfg, nil
+}
+
+// Record 是一个已提交高度上与选主有关的信息（view 与高度一致）。
+type Record struct {
+	Height   int    `json:"height"`
+	Proposer int    `json:"proposer"`
+	Signers  []int  `json:"signers,omitempty"`
+	QC       string `json:"qc,omitempty"`
+}
+
+// LeaderElection 决定每个 view 的 leader。实现只依赖已提交历史，诚实节点对同一 view 得出相同结果。
+type LeaderElection interface {
+	Name() string
+	Leader(view int) int
+	// Observe 记录一个已提交高度，须按高度递增调用。
+	Observe(rec Record)
+	// Export / Restore 用于快照引导：导出并恢复选主所需的最近历史。
+	Export() (json.RawMessage, error)
+	Restore(raw json.RawMessage) error
+}
+
+// Members 返回 view 下的验证者集合（升序、非空），随成员变更而变化。
+type Members func(view int) []int
+
+// New 按配置创建选主策略，leader 只从 members 给出的当前验证者中选出。
+func New(cfg Config, members Members) (LeaderElection, error) {
+	if cfg.Window < 1 {
+		cfg.Window = defaultWindow
+	}
+	h := &history{members: members, window: cfg.Window, records: map[int]Record{}}
+	switch cfg.Policy {
+	case "", PolicyRoundRobin:
+		return &roundRobin{history: h}, nil
+	case PolicyReputation:
+		return &reputation{history: h}, nil
+	case PolicyStable:
+		return &stable{history: h}, nil
+	case PolicyRandom:
+		return &random{history: h, seed: cfg.Seed}, nil
+	default:
+		return nil, fmt.Errorf("unknown leader policy: %s", cfg.Policy)
+	}
+}
+
+// history 保存最近三个周期的已提交记录，供各策略共用。
+type history struct {
+	mu      sync.Mutex
+	members Members
+	window  int
+	records map[int]Record
+}
+
+func (h *history) epoch(view int) int { return (view - 1) / h.window }
+
+func (h *history) Observe(rec Record) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records[rec.Height] = rec
+	keep := (h.epoch(rec.Height) - 2) * h.window
+	for height := range h.records {
+		if height <= keep {
+			delete(h.records, height)
+		}
+	}
+}
+
+func (h *history) Export() (json.RawMessage, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]Record, 0, len(h.records))
+	for _, rec := range h.records {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Height < out[j].Height })
+	return json.Marshal(out)
+}
+
+func (h *history) Restore(raw json.RawMessage) error {
+	var records []Record
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &records); err != nil {
+			return err
+		}
+	}
+	h.mu.Lock()
+	h.records = map[int]Record{}
+	h.mu.Unlock()
+	for _, rec := range records {
+		h.Observe(rec)
+	}
+	return nil
+}
+
+// span 返回周期 e 内已记录的高度（由低到高），调用方需持有 h.mu。
+func (h *history) span(e int) []Record {
+	var out []Record
+	if e < 0 {
+		return out
+	}
+	for height := e*h.window + 1; height <= (e+1)*h.window; height++ {
+		if rec, ok := h.records[height]; ok {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// candidates 按周期 e-2 的历史从 view 的验证者中挑出活跃节点：得分为签入 QC 的次数加提出已提交区块的次数，
+// 得分超过最高分一半的节点入选（按编号排序）；历史为空或无人入选时全部验证者入选。调用方需持有 h.mu。
+func (h *history) candidates(e, view int) []int {
+	records := h.span(e - 2)
+	all := h.members(view)
+	if len(records) == 0 {
+		return all
+	}
+	score := map[int]int{}
+	for _, rec := range records {
+		score[rec.Proposer]++
+		for _, id := range rec.Signers {
+			score[id]++
+		}
+	}
+	best := 0
+	for _, id := range all {
+		best = max(best, score[id])
+	}
+	var out []int
+	for _, id := range all {
+		if score[id] > 0 && 2*score[id] > best {
+			out = append(out, id)
+		}
+	}
+	if len(out) == 0 {
+		return all
+	}
+	return out
+}
+
+// roundRobin：在 view 的验证者中按 (view-1) mod N 轮换，成员固定为 1..N 时即 ((view-1) mod N) + 1。
+type roundRobin struct{ *history }
+
+func (p *roundRobin) Name() string { return PolicyRoundRobin }
+
+func (p *roundRobin) Leader(view int) int {
+	members := p.members(view)
+	return members[(view-1)%len(members)]
+}
+
+// reputation 在近期活跃的节点中轮换（类似 DiemBFT 的 leader reputation）：
+// 持续未能及时投票进入 QC 的慢节点与已停止的节点不再被选为 leader。
+type reputation struct{ *history }
+
+func (p *reputation) Name() string { return PolicyReputation }
+
+func (p *reputation) Leader(view int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := p.candidates(p.epoch(view), view)
+	return c[(view-1)%len(c)]
+}
+
+// stable 固定由同一节点担任 leader，直到它不再活跃：周期 e 沿用周期 e-1 的 leader
+// （即该周期首个高度的提议者），若它不在周期 e-2 的活跃节点中则改由编号其后的下一个活跃节点担任。
+type stable struct{ *history }
+
+func (p *stable) Name() string { return PolicyStable }
+
+func (p *stable) Leader(view int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.epoch(view)
+	prev, ok := p.records[(e-1)*p.window+1]
+	if e == 0 || !ok {
+		return p.members(view)[0]
+	}
+	c := p.candidates(e, view)
+	for _, id := range c {
+		if id == prev.Proposer {
+			return id
+		}
+	}
+	for _, id := range c {
+		if id > prev.Proposer {
+			return id
+		}
+	}
+	return c[0]
+}
+
+// random 以哈希为每个 view 随机选主：种子为配置的 seed 与周期 e-2 最后一个已提交 QC
+// （聚合签名，提交前不可预测），作为不需要额外密钥的类 VRF 信标。
+type random struct {
+	*history
+	seed string
+}
+
+func (p *random) Name() string { return PolicyRandom }
+
+func (p *random) Leader(view int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	beacon := ""
+	if records := p.span(p.epoch(view) - 2); len(records) > 0 {
+		beacon = records[len(records)-1].QC
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d", p.seed, beacon, view)))
+	members := p.members(view)
+	return members[binary.BigEndian.Uint64(sum[:8])%uint64(len(members))]
+}
@@ -0,0 +1,113 @@
+package election
+
+import (
+	"fmt"
+	"testing"
+)
+
+func fourMembers(int) []int { return []int{1, 2, 3, 4} }
+
+// testHistory 为窗口 4 下高度 1..8 的提交记录：提议者按轮换，节点 1 从不签入 QC。
+func testHistory() []Record {
+	var out []Record
+	for h := 1; h <= 8; h++ {
+		out = append(out, Record{Height: h, Proposer: (h-1)%4 + 1, Signers: []int{2, 3, 4}, QC: fmt.Sprintf("qc%d", h)})
+	}
+	return out
+}
+
+func newPolicy(t *testing.T, policy string) LeaderElection {
+	t.Helper()
+	e, err := New(Config{Policy: policy, Window: 4, Seed: "test"}, fourMembers)
+	if err != nil {
+		t.Fatalf("New(%s): %v", policy, err)
+	}
+	for _, rec := range testHistory() {
+		e.Observe(rec)
+	}
+	return e
+}
+
+func TestLeader(t *testing.T) {
+	cases := []struct {
+		policy string
+		view   int
+		want   int
+	}{
+		// 周期 0、1 没有 e-2 历史，按全部验证者选主。
+		{PolicyRoundRobin, 1, 1},
+		{PolicyRoundRobin, 5, 1},
+		{PolicyRoundRobin, 11, 3},
+		{PolicyReputation, 5, 1},
+		{PolicyReputation, 6, 2},
+		// 周期 2 起按周期 0 的历史，节点 1 得分不足被排除，候选为 [2 3 4]。
+		{PolicyReputation, 9, 4},
+		{PolicyReputation, 10, 2},
+		{PolicyReputation, 11, 3},
+		{PolicyReputation, 13, 2},
+		{PolicyStable, 1, 1},
+		{PolicyStable, 5, 1},
+		// 周期 1 首个高度的提议者 1 不活跃，改由其后的下一个活跃节点担任。
+		{PolicyStable, 9, 2},
+		{PolicyStable, 12, 2},
+		// 周期 2 首个高度尚未提交时回到第一个验证者。
+		{PolicyStable, 13, 1},
+	}
+	for _, tc := range cases {
+		e := newPolicy(t, tc.policy)
+		if got := e.Leader(tc.view); got != tc.want {
+			t.Errorf("%s Leader(%d) = %d, want %d", tc.policy, tc.view, got, tc.want)
+		}
+	}
+}
+
+func TestLeaderAgreesAfterRestore(t *testing.T) {
+	for _, policy := range []string{PolicyRoundRobin, PolicyReputation, PolicyStable, PolicyRandom} {
+		e := newPolicy(t, policy)
+		raw, err := e.Export()
+		if err != nil {
+			t.Fatalf("%s Export: %v", policy, err)
+		}
+		restored, err := New(Config{Policy: policy, Window: 4, Seed: "test"}, fourMembers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := restored.Restore(raw); err != nil {
+			t.Fatalf("%s Restore: %v", policy, err)
+		}
+		for view := 1; view <= 16; view++ {
+			got, want := restored.Leader(view), e.Leader(view)
+			if got != want {
+				t.Errorf("%s restored Leader(%d) = %d, want %d", policy, view, got, want)
+			}
+			if want < 1 || want > 4 {
+				t.Errorf("%s Leader(%d) = %d is not a member", policy, view, want)
+			}
+		}
+	}
+}
+
+func TestRandomDependsOnBeacon(t *testing.T) {
+	a := newPolicy(t, PolicyRandom)
+	b, err := New(Config{Policy: PolicyRandom, Window: 4, Seed: "test"}, fourMembers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range testHistory() {
+		rec.QC = "other-" + rec.QC
+		b.Observe(rec)
+	}
+	differs := false
+	for view := 9; view <= 16; view++ {
+		differs = differs || a.Leader(view) != b.Leader(view)
+	}
+	if !differs {
+		t.Fatal("random leaders do not depend on the committed QC beacon")
+	}
+}
+
+func TestNewUnknownPolicy(t *testing.T) {
+	if _, err := New(Config{Policy: "nope"}, fourMembers); err == nil {
+		t.Fatal("New accepted an unknown policy")
+	}
+}
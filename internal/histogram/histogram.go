@@ -0,0 +1,183 @@
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"time"
+
+	"mybft/internal/storage"
+)
+
+// 以微秒记录时延，采用 HDR 式对数-线性分桶：3 位有效数字，上限 1 小时。
+const (
+	highestTrackable      = int64(time.Hour / time.Microsecond)
+	subBucketCount        = 2048
+	subBucketHalfCount    = subBucketCount / 2
+	subBucketHalfCountMag = 10
+	subBucketMask         = subBucketCount - 1
+)
+
+var countsLen = func() int {
+	buckets := 1
+	for smallest := int64(subBucketCount); smallest <= highestTrackable; smallest <<= 1 {
+		buckets++
+	}
+	return (buckets + 1) * subBucketHalfCount
+}()
+
+type Histogram struct {
+	counts []int64
+	total  int64
+	min    int64
+	max    int64
+	sum    int64
+}
+
+func New() *Histogram {
+	return &Histogram{counts: make([]int64, countsLen), min: math.MaxInt64}
+}
+
+func countsIndex(v int64) int {
+	pow2Ceiling := 64 - bits.LeadingZeros64(uint64(v|subBucketMask))
+	bucketIdx := pow2Ceiling - (subBucketHalfCountMag + 1)
+	subBucketIdx := int(v >> uint(bucketIdx))
+	return (bucketIdx+1)<<subBucketHalfCountMag + subBucketIdx - subBucketHalfCount
+}
+
+// highestEquivalent 返回与下标 idx 同桶的最大值。
+func highestEquivalent(idx int) int64 {
+	bucketIdx := idx>>subBucketHalfCountMag - 1
+	subBucketIdx := idx&(subBucketHalfCount-1) + subBucketHalfCount
+	if bucketIdx < 0 {
+		subBucketIdx -= subBucketHalfCount
+		bucketIdx = 0
+	}
+	return int64(subBucketIdx)<<uint(bucketIdx) + (int64(1) << uint(bucketIdx)) - 1
+}
+
+// Record 记录一个微秒值，超出范围的值截断到边界。
+func (h *Histogram) Record(us int64) {
+	if us < 0 {
+		us = 0
+	}
+	if us > highestTrackable {
+		us = highestTrackable
+	}
+	h.counts[countsIndex(us)]++
+	h.total++
+	h.sum += us
+	if us < h.min {
+		h.min = us
+	}
+	if us > h.max {
+		h.max = us
+	}
+}
+
+func (h *Histogram) RecordDuration(d time.Duration) {
+	h.Record(int64(d / time.Microsecond))
+}
+
+func (h *Histogram) Count() int64 { return h.total }
+
+func (h *Histogram) Min() int64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.min
+}
+
+func (h *Histogram) Max() int64 { return h.max }
+
+func (h *Histogram) Mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return float64(h.sum) / float64(h.total)
+}
+
+// ValueAtQuantile 返回分位数 q∈[0,1] 对应的值（桶内最大等价值，不超过实际最大值）。
+func (h *Histogram) ValueAtQuantile(q float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	if q > 1 {
+		q = 1
+	}
+	target := int64(math.Ceil(q * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for idx, c := range h.counts {
+		seen += c
+		if seen >= target {
+			v := highestEquivalent(idx)
+			if v > h.max {
+				v = h.max
+			}
+			return v
+		}
+	}
+	return h.max
+}
+
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.total == 0 {
+		return
+	}
+	for idx, c := range other.counts {
+		h.counts[idx] += c
+	}
+	h.total += other.total
+	h.sum += other.sum
+	if other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// Summary 汇总为以秒为单位的统计值。
+func (h *Histogram) Summary() storage.LatencySummary {
+	sec := func(us int64) float64 { return float64(us) / 1e6 }
+	return storage.LatencySummary{
+		Count: h.total,
+		Min:   sec(h.Min()),
+		Max:   sec(h.Max()),
+		Mean:  h.Mean() / 1e6,
+		P50:   sec(h.ValueAtQuantile(0.50)),
+		P90:   sec(h.ValueAtQuantile(0.90)),
+		P99:   sec(h.ValueAtQuantile(0.99)),
+		P999:  sec(h.ValueAtQuantile(0.999)),
+	}
+}
+
+// Export 以稀疏桶形式导出，便于持久化。
+func (h *Histogram) Export() storage.HistogramRecord {
+	record := storage.HistogramRecord{Count: h.total, Min: h.Min(), Max: h.max, Sum: h.sum, Buckets: map[int]int64{}}
+	for idx, c := range h.counts {
+		if c > 0 {
+			record.Buckets[idx] = c
+		}
+	}
+	return record
+}
+
+// Import 从持久化记录恢复直方图，忽略越界的桶。
+func Import(record storage.HistogramRecord) *Histogram {
+	h := New()
+	for idx, c := range record.Buckets {
+		if idx < 0 || idx >= len(h.counts) || c <= 0 {
+			continue
+		}
+		h.counts[idx] += c
+		h.total += c
+	}
+	if h.total == 0 {
+		return h
+	}
+	h.min, h.max, h.sum = record.Min, record.Max, record.Sum
+	return h
+}
@@ -0,0 +1,100 @@
+package histogram
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"mybft/internal/storage"
+)
+
+// within 判断 got 与 want 的相对误差不超过 3 位有效数字的精度。
+func within(got, want int64) bool {
+	return math.Abs(float64(got-want)) <= float64(want)/1000+1
+}
+
+func TestValueAtQuantile(t *testing.T) {
+	h := New()
+	for v := int64(1); v <= 10000; v++ {
+		h.Record(v)
+	}
+	cases := []struct {
+		q    float64
+		want int64
+	}{
+		{0, 1},
+		{0.5, 5000},
+		{0.9, 9000},
+		{0.99, 9900},
+		{0.999, 9990},
+		{1, 10000},
+		{2, 10000},
+	}
+	for _, tc := range cases {
+		if got := h.ValueAtQuantile(tc.q); !within(got, tc.want) {
+			t.Errorf("ValueAtQuantile(%v) = %d, want ~%d", tc.q, got, tc.want)
+		}
+	}
+	if h.Count() != 10000 || h.Min() != 1 || h.Max() != 10000 || h.Mean() != 5000.5 {
+		t.Fatalf("count=%d min=%d max=%d mean=%v", h.Count(), h.Min(), h.Max(), h.Mean())
+	}
+}
+
+func TestRecordClampsAndEmpty(t *testing.T) {
+	h := New()
+	if h.Min() != 0 || h.Max() != 0 || h.Mean() != 0 || h.ValueAtQuantile(0.5) != 0 {
+		t.Fatal("empty histogram should report zeros")
+	}
+	h.Record(-5)
+	h.RecordDuration(2 * time.Hour)
+	if h.Min() != 0 || h.Max() != highestTrackable {
+		t.Fatalf("min=%d max=%d, want 0 and %d", h.Min(), h.Max(), highestTrackable)
+	}
+	if got := h.ValueAtQuantile(1); got != highestTrackable {
+		t.Fatalf("p100 = %d, want %d", got, highestTrackable)
+	}
+}
+
+func TestBucketBoundaries(t *testing.T) {
+	for _, v := range []int64{0, 1, subBucketCount - 1, subBucketCount, 123456, highestTrackable} {
+		idx := countsIndex(v)
+		if idx < 0 || idx >= countsLen {
+			t.Fatalf("countsIndex(%d) = %d out of range [0,%d)", v, idx, countsLen)
+		}
+		if hi := highestEquivalent(idx); hi < v || !within(hi, v) {
+			t.Fatalf("highestEquivalent(countsIndex(%d)) = %d", v, hi)
+		}
+	}
+}
+
+func TestMergeAndExportImport(t *testing.T) {
+	a, b := New(), New()
+	for v := int64(100); v < 200; v++ {
+		a.Record(v)
+	}
+	for v := int64(5000); v < 5100; v++ {
+		b.Record(v)
+	}
+	a.Merge(b)
+	a.Merge(nil)
+	a.Merge(New())
+	if a.Count() != 200 || a.Min() != 100 || a.Max() != 5099 {
+		t.Fatalf("merged count=%d min=%d max=%d", a.Count(), a.Min(), a.Max())
+	}
+
+	restored := Import(a.Export())
+	if !reflect.DeepEqual(restored.Summary(), a.Summary()) {
+		t.Fatalf("Import(Export) summary = %+v, want %+v", restored.Summary(), a.Summary())
+	}
+
+	record := a.Export()
+	record.Buckets[-1] = 7
+	record.Buckets[countsLen] = 7
+	if got := Import(record).Count(); got != a.Count() {
+		t.Fatalf("Import kept out-of-range buckets: count = %d, want %d", got, a.Count())
+	}
+	if empty := Import(storage.HistogramRecord{}); empty.Count() != 0 || empty.Min() != 0 {
+		t.Fatalf("Import(empty) count=%d min=%d", empty.Count(), empty.Min())
+	}
+}
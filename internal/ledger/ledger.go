@@ -0,0 +1,192 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
+	"mybft/internal/storage"
+)
+
+type accountReader interface {
+	account(id int) storage.AccountRecord
+}
+
+// Ledger 为持久化的账户状态机：已提交状态常驻内存，按块原子写入 LevelDB。
+type Ledger struct {
+	mu       sync.RWMutex
+	store    storage.LedgerStore
+	accounts map[int]storage.AccountRecord
+	last     storage.StateRootRecord
+}
+
+// Open 从 LedgerStore 恢复已提交状态；未出现过的账户视为创世余额、nonce=0。
+func Open(store storage.LedgerStore) (*Ledger, error) {
+	l := &Ledger{store: store, accounts: map[int]storage.AccountRecord{}}
+	if store == nil {
+		l.last = storage.StateRootRecord{Root: stateRoot(l)}
+		return l, nil
+	}
+	accounts, err := store.LoadAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		l.accounts[account.ID] = account
+	}
+	last, err := store.LoadLatestStateRoot()
+	if err != nil && !errors.Is(err, goleveldb.ErrNotFound) {
+		return nil, err
+	}
+	if errors.Is(err, goleveldb.ErrNotFound) {
+		last = storage.StateRootRecord{Root: stateRoot(l)}
+	}
+	l.last = last
+	return l, nil
+}
+
+// Account 返回账户在已提交状态下的余额与 nonce；未出现过的账户为创世余额。
+func (l *Ledger) Account(id int) storage.AccountRecord { return l.account(id) }
+
+func (l *Ledger) account(id int) storage.AccountRecord {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.accountLocked(id)
+}
+
+func (l *Ledger) accountLocked(id int) storage.AccountRecord {
+	if account, ok := l.accounts[id]; ok {
+		return account
+	}
+	return storage.AccountRecord{ID: id, Balance: InitialBalance}
+}
+
+// LastRoot 返回最近一次提交后的状态根记录。
+func (l *Ledger) LastRoot() storage.StateRootRecord {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.last
+}
+
+// Root 查询指定高度提交后的状态根，用于节点间比对是否分叉。
+func (l *Ledger) Root(height int) (storage.StateRootRecord, error) {
+	if l.store == nil {
+		return storage.StateRootRecord{}, goleveldb.ErrNotFound
+	}
+	return l.store.LoadStateRoot(height)
+}
+
+// NewOverlay 基于已提交状态创建写时复制视图，用于校验提案或构造交易。
+func (l *Ledger) NewOverlay() *Overlay {
+	return newOverlay(l)
+}
+
+// Commit 把在 Overlay 上执行完的区块结果落账：更新内存状态、计算并持久化该高度的状态根。
+func (l *Ledger) Commit(height int, blockID string, ov *Overlay, applied, skipped int) (storage.StateRootRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if height <= l.last.Height {
+		return l.last, fmt.Errorf("height %d already committed (last %d)", height, l.last.Height)
+	}
+	changed := ov.changes()
+	for _, account := range changed {
+		l.accounts[account.ID] = account
+	}
+	root := storage.StateRootRecord{
+		Height:    height,
+		BlockID:   blockID,
+		Root:      stateRoot(lockedReader{l}),
+		Applied:   applied,
+		Skipped:   skipped,
+		CreatedAt: time.Now().UnixNano(),
+	}
+	if l.store != nil {
+		if err := l.store.SaveBlockState(changed, root); err != nil {
+			return root, err
+		}
+	}
+	l.last = root
+	return root, nil
+}
+
+// Export 导出全部非创世账户的余额与 nonce（用于快照）。
+func (l *Ledger) Export() (map[int]int, map[int]int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	balances := make(map[int]int, len(l.accounts))
+	nonces := make(map[int]int, len(l.accounts))
+	for id, account := range l.accounts {
+		balances[id] = account.Balance
+		nonces[id] = account.Nonce
+	}
+	return balances, nonces
+}
+
+// Restore 用快照中的余额与 nonce 替换本地状态，并校验重算的状态根。
+func (l *Ledger) Restore(height int, blockID string, balances, nonces map[int]int, wantRoot string) (storage.StateRootRecord, error) {
+	accounts := map[int]storage.AccountRecord{}
+	for id, balance := range balances {
+		accounts[id] = storage.AccountRecord{ID: id, Balance: balance, Nonce: nonces[id]}
+	}
+	for id, nonce := range nonces {
+		if _, ok := accounts[id]; !ok {
+			accounts[id] = storage.AccountRecord{ID: id, Balance: InitialBalance, Nonce: nonce}
+		}
+	}
+	restored := &Ledger{accounts: accounts}
+	root := storage.StateRootRecord{
+		Height:    height,
+		BlockID:   blockID,
+		Root:      stateRoot(lockedReader{restored}),
+		CreatedAt: time.Now().UnixNano(),
+	}
+	if wantRoot != "" && root.Root != wantRoot {
+		return root, fmt.Errorf("state root mismatch at height %d: got %s want %s", height, root.Root, wantRoot)
+	}
+	records := make([]storage.AccountRecord, 0, len(accounts))
+	for _, account := range accounts {
+		records = append(records, account)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.store != nil {
+		if err := l.store.ReplaceAccounts(records, root); err != nil {
+			return root, err
+		}
+	}
+	l.accounts = accounts
+	l.last = root
+	return root, nil
+}
+
+// lockedReader 供已持有 Ledger 锁的调用方读取账户。
+type lockedReader struct{ l *Ledger }
+
+func (r lockedReader) account(id int) storage.AccountRecord { return r.l.accountLocked(id) }
+
+// 状态根：按账户 ID 顺序对全部账户的 (id, balance, nonce) 做 SHA-256。
+func stateRoot(r accountReader) string {
+	h := sha256.New()
+	for id := 1; id <= AccountCount; id++ {
+		account := r.account(id)
+		fmt.Fprintf(h, "%d:%d:%d;", id, account.Balance, account.Nonce)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CheckCommitted 做字段检查并返回发送方在已提交状态下的余额与 nonce。
+func (l *Ledger) CheckCommitted(tx Tx) (balance, nonce int, err error) {
+	if err := checkStateless(tx); err != nil {
+		return 0, 0, err
+	}
+	account := l.account(tx.From)
+	return account.Balance, account.Nonce, nil
+}
@@ -0,0 +1,153 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+func openLedger(t *testing.T) *Ledger {
+	t.Helper()
+	l, err := Open(nil)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return l
+}
+
+func TestOverlayCheck(t *testing.T) {
+	l := openLedger(t)
+	ov := l.NewOverlay()
+	cases := []struct {
+		name string
+		line string
+		want error
+	}{
+		{"ok", "1 2 100 1 1", nil},
+		{"malformed", "1 2 x 1 1", ErrMalformedTx},
+		{"self transfer", "1 1 100 1 1", ErrInvalidAccount},
+		{"unknown account", "1 1001 100 1 1", ErrInvalidAccount},
+		{"zero amount", "1 2 0 1 1", ErrInvalidAmount},
+		{"nonce gap", "1 2 100 2 1", ErrBadNonce},
+		{"overdraft", "1 2 100000 1 1", ErrInsufficientBalance},
+		{"amount overflow", "1 2 9223372036854775807 1 1", ErrInvalidAmount},
+		{"fee overflow", "1 2 1 1 9223372036854775807", ErrInvalidAmount},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx, ok := ParseTx(tc.line)
+			var err error
+			if !ok {
+				err = ErrMalformedTx
+			} else {
+				err = ov.Check(tx)
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("Check(%q) = %v, want %v", tc.line, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestOverlayIsolatedUntilCommit(t *testing.T) {
+	l := openLedger(t)
+	genesis := l.LastRoot().Root
+	ov := l.NewOverlay()
+	applied, skipped := ov.ApplyBlock([]string{"1 2 100 1 1", "1 2 100 1 1", "bad", "2 3 50 1 0"})
+	if applied != 2 || skipped != 2 {
+		t.Fatalf("ApplyBlock = (%d, %d), want (2, 2)", applied, skipped)
+	}
+	if balance, nonce := ov.Account(1); balance != InitialBalance-101 || nonce != 1 {
+		t.Fatalf("overlay account 1 = (%d, %d)", balance, nonce)
+	}
+	if account := l.Account(1); account.Balance != InitialBalance || account.Nonce != 0 {
+		t.Fatalf("ledger saw uncommitted write: %+v", account)
+	}
+
+	root, err := l.Commit(1, "b1", ov, applied, skipped)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if root.Root == genesis || root.Height != 1 || root.Applied != 2 || root.Skipped != 2 {
+		t.Fatalf("Commit root = %+v", root)
+	}
+	if account := l.Account(2); account.Balance != InitialBalance+100-50 || account.Nonce != 1 {
+		t.Fatalf("committed account 2 = %+v", account)
+	}
+	if _, err := l.Commit(1, "b1", l.NewOverlay(), 0, 0); err == nil {
+		t.Fatal("Commit accepted a height that is already committed")
+	}
+}
+
+func TestOverlayExecBatchStrict(t *testing.T) {
+	l := openLedger(t)
+	ov := l.NewOverlay()
+	if err := ov.ExecBatch([]string{"1 2 10 1 0", "1 2 10 3 0"}); !errors.Is(err, ErrBadNonce) {
+		t.Fatalf("ExecBatch = %v, want %v", err, ErrBadNonce)
+	}
+	if err := l.NewOverlay().ExecBatch([]string{"1 2 10 1 0", "1 2 10 2 0"}); err != nil {
+		t.Fatalf("ExecBatch: %v", err)
+	}
+}
+
+func TestRestoreMatchesCommittedRoot(t *testing.T) {
+	l := openLedger(t)
+	ov := l.NewOverlay()
+	ov.ApplyBlock([]string{"5 6 700 1 3", "6 7 20 1 0"})
+	root, err := l.Commit(1, "b1", ov, 2, 0)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	balances, nonces := l.Export()
+
+	restored := openLedger(t)
+	got, err := restored.Restore(root.Height, root.BlockID, balances, nonces, root.Root)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got.Root != root.Root {
+		t.Fatalf("restored root = %s, want %s", got.Root, root.Root)
+	}
+	balances[5]++
+	if _, err := openLedger(t).Restore(root.Height, root.BlockID, balances, nonces, root.Root); err == nil {
+		t.Fatal("Restore accepted state that does not match the root")
+	}
+}
+
+func TestForkRebase(t *testing.T) {
+	l := openLedger(t)
+	parent := l.NewOverlay()
+	parent.ApplyBlock([]string{"1 2 100 1 0"})
+	child := parent.Fork()
+	if err := child.ExecLine("1 2 100 2 0"); err != nil {
+		t.Fatalf("child exec on parent state: %v", err)
+	}
+	if balance, _ := parent.Account(2); balance != InitialBalance+100 {
+		t.Fatalf("parent saw child write: balance = %d", balance)
+	}
+
+	if _, err := l.Commit(1, "b1", parent, 1, 0); err != nil {
+		t.Fatalf("Commit parent: %v", err)
+	}
+	child.Rebase(l)
+	if _, err := l.Commit(2, "b2", child, 1, 0); err != nil {
+		t.Fatalf("Commit child: %v", err)
+	}
+	if account := l.Account(1); account.Balance != InitialBalance-200 || account.Nonce != 2 {
+		t.Fatalf("account 1 after both commits = %+v", account)
+	}
+}
+
+func TestOverflowingTransferCannotMint(t *testing.T) {
+	l := openLedger(t)
+	ov := l.NewOverlay()
+	applied, skipped := ov.ApplyBlock([]string{"1 2 9223372036854775807 1 1"})
+	if applied != 0 || skipped != 1 {
+		t.Fatalf("ApplyBlock = (%d, %d), want (0, 1)", applied, skipped)
+	}
+	if balance, nonce := ov.Account(1); balance != InitialBalance || nonce != 0 {
+		t.Fatalf("sender after overflowing transfer = (%d, %d)", balance, nonce)
+	}
+	if _, _, err := l.CheckCommitted(Tx{From: 1, To: 2, Amount: MaxAmount + 1, Nonce: 1}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("CheckCommitted = %v, want %v", err, ErrInvalidAmount)
+	}
+}
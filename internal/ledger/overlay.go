@@ -0,0 +1,110 @@
+package ledger
+
+import (
+	"sort"
+
+	"mybft/internal/storage"
+)
+
+// Overlay 是叠加在已提交状态（或另一个 Overlay）之上的写时复制账户视图。
+type Overlay struct {
+	base     accountReader
+	accounts map[int]storage.AccountRecord
+}
+
+func newOverlay(base accountReader) *Overlay {
+	return &Overlay{base: base, accounts: map[int]storage.AccountRecord{}}
+}
+
+// Fork 在当前视图之上再叠加一层视图，用于在未提交的父块状态上执行子块。
+func (o *Overlay) Fork() *Overlay {
+	return newOverlay(o)
+}
+
+// Rebase 在父视图的写入已提交到 l 之后，把本视图直接叠加到已提交状态上，释放父视图。
+func (o *Overlay) Rebase(l *Ledger) {
+	o.base = l
+}
+
+func (o *Overlay) account(id int) storage.AccountRecord {
+	if account, ok := o.accounts[id]; ok {
+		return account
+	}
+	return o.base.account(id)
+}
+
+// Account 返回账户在当前视图下的余额与 nonce。
+func (o *Overlay) Account(id int) (balance, nonce int) {
+	account := o.account(id)
+	return account.Balance, account.Nonce
+}
+
+// Check 校验交易在当前视图下是否可执行。
+func (o *Overlay) Check(tx Tx) error {
+	if err := checkStateless(tx); err != nil {
+		return err
+	}
+	from := o.account(tx.From)
+	if tx.Nonce != from.Nonce+1 {
+		return ErrBadNonce
+	}
+	if from.Balance < tx.Amount+tx.Fee {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// Exec 校验通过后把交易写入视图。
+func (o *Overlay) Exec(tx Tx) error {
+	if err := o.Check(tx); err != nil {
+		return err
+	}
+	from := o.account(tx.From)
+	to := o.account(tx.To)
+	from.Balance -= tx.Amount + tx.Fee
+	from.Nonce = tx.Nonce
+	to.Balance += tx.Amount
+	o.accounts[from.ID] = from
+	o.accounts[to.ID] = to
+	return nil
+}
+
+// ExecLine 解析并执行一行交易文本。
+func (o *Overlay) ExecLine(line string) error {
+	tx, ok := ParseTx(line)
+	if !ok {
+		return ErrMalformedTx
+	}
+	return o.Exec(tx)
+}
+
+// ExecBatch 严格模式：任意一笔失败即返回错误（用于投票前校验）。
+func (o *Overlay) ExecBatch(lines []string) error {
+	for _, line := range lines {
+		if err := o.ExecLine(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyBlock 宽松模式：跳过非法交易，返回成功与跳过的笔数（用于提交执行）。
+func (o *Overlay) ApplyBlock(lines []string) (applied, skipped int) {
+	for _, line := range lines {
+		if err := o.ExecLine(line); err != nil {
+			skipped++
+			continue
+		}
+		applied++
+	}
+	return applied, skipped
+}
+
+func (o *Overlay) changes() []storage.AccountRecord {
+	out := make([]storage.AccountRecord, 0, len(o.accounts))
+	for _, account := range o.accounts {
+		out = append(out, account)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
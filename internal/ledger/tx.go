@@ -0,0 +1,73 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	AccountCount   = 1000
+	InitialBalance = 100000
+	// MaxAmount 为单笔转账金额与费用各自的上限（即总发行量），二者之和不会溢出。
+	MaxAmount = AccountCount * InitialBalance
+)
+
+var (
+	ErrMalformedTx         = errors.New("malformed tx")
+	ErrInvalidAccount      = errors.New("invalid account")
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrBadNonce            = errors.New("bad nonce")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+// Tx 为模拟转账交易，文本格式为 "from to amount nonce fee [memo]"；
+// memo 不参与执行，仅用于把交易填充到指定大小。
+type Tx struct {
+	From   int
+	To     int
+	Amount int
+	Nonce  int
+	Fee    int
+	Memo   string
+}
+
+func (tx Tx) String() string {
+	if tx.Memo != "" {
+		return fmt.Sprintf("%d %d %d %d %d %s", tx.From, tx.To, tx.Amount, tx.Nonce, tx.Fee, tx.Memo)
+	}
+	return fmt.Sprintf("%d %d %d %d %d", tx.From, tx.To, tx.Amount, tx.Nonce, tx.Fee)
+}
+
+// ParseTx 解析一行交易文本。
+func ParseTx(line string) (Tx, bool) {
+	parts := strings.Fields(line)
+	if len(parts) != 5 && len(parts) != 6 {
+		return Tx{}, false
+	}
+	var vals [5]int
+	for i, p := range parts[:5] {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return Tx{}, false
+		}
+		vals[i] = v
+	}
+	tx := Tx{From: vals[0], To: vals[1], Amount: vals[2], Nonce: vals[3], Fee: vals[4]}
+	if len(parts) == 6 {
+		tx.Memo = parts[5]
+	}
+	return tx, true
+}
+
+// 与账户状态无关的字段检查。
+func checkStateless(tx Tx) error {
+	if tx.From < 1 || tx.From > AccountCount || tx.To < 1 || tx.To > AccountCount || tx.From == tx.To {
+		return ErrInvalidAccount
+	}
+	if tx.Amount <= 0 || tx.Amount > MaxAmount || tx.Fee < 0 || tx.Fee > MaxAmount || tx.Nonce <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package loadgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"mybft/internal/common"
+	"mybft/internal/histogram"
+	"mybft/internal/storage"
+	"mybft/internal/tlsx"
+	"mybft/internal/workload"
+)
+
+const (
+	StatusSubmitted = "submitted"
+	StatusAccepted  = "accepted"
+	StatusRejected  = "rejected"
+	StatusCommitted = "committed"
+	StatusFailed    = "failed"
+	StatusError     = "error"
+)
+
+// Config 描述一次开环负载：按 Rate 笔/秒、每次 Batch 笔轮流向 Nodes 提交，持续 Duration，
+// 停止提交后最多再等待 Drain 让已接收的交易上链。
+type Config struct {
+	Nodes    []string
+	Watch    string
+	App      string
+	Rate     float64
+	Batch    int
+	Duration time.Duration
+	Drain    time.Duration
+	Poll     time.Duration
+	Workload workload.Config
+	// TLS 为 true 时经 https 访问节点，Client 须为出示 client 证书的 HTTP 客户端（见 tlsx.NewHTTPClient）。
+	TLS    bool
+	Client *http.Client
+}
+
+// TxRecord 是单笔交易的端到端记录，时间均为 Unix 纳秒。
+// CommittedAt 为节点执行该区块的时间，ObservedAt 为客户端从 /commits 看到它的时间。
+type TxRecord struct {
+	ID          string `json:"id"`
+	Node        string `json:"node"`
+	SubmittedAt int64  `json:"submitted_at"`
+	CommittedAt int64  `json:"committed_at,omitempty"`
+	ObservedAt  int64  `json:"observed_at,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Code        int    `json:"code"`
+	Log         string `json:"log,omitempty"`
+	Status      string `json:"status"`
+}
+
+// Latency 返回提交到客户端观察到上链的时延；未上链时为 0。
+func (r TxRecord) Latency() time.Duration {
+	if r.ObservedAt == 0 {
+		return 0
+	}
+	return time.Duration(r.ObservedAt - r.SubmittedAt)
+}
+
+// Result 汇总一次负载运行，时延单位为秒。
+// Latency 为提交到客户端观察到上链，CommitLatency 为提交到节点执行（不含轮询间隔）。
+type Result struct {
+	App           string                 `json:"app"`
+	Nodes         []string               `json:"nodes"`
+	Rate          float64                `json:"rate"`
+	Batch         int                    `json:"batch"`
+	Duration      float64                `json:"duration"`
+	StartedAt     time.Time              `json:"started_at"`
+	Submitted     int                    `json:"submitted"`
+	Accepted      int                    `json:"accepted"`
+	Rejected      int                    `json:"rejected"`
+	Committed     int                    `json:"committed"`
+	Failed        int                    `json:"failed"`
+	Pending       int                    `json:"pending"`
+	Errors        int                    `json:"errors"`
+	Span          float64                `json:"span"`
+	Throughput    float64                `json:"throughput"`
+	Latency       storage.LatencySummary `json:"latency"`
+	CommitLatency storage.LatencySummary `json:"commit_latency"`
+	Rejections    map[string]int         `json:"rejections,omitempty"`
+}
+
+type runner struct {
+	cfg    Config
+	client *http.Client
+	gen    *workload.Generator
+	state  *accountState
+
+	mu            sync.Mutex
+	txs           map[string]*TxRecord
+	order         []string
+	outstanding   int
+	latency       *histogram.Histogram
+	commitLatency *histogram.Histogram
+	lastObserved  time.Time
+}
+
+// Run 执行一次负载并返回汇总与逐笔记录（按提交顺序）。
+func Run(cfg Config) (Result, []TxRecord, error) {
+	if len(cfg.Nodes) == 0 {
+		return Result{}, nil, fmt.Errorf("no nodes to submit to")
+	}
+	if cfg.Watch == "" {
+		cfg.Watch = cfg.Nodes[0]
+	}
+	if cfg.Rate <= 0 || cfg.Batch <= 0 {
+		return Result{}, nil, fmt.Errorf("rate and batch must be positive")
+	}
+	if cfg.Poll <= 0 {
+		cfg.Poll = 50 * time.Millisecond
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	r := &runner{
+		cfg:           cfg,
+		client:        cfg.Client,
+		gen:           workload.New(cfg.Workload),
+		txs:           map[string]*TxRecord{},
+		latency:       histogram.New(),
+		commitLatency: histogram.New(),
+	}
+	if cfg.App == "" || cfg.App == "transfer" {
+		state, err := r.loadAccounts()
+		if err != nil {
+			return Result{}, nil, fmt.Errorf("load accounts from %s: %w", cfg.Watch, err)
+		}
+		r.state = state
+	}
+	var cursor common.CommitsResponse
+	if err := r.getJSON(r.url(cfg.Watch, "/commits?limit=1"), &cursor); err != nil {
+		return Result{}, nil, fmt.Errorf("read commits from %s: %w", cfg.Watch, err)
+	}
+
+	started := time.Now()
+	stopWatch := make(chan struct{})
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		r.watch(cursor.Latest+1, stopWatch)
+	}()
+
+	var submits sync.WaitGroup
+	interval := time.Duration(float64(cfg.Batch) / cfg.Rate * float64(time.Second))
+	ticker := time.NewTicker(interval)
+	deadline := started.Add(cfg.Duration)
+	for round := 1; time.Now().Before(deadline); round++ {
+		txs := r.generate(round)
+		node := cfg.Nodes[(round-1)%len(cfg.Nodes)]
+		submits.Add(1)
+		go func() {
+			defer submits.Done()
+			r.submit(node, txs)
+		}()
+		<-ticker.C
+	}
+	ticker.Stop()
+	submits.Wait()
+
+	drainUntil := time.Now().Add(cfg.Drain)
+	for time.Now().Before(drainUntil) {
+		r.mu.Lock()
+		outstanding := r.outstanding
+		r.mu.Unlock()
+		if outstanding == 0 {
+			break
+		}
+		time.Sleep(cfg.Poll)
+	}
+	close(stopWatch)
+	<-watchDone
+	return r.result(started), r.records(), nil
+}
+
+// generate 生成第 round 轮的交易；转账在本地账户视图上续写 nonce，保证同一发送方的交易依次可执行。
+func (r *runner) generate(round int) []string {
+	if r.state == nil {
+		return r.gen.KVWrites(round, r.cfg.Batch)
+	}
+	return r.gen.Transfers(round, r.cfg.Batch, r.state)
+}
+
+// submit 登记提交时间后把一批交易 POST 到节点的 /tx，并按返回结果更新状态。
+func (r *runner) submit(node string, txs []string) {
+	if len(txs) == 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	recs := make([]*TxRecord, len(txs))
+	r.mu.Lock()
+	for i, tx := range txs {
+		id := common.TxID(tx)
+		recs[i] = &TxRecord{ID: id, Node: node, SubmittedAt: now, Status: StatusSubmitted}
+		r.txs[id] = recs[i]
+		r.order = append(r.order, id)
+		r.outstanding++
+	}
+	r.mu.Unlock()
+
+	var resp common.TxSubmitResponse
+	err := r.postJSON(r.url(node, "/tx"), common.TxSubmitRequest{Txs: txs}, &resp)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		log.Printf("loadgen submit to %s: %v", node, err)
+		for _, rec := range recs {
+			r.settle(rec, StatusError, err.Error())
+		}
+		return
+	}
+	for _, res := range resp.Results {
+		rec, ok := r.txs[res.ID]
+		if !ok || rec.Status != StatusSubmitted {
+			continue
+		}
+		if res.Code != 0 {
+			rec.Code = res.Code
+			r.settle(rec, StatusRejected, res.Log)
+			continue
+		}
+		rec.Status = StatusAccepted
+	}
+}
+
+// settle 把尚未结束的交易置为终态，调用方需持有 r.mu。
+func (r *runner) settle(rec *TxRecord, status, msg string) {
+	if rec.Status != StatusSubmitted && rec.Status != StatusAccepted {
+		return
+	}
+	rec.Status, rec.Log = status, msg
+	r.outstanding--
+}
+
+// watch 每隔 Poll 从 Watch 节点按高度游标拉取提交通知，直到 stop 关闭后再做最后一次拉取。
+func (r *runner) watch(from int, stop <-chan struct{}) {
+	ticker := time.NewTicker(r.cfg.Poll)
+	defer ticker.Stop()
+	for {
+		from = r.poll(from)
+		select {
+		case <-stop:
+			r.poll(from)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *runner) poll(from int) int {
+	for {
+		var resp common.CommitsResponse
+		if err := r.getJSON(r.url(r.cfg.Watch, fmt.Sprintf("/commits?from=%d", from)), &resp); err != nil {
+			log.Printf("loadgen poll commits: %v", err)
+			return from
+		}
+		if resp.Oldest > from {
+			log.Printf("loadgen commits gap: want height %d, node keeps %d..%d", from, resp.Oldest, resp.Latest)
+		}
+		if len(resp.Entries) == 0 {
+			return from
+		}
+		observed := time.Now()
+		r.mu.Lock()
+		for _, entry := range resp.Entries {
+			for _, tx := range entry.Txs {
+				rec, ok := r.txs[tx.ID]
+				if !ok || (rec.Status != StatusSubmitted && rec.Status != StatusAccepted) {
+					continue
+				}
+				rec.Height, rec.Code = entry.Height, tx.Code
+				rec.CommittedAt, rec.ObservedAt = entry.CommittedAt, observed.UnixNano()
+				if tx.Code != 0 {
+					r.settle(rec, StatusFailed, "")
+					continue
+				}
+				r.settle(rec, StatusCommitted, "")
+				r.latency.RecordDuration(rec.Latency())
+				r.commitLatency.RecordDuration(time.Duration(rec.CommittedAt - rec.SubmittedAt))
+				r.lastObserved = observed
+			}
+			from = entry.Height + 1
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *runner) result(started time.Time) Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := Result{
+		App: r.cfg.App, Nodes: r.cfg.Nodes, Rate: r.cfg.Rate, Batch: r.cfg.Batch,
+		Duration: r.cfg.Duration.Seconds(), StartedAt: started, Submitted: len(r.order),
+		Latency: r.latency.Summary(), CommitLatency: r.commitLatency.Summary(),
+	}
+	for _, id := range r.order {
+		rec := r.txs[id]
+		switch rec.Status {
+		case StatusRejected:
+			res.Rejected++
+			if res.Rejections == nil {
+				res.Rejections = map[string]int{}
+			}
+			res.Rejections[rec.Log]++
+		case StatusError:
+			res.Errors++
+		case StatusCommitted:
+			res.Committed++
+		case StatusFailed:
+			res.Failed++
+		default:
+			res.Pending++
+		}
+	}
+	res.Accepted = res.Submitted - res.Rejected - res.Errors
+	if !r.lastObserved.IsZero() {
+		res.Span = r.lastObserved.Sub(started).Seconds()
+		if res.Span > 0 {
+			res.Throughput = float64(res.Committed) / res.Span
+		}
+	}
+	return res
+}
+
+func (r *runner) records() []TxRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]TxRecord, len(r.order))
+	for i, id := range r.order {
+		out[i] = *r.txs[id]
+	}
+	return out
+}
+
+func (r *runner) url(node, path string) string {
+	return tlsx.Scheme(r.cfg.TLS) + "://" + node + path
+}
+
+func (r *runner) getJSON(url string, out any) error {
+	resp, err := r.client.Get(url)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+func (r *runner) postJSON(url string, body, out any) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(url, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("%s %s: %s", resp.Request.Method, resp.Request.URL, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,47 @@
+package loadgen
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WriteResult 把汇总写成带缩进的 JSON。
+func WriteResult(path string, res Result) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// WriteTxs 把逐笔记录写成 CSV，latency 列为提交到客户端观察到上链的秒数。
+func WriteTxs(path string, records []TxRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"id", "node", "status", "code", "height", "submitted_at", "committed_at", "observed_at", "latency", "log"}); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		row := []string{rec.ID, rec.Node, rec.Status, strconv.Itoa(rec.Code), strconv.Itoa(rec.Height),
+			strconv.FormatInt(rec.SubmittedAt, 10), strconv.FormatInt(rec.CommittedAt, 10), strconv.FormatInt(rec.ObservedAt, 10),
+			strconv.FormatFloat(rec.Latency().Seconds(), 'f', 6, 64), rec.Log}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,53 @@
+package loadgen
+
+import "mybft/internal/ledger"
+
+// accountState 是负载生成器本地维护的账户视图：以节点已提交状态为起点，
+// 依次叠加已生成的转账，使同一发送方的 nonce 连续递增。
+type accountState struct {
+	balances map[int]int
+	nonces   map[int]int
+}
+
+// Account 实现 workload.State；未出现过的账户为创世余额。
+func (a *accountState) Account(id int) (balance, nonce int) {
+	balance, ok := a.balances[id]
+	if !ok {
+		balance = ledger.InitialBalance
+	}
+	return balance, a.nonces[id]
+}
+
+// Exec 实现 workload.State，规则与 ledger.Overlay.Exec 一致。
+func (a *accountState) Exec(tx ledger.Tx) error {
+	balance, nonce := a.Account(tx.From)
+	if tx.Nonce != nonce+1 {
+		return ledger.ErrBadNonce
+	}
+	if balance < tx.Amount+tx.Fee {
+		return ledger.ErrInsufficientBalance
+	}
+	to, _ := a.Account(tx.To)
+	a.balances[tx.From] = balance - tx.Amount - tx.Fee
+	a.nonces[tx.From] = tx.Nonce
+	a.balances[tx.To] = to + tx.Amount
+	return nil
+}
+
+// loadAccounts 通过 /query?path=accounts 读取节点的已提交账户状态。
+func (r *runner) loadAccounts() (*accountState, error) {
+	var state struct {
+		Balances map[int]int `json:"balances"`
+		Nonces   map[int]int `json:"nonces"`
+	}
+	if err := r.getJSON(r.url(r.cfg.Watch, "/query?path=accounts"), &state); err != nil {
+		return nil, err
+	}
+	if state.Balances == nil {
+		state.Balances = map[int]int{}
+	}
+	if state.Nonces == nil {
+		state.Nonces = map[int]int{}
+	}
+	return &accountState{balances: state.Balances, nonces: state.Nonces}, nil
+}
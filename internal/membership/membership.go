@@ -0,0 +1,241 @@
+package membership
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 成员变更交易的前缀，格式为 "reconfig add <id> [addr]" 或 "reconfig remove <id>"。
+const TxPrefix = "reconfig "
+
+const (
+	OpAdd    = "add"
+	OpRemove = "remove"
+)
+
+// Change 是一条成员变更交易的内容；Addr 为空时使用默认地址 127.0.0.1:(basePort+id)。
+type Change struct {
+	Op   string `json:"op"`
+	ID   int    `json:"id"`
+	Addr string `json:"addr,omitempty"`
+}
+
+// IsTx 判断交易是否为成员变更交易（不经过应用）。
+func IsTx(tx string) bool {
+	return strings.HasPrefix(tx, TxPrefix)
+}
+
+// ParseTx 解析成员变更交易，只做语法检查。
+func ParseTx(tx string) (Change, error) {
+	if !IsTx(tx) {
+		return Change{}, fmt.Errorf("not a reconfig tx: %q", tx)
+	}
+	fields := strings.Fields(strings.TrimPrefix(tx, TxPrefix))
+	if len(fields) < 2 {
+		return Change{}, fmt.Errorf("malformed reconfig tx %q", tx)
+	}
+	id, err := strconv.Atoi(fields[1])
+	if err != nil || id < 1 {
+		return Change{}, fmt.Errorf("invalid validator id in %q", tx)
+	}
+	c := Change{Op: fields[0], ID: id}
+	switch {
+	case c.Op == OpAdd && len(fields) <= 3:
+		if len(fields) == 3 {
+			c.Addr = fields[2]
+		}
+	case c.Op == OpRemove && len(fields) == 2:
+	default:
+		return Change{}, fmt.Errorf("malformed reconfig tx %q", tx)
+	}
+	return c, nil
+}
+
+// Tx 返回变更的交易文本。
+func (c Change) Tx() string {
+	tx := fmt.Sprintf("%s%s %d", TxPrefix, c.Op, c.ID)
+	if c.Addr != "" {
+		tx += " " + c.Addr
+	}
+	return tx
+}
+
+// Epoch 是自高度 From 起生效的验证者集合（升序）。
+type Epoch struct {
+	From       int   `json:"from"`
+	Validators []int `json:"validators"`
+}
+
+// Set 记录验证者集合的变更历史：成员变更在提交高度之后的固定延迟处生效，
+// 诚实节点按已提交的变更得出相同的逐 view 集合。
+type Set struct {
+	mu     sync.RWMutex
+	epochs []Epoch
+	addrs  map[int]string
+}
+
+type exported struct {
+	Epochs []Epoch        `json:"epochs"`
+	Addrs  map[int]string `json:"addrs,omitempty"`
+}
+
+// Genesis 返回 1..n 组成的初始集合。
+func Genesis(n int) *Set {
+	validators := make([]int, n)
+	for i := range validators {
+		validators[i] = i + 1
+	}
+	return &Set{epochs: []Epoch{{From: 1, Validators: validators}}, addrs: map[int]string{}}
+}
+
+func (s *Set) epochAt(view int) Epoch {
+	i := sort.Search(len(s.epochs), func(i int) bool { return s.epochs[i].From > view })
+	if i == 0 {
+		return s.epochs[0]
+	}
+	return s.epochs[i-1]
+}
+
+// Validators 返回 view（与高度一致）下的验证者集合。
+func (s *Set) Validators(view int) []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]int(nil), s.epochAt(view).Validators...)
+}
+
+// Contains 判断 id 在 view 下是否为验证者。
+func (s *Set) Contains(view, id int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.epochAt(view).Validators {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest 返回已安排的最后一个集合及其生效高度，新的变更在其上校验。
+func (s *Set) Latest() Epoch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	last := s.epochs[len(s.epochs)-1]
+	return Epoch{From: last.From, Validators: append([]int(nil), last.Validators...)}
+}
+
+// Known 返回曾出现在任一集合中的节点（升序），用于维护同伴地址与密钥。
+func (s *Set) Known() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := map[int]bool{}
+	var out []int
+	for _, e := range s.epochs {
+		for _, id := range e.Validators {
+			if !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Addr 返回变更交易为节点指定的地址，未指定时为空。
+func (s *Set) Addr(id int) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.addrs[id]
+}
+
+// apply 返回在 validators 上应用变更后的集合；添加已存在的节点、移除不存在的节点或移除最后一个节点时返回错误。
+func apply(validators []int, c Change) ([]int, error) {
+	member := false
+	for _, id := range validators {
+		member = member || id == c.ID
+	}
+	var next []int
+	switch c.Op {
+	case OpAdd:
+		if member {
+			return nil, fmt.Errorf("node %d is already a validator", c.ID)
+		}
+		next = append(append([]int(nil), validators...), c.ID)
+		sort.Ints(next)
+	case OpRemove:
+		if !member {
+			return nil, fmt.Errorf("node %d is not a validator", c.ID)
+		}
+		if len(validators) == 1 {
+			return nil, fmt.Errorf("cannot remove the last validator")
+		}
+		for _, id := range validators {
+			if id != c.ID {
+				next = append(next, id)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown reconfig op %q", c.Op)
+	}
+	return next, nil
+}
+
+// Check 判断变更能否应用在最新集合上，不修改集合；提交时仍以 Schedule 的结果为准。
+func (s *Set) Check(c Change) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := apply(s.epochs[len(s.epochs)-1].Validators, c)
+	return err
+}
+
+// Schedule 在最新集合上应用变更，自高度 from 起生效；from 不得早于最新集合的生效高度。
+// 变更无效时返回错误，集合不变。
+func (s *Set) Schedule(from int, c Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last := s.epochs[len(s.epochs)-1]
+	if from < last.From {
+		return fmt.Errorf("activation height %d precedes scheduled epoch %d", from, last.From)
+	}
+	next, err := apply(last.Validators, c)
+	if err != nil {
+		return err
+	}
+	if c.Addr != "" {
+		s.addrs[c.ID] = c.Addr
+	}
+	if from == last.From {
+		s.epochs[len(s.epochs)-1].Validators = next
+		return nil
+	}
+	s.epochs = append(s.epochs, Epoch{From: from, Validators: next})
+	return nil
+}
+
+// Export / Restore 用于持久化与快照引导。
+func (s *Set) Export() (json.RawMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return json.Marshal(exported{Epochs: s.epochs, Addrs: s.addrs})
+}
+
+func (s *Set) Restore(raw json.RawMessage) error {
+	var v exported
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	if len(v.Epochs) == 0 {
+		return fmt.Errorf("membership without epochs")
+	}
+	if v.Addrs == nil {
+		v.Addrs = map[int]string{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.epochs, s.addrs = v.Epochs, v.Addrs
+	return nil
+}
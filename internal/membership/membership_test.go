@@ -0,0 +1,166 @@
+package membership
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scheduled 返回初始为 1..4、自高度 5 起加入 5、自高度 9 起移除 2 的集合。
+func scheduled(t *testing.T) *Set {
+	t.Helper()
+	s := Genesis(4)
+	if err := s.Schedule(5, Change{Op: OpAdd, ID: 5, Addr: "10.0.0.5:9005"}); err != nil {
+		t.Fatalf("schedule add: %v", err)
+	}
+	if err := s.Schedule(9, Change{Op: OpRemove, ID: 2}); err != nil {
+		t.Fatalf("schedule remove: %v", err)
+	}
+	return s
+}
+
+func TestEpochAtBoundaries(t *testing.T) {
+	s := scheduled(t)
+	cases := []struct {
+		view int
+		want []int
+	}{
+		{0, []int{1, 2, 3, 4}},
+		{1, []int{1, 2, 3, 4}},
+		{4, []int{1, 2, 3, 4}},
+		{5, []int{1, 2, 3, 4, 5}},
+		{8, []int{1, 2, 3, 4, 5}},
+		{9, []int{1, 3, 4, 5}},
+		{100, []int{1, 3, 4, 5}},
+	}
+	for _, tc := range cases {
+		if got := s.Validators(tc.view); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Validators(%d) = %v, want %v", tc.view, got, tc.want)
+		}
+		for _, id := range []int{2, 5} {
+			want := false
+			for _, v := range tc.want {
+				want = want || v == id
+			}
+			if got := s.Contains(tc.view, id); got != want {
+				t.Errorf("Contains(%d, %d) = %v, want %v", tc.view, id, got, want)
+			}
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	cases := []struct {
+		name   string
+		from   int
+		change Change
+		err    string
+		want   []Epoch
+	}{
+		{
+			name:   "same height merges into latest epoch",
+			from:   9,
+			change: Change{Op: OpAdd, ID: 6},
+			want:   []Epoch{{1, []int{1, 2, 3, 4}}, {5, []int{1, 2, 3, 4, 5}}, {9, []int{1, 3, 4, 5, 6}}},
+		},
+		{
+			name:   "later height appends epoch",
+			from:   12,
+			change: Change{Op: OpRemove, ID: 1},
+			want:   []Epoch{{1, []int{1, 2, 3, 4}}, {5, []int{1, 2, 3, 4, 5}}, {9, []int{1, 3, 4, 5}}, {12, []int{3, 4, 5}}},
+		},
+		{
+			name:   "earlier than latest epoch",
+			from:   8,
+			change: Change{Op: OpAdd, ID: 6},
+			err:    "precedes",
+		},
+		{
+			name:   "checked against latest set",
+			from:   12,
+			change: Change{Op: OpRemove, ID: 2},
+			err:    "not a validator",
+		},
+		{
+			name:   "add existing",
+			from:   12,
+			change: Change{Op: OpAdd, ID: 5},
+			err:    "already a validator",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := scheduled(t)
+			before := s.Latest()
+			err := s.Schedule(tc.from, tc.change)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Schedule error = %v, want %q", err, tc.err)
+				}
+				if got := s.Latest(); !reflect.DeepEqual(got, before) {
+					t.Fatalf("failed Schedule changed latest epoch to %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Schedule: %v", err)
+			}
+			if !reflect.DeepEqual(s.epochs, tc.want) {
+				t.Fatalf("epochs = %+v, want %+v", s.epochs, tc.want)
+			}
+		})
+	}
+}
+
+func TestRemoveLastValidator(t *testing.T) {
+	s := Genesis(1)
+	if err := s.Check(Change{Op: OpRemove, ID: 1}); err == nil {
+		t.Fatal("Check allowed removing the last validator")
+	}
+}
+
+func TestExportRestore(t *testing.T) {
+	s := scheduled(t)
+	raw, err := s.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &Set{}
+	if err := restored.Restore(raw); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if !reflect.DeepEqual(restored.epochs, s.epochs) || restored.Addr(5) != "10.0.0.5:9005" {
+		t.Fatalf("restored = %+v addr=%q", restored.epochs, restored.Addr(5))
+	}
+	if got, want := restored.Known(), []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Known = %v, want %v", got, want)
+	}
+	if err := (&Set{}).Restore([]byte(`{"epochs":[]}`)); err == nil {
+		t.Fatal("Restore accepted membership without epochs")
+	}
+}
+
+func TestParseTx(t *testing.T) {
+	cases := []struct {
+		tx   string
+		want Change
+		ok   bool
+	}{
+		{"reconfig add 5", Change{Op: OpAdd, ID: 5}, true},
+		{"reconfig add 5 10.0.0.5:9005", Change{Op: OpAdd, ID: 5, Addr: "10.0.0.5:9005"}, true},
+		{"reconfig remove 2", Change{Op: OpRemove, ID: 2}, true},
+		{"reconfig remove 2 extra", Change{}, false},
+		{"reconfig add 0", Change{}, false},
+		{"reconfig swap 1", Change{}, false},
+		{"1 2 3 4 5", Change{}, false},
+	}
+	for _, tc := range cases {
+		got, err := ParseTx(tc.tx)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("ParseTx(%q) = %+v, %v", tc.tx, got, err)
+		}
+		if tc.ok && got.Tx() != tc.tx {
+			t.Errorf("Tx() = %q, want %q", got.Tx(), tc.tx)
+		}
+	}
+}
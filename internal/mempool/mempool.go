@@ -0,0 +1,227 @@
+package mempool
+
+import (
+	"container/heap"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"mybft/internal/app"
+	"mybft/internal/common"
+)
+
+var (
+	ErrDuplicate     = errors.New("tx already in mempool")
+	ErrNonceConflict = errors.New("nonce already pending with higher or equal fee")
+	ErrFull          = errors.New("mempool full and fee too low")
+	ErrCheckTx       = errors.New("check tx failed")
+)
+
+// Checker 为交易池提供 CheckTx 校验（通常就是 app.Application）。
+type Checker interface {
+	CheckTx(tx string) app.CheckTxResult
+}
+
+type Entry struct {
+	ID       string
+	Tx       string
+	Sender   string
+	Nonce    int
+	Priority int
+	AddedAt  time.Time
+}
+
+// Mempool 按发送方维护 nonce 有序队列；容量满时淘汰费用最低的队尾交易。
+type Mempool struct {
+	mu       sync.Mutex
+	capacity int
+	checker  Checker
+	senders  map[string][]*Entry
+	byID     map[string]*Entry
+	size     int
+	// arrived 在下一笔交易入池时关闭并换新。
+	arrived chan struct{}
+}
+
+func New(capacity int, checker Checker) *Mempool {
+	return &Mempool{
+		capacity: capacity,
+		checker:  checker,
+		senders:  map[string][]*Entry{},
+		byID:     map[string]*Entry{},
+		arrived:  make(chan struct{}),
+	}
+}
+
+// Arrivals 返回在下一笔交易入池时关闭的通道；等待攒批的一方先取通道再检查 Size，避免漏掉通知。
+func (m *Mempool) Arrivals() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.arrived
+}
+
+// Size 返回当前池中交易数。
+func (m *Mempool) Size() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.size
+}
+
+// Add 校验并加入一笔交易；同一发送方同一 nonce 仅在费用更高时替换。
+func (m *Mempool) Add(tx string) (*Entry, app.CheckTxResult, error) {
+	res := m.checker.CheckTx(tx)
+	if !res.OK() {
+		return nil, res, ErrCheckTx
+	}
+	entry := &Entry{ID: common.TxID(tx), Tx: tx, Sender: res.Sender, Nonce: res.Nonce, Priority: res.Priority, AddedAt: time.Now()}
+	if entry.Sender == "" {
+		entry.Sender = "tx:" + entry.ID
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.byID[entry.ID]; ok {
+		return nil, res, ErrDuplicate
+	}
+	queue := m.senders[entry.Sender]
+	idx := sort.Search(len(queue), func(i int) bool { return queue[i].Nonce >= entry.Nonce })
+	if idx < len(queue) && queue[idx].Nonce == entry.Nonce {
+		old := queue[idx]
+		if entry.Priority <= old.Priority {
+			return nil, res, ErrNonceConflict
+		}
+		delete(m.byID, old.ID)
+		queue[idx] = entry
+		m.byID[entry.ID] = entry
+		return entry, res, nil
+	}
+	if m.capacity > 0 && m.size >= m.capacity {
+		if !m.evictLocked(entry) {
+			return nil, res, ErrFull
+		}
+		queue = m.senders[entry.Sender]
+		idx = sort.Search(len(queue), func(i int) bool { return queue[i].Nonce >= entry.Nonce })
+	}
+	queue = append(queue, nil)
+	copy(queue[idx+1:], queue[idx:])
+	queue[idx] = entry
+	m.senders[entry.Sender] = queue
+	m.byID[entry.ID] = entry
+	m.size++
+	close(m.arrived)
+	m.arrived = make(chan struct{})
+	return entry, res, nil
+}
+
+// 淘汰其他发送方中费用最低的队尾交易（只淘汰队尾以免打断 nonce 连续性），新交易费用不高于它时拒绝。
+// 新交易发送方自己的队列不参与淘汰：新交易可能插在队尾之前，淘汰其队尾会留下 nonce 空洞。
+func (m *Mempool) evictLocked(incoming *Entry) bool {
+	var victim *Entry
+	for sender, queue := range m.senders {
+		if sender == incoming.Sender {
+			continue
+		}
+		tail := queue[len(queue)-1]
+		if victim == nil || tail.Priority < victim.Priority || (tail.Priority == victim.Priority && tail.AddedAt.After(victim.AddedAt)) {
+			victim = tail
+		}
+	}
+	if victim == nil || victim.Priority >= incoming.Priority {
+		return false
+	}
+	m.removeLocked(victim)
+	return true
+}
+
+func (m *Mempool) removeLocked(entry *Entry) {
+	queue := m.senders[entry.Sender]
+	for i, e := range queue {
+		if e.ID != entry.ID {
+			continue
+		}
+		queue = append(queue[:i], queue[i+1:]...)
+		break
+	}
+	if len(queue) == 0 {
+		delete(m.senders, entry.Sender)
+	} else {
+		m.senders[entry.Sender] = queue
+	}
+	delete(m.byID, entry.ID)
+	m.size--
+}
+
+// Reap 取出至多 max 笔交易组成提案候选：各发送方按 nonce 顺序出队，
+// 发送方之间按队首费用从高到低交替选取。exclude 中的交易（已在待提交区块中）跳过。
+func (m *Mempool) Reap(max int, exclude map[string]struct{}) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := &senderHeap{}
+	for _, queue := range m.senders {
+		*h = append(*h, &cursor{queue: queue})
+	}
+	heap.Init(h)
+	out := make([]string, 0)
+	for h.Len() > 0 && (max <= 0 || len(out) < max) {
+		c := (*h)[0]
+		entry := c.queue[c.pos]
+		if _, skip := exclude[entry.ID]; !skip {
+			out = append(out, entry.Tx)
+		}
+		c.pos++
+		if c.pos >= len(c.queue) {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return out
+}
+
+// Update 在区块提交后移除已上链交易，并只对这些交易的发送方在池中的剩余交易重新 CheckTx，
+// 清除已失效的部分（nonce 已用或余额不足）；其他发送方的交易不受本区块影响。
+func (m *Mempool) Update(committed []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	touched := map[string]struct{}{}
+	for _, tx := range committed {
+		if entry, ok := m.byID[common.TxID(tx)]; ok {
+			touched[entry.Sender] = struct{}{}
+			m.removeLocked(entry)
+		} else if sender := m.checker.CheckTx(tx).Sender; sender != "" {
+			touched[sender] = struct{}{}
+		}
+	}
+	for sender := range touched {
+		for _, entry := range append([]*Entry(nil), m.senders[sender]...) {
+			if !m.checker.CheckTx(entry.Tx).OK() {
+				m.removeLocked(entry)
+			}
+		}
+	}
+}
+
+type cursor struct {
+	queue []*Entry
+	pos   int
+}
+
+type senderHeap []*cursor
+
+func (h senderHeap) Len() int { return len(h) }
+func (h senderHeap) Less(i, j int) bool {
+	a, b := h[i].queue[h[i].pos], h[j].queue[h[j].pos]
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.AddedAt.Before(b.AddedAt)
+}
+func (h senderHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *senderHeap) Push(x any)   { *h = append(*h, x.(*cursor)) }
+func (h *senderHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
@@ -0,0 +1,180 @@
+package mempool
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mybft/internal/app"
+)
+
+// stubChecker 按 "sender:nonce:fee" 解析交易；nonce 不大于 used[sender] 的交易视为失效。
+type stubChecker struct {
+	used  map[string]int
+	calls int
+}
+
+func (c *stubChecker) CheckTx(tx string) app.CheckTxResult {
+	c.calls++
+	var sender string
+	var nonce, fee int
+	parts := strings.Split(tx, ":")
+	if len(parts) != 3 {
+		return app.CheckTxResult{Code: app.CodeMalformed}
+	}
+	sender = parts[0]
+	if _, err := fmt.Sscanf(parts[1]+" "+parts[2], "%d %d", &nonce, &fee); err != nil {
+		return app.CheckTxResult{Code: app.CodeMalformed}
+	}
+	res := app.CheckTxResult{Sender: sender, Nonce: nonce, Priority: fee}
+	if nonce <= c.used[sender] {
+		res.Code = app.CodeRejected
+	}
+	return res
+}
+
+func newTestPool(capacity int, txs ...string) (*Mempool, *stubChecker) {
+	checker := &stubChecker{used: map[string]int{}}
+	m := New(capacity, checker)
+	for _, tx := range txs {
+		if _, _, err := m.Add(tx); err != nil {
+			panic(fmt.Sprintf("add %s: %v", tx, err))
+		}
+	}
+	return m, checker
+}
+
+func TestAddEviction(t *testing.T) {
+	cases := []struct {
+		name    string
+		pool    []string
+		add     string
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "evicts lowest fee tail of another sender",
+			pool: []string{"a:1:5", "b:1:1"},
+			add:  "c:1:3",
+			want: []string{"a:1:5", "c:1:3"},
+		},
+		{
+			name:    "rejects when fee not higher than victim",
+			pool:    []string{"a:1:5", "b:1:2"},
+			add:     "c:1:2",
+			wantErr: ErrFull,
+			want:    []string{"a:1:5", "b:1:2"},
+		},
+		{
+			name:    "never evicts from incoming sender's queue",
+			pool:    []string{"a:1:9", "a:3:1"},
+			add:     "a:2:5",
+			wantErr: ErrFull,
+			want:    []string{"a:1:9", "a:3:1"},
+		},
+		{
+			name: "skips incoming sender when choosing victim",
+			pool: []string{"a:1:9", "a:3:1", "b:1:2"},
+			add:  "a:2:5",
+			want: []string{"a:1:9", "a:2:5", "a:3:1"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _ := newTestPool(len(tc.pool), tc.pool...)
+			if _, _, err := m.Add(tc.add); err != tc.wantErr {
+				t.Fatalf("Add(%s) error = %v, want %v", tc.add, err, tc.wantErr)
+			}
+			if got := m.Reap(0, nil); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Reap = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddNonceReplacement(t *testing.T) {
+	m, _ := newTestPool(0, "a:1:2")
+	if _, _, err := m.Add("a:1:2"); err != ErrDuplicate {
+		t.Fatalf("duplicate error = %v, want %v", err, ErrDuplicate)
+	}
+	if _, _, err := m.Add("a:1:02"); err != ErrNonceConflict {
+		t.Fatalf("equal fee error = %v, want %v", err, ErrNonceConflict)
+	}
+	if _, _, err := m.Add("a:1:3"); err != nil {
+		t.Fatalf("higher fee: %v", err)
+	}
+	if got, want := m.Reap(0, nil), []string{"a:1:3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Reap = %v, want %v", got, want)
+	}
+}
+
+func TestReapOrder(t *testing.T) {
+	m, _ := newTestPool(0, "a:2:9", "a:1:1", "b:1:5", "c:1:3")
+	want := []string{"b:1:5", "c:1:3", "a:1:1", "a:2:9"}
+	if got := m.Reap(0, nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Reap = %v, want %v", got, want)
+	}
+	if got := m.Reap(2, map[string]struct{}{}); !reflect.DeepEqual(got, want[:2]) {
+		t.Fatalf("Reap(2) = %v, want %v", got, want[:2])
+	}
+}
+
+func TestUpdateRechecksTouchedSenders(t *testing.T) {
+	m, checker := newTestPool(0, "a:1:1", "a:2:1", "a:3:3", "b:1:2", "c:5:1")
+	// a 的 nonce 1、2 已上链（2 不在池中由其他节点打包），c 的状态在池外被改变但本区块未涉及 c。
+	checker.used["a"] = 2
+	checker.used["c"] = 9
+	checker.calls = 0
+	m.Update([]string{"a:1:1", "a:2:7"})
+	if got, want := m.Reap(0, nil), []string{"a:3:3", "b:1:2", "c:5:1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Reap = %v, want %v", got, want)
+	}
+	if checker.calls != 3 {
+		t.Fatalf("CheckTx calls = %d, want 3 (one lookup for the unpooled tx, two for a's queue)", checker.calls)
+	}
+}
+
+func TestArrivalsClosedOnInsert(t *testing.T) {
+	m, _ := newTestPool(0, "a:1:1")
+	arrived := m.Arrivals()
+	if _, _, err := m.Add("a:1:2"); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	select {
+	case <-arrived:
+		t.Fatal("Arrivals closed by a same-nonce replacement")
+	default:
+	}
+	if _, _, err := m.Add("b:1:1"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	select {
+	case <-arrived:
+	default:
+		t.Fatal("Arrivals not closed after insert")
+	}
+	if m.Arrivals() == arrived {
+		t.Fatal("Arrivals not renewed after insert")
+	}
+}
+
+func TestKVStoreWritesToSameKeyStayPending(t *testing.T) {
+	kv, err := app.NewKVStoreApp(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(0, kv)
+	txs := []string{"set k 1", "set k 2", "del k"}
+	for _, tx := range txs {
+		if _, _, err := m.Add(tx); err != nil {
+			t.Fatalf("Add(%q): %v", tx, err)
+		}
+	}
+	if got := m.Reap(0, nil); len(got) != len(txs) {
+		t.Fatalf("Reap = %v, want all of %v", got, txs)
+	}
+	if _, _, err := m.Add("set k"); err == nil {
+		t.Fatal("Add accepted a malformed kv tx")
+	}
+}
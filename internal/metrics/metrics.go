@@ -0,0 +1,310 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry 按注册顺序以 Prometheus 文本格式（0.0.4）输出指标，不依赖外部库。
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Handler 返回 /metrics 处理函数。
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		r.mu.Lock()
+		collectors := append([]collector(nil), r.collectors...)
+		r.mu.Unlock()
+		for _, c := range collectors {
+			c.write(bw)
+		}
+		_ = bw.Flush()
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%q", name, values[i])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// Counter 是单调递增计数器。
+type Counter struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	r.register(c)
+	return c
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	v := c.value
+	c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %s\n", c.name, formatFloat(v))
+}
+
+// Gauge 是可任意设置的瞬时值。
+type Gauge struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	r.register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	v := g.value
+	g.mu.Unlock()
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(v))
+}
+
+// CounterVec 是带标签的计数器族，标签值按字典序输出。
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+	r.register(c)
+	return c
+}
+
+// Inc 为给定标签值（顺序与注册时一致）加一。
+func (c *CounterVec) Inc(values ...string) { c.Add(1, values...) }
+
+func (c *CounterVec) Add(v float64, values ...string) {
+	if v < 0 || len(values) != len(c.labels) {
+		return
+	}
+	key := strings.Join(values, "\xff")
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]float64, len(keys))
+	for i, k := range keys {
+		values[i] = c.values[k]
+	}
+	c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	for i, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, strings.Split(k, "\xff")), formatFloat(values[i]))
+	}
+}
+
+// Histogram 是累积分桶直方图，Buckets 为各桶上界（升序）。
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	mu         sync.Mutex
+	counts     []uint64
+	sum        float64
+	count      uint64
+}
+
+// DefaultLatencyBuckets 覆盖 5ms 到 30s 的时延区间（秒）。
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets))}
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.observe(v)
+}
+
+func (h *Histogram) observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	h.writeSeries(w, "")
+}
+
+// writeSeries 输出一组分桶序列，labels 为已格式化的额外标签（如 alg="sbft",），调用方需持有锁。
+func (h *Histogram) writeSeries(w *bufio.Writer, labels string) {
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", h.name, labels, formatFloat(upper), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", h.name, labels, h.count)
+	suffix := ""
+	if labels != "" {
+		suffix = "{" + strings.TrimSuffix(labels, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.name, suffix, formatFloat(h.sum), h.name, suffix, h.count)
+}
+
+// HistogramVec 是带标签的直方图族。
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	labels     []string
+	mu         sync.Mutex
+	series     map[string]*Histogram
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, buckets: buckets, labels: labels, series: map[string]*Histogram{}}
+	r.register(h)
+	return h
+}
+
+// Observe 向给定标签值（顺序与注册时一致）的序列记录一个值。
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	if len(values) != len(h.labels) {
+		return
+	}
+	key := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.series[key]
+	if !ok {
+		series = &Histogram{name: h.name, buckets: h.buckets, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+	series.observe(v)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, k := range keys {
+		labels := formatLabels(h.labels, strings.Split(k, "\xff"))
+		h.series[k].writeSeries(w, labels[1:len(labels)-1]+",")
+	}
+}
+
+// SummaryValue 是一次采集得到的分位数快照。
+type SummaryValue struct {
+	Quantiles map[float64]float64
+	Sum       float64
+	Count     int64
+}
+
+type summaryFunc struct {
+	name, help string
+	fn         func() SummaryValue
+}
+
+// NewSummaryFunc 注册一个在采集时调用 fn 计算分位数的 summary。
+func (r *Registry) NewSummaryFunc(name, help string, fn func() SummaryValue) {
+	r.register(&summaryFunc{name: name, help: help, fn: fn})
+}
+
+func (s *summaryFunc) write(w *bufio.Writer) {
+	v := s.fn()
+	qs := make([]float64, 0, len(v.Quantiles))
+	for q := range v.Quantiles {
+		qs = append(qs, q)
+	}
+	sort.Float64s(qs)
+	writeHeader(w, s.name, s.help, "summary")
+	for _, q := range qs {
+		fmt.Fprintf(w, "%s{quantile=%q} %s\n", s.name, formatFloat(q), formatFloat(v.Quantiles[q]))
+	}
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", s.name, formatFloat(v.Sum), s.name, v.Count)
+}
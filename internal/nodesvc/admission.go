@@ -0,0 +1,181 @@
+package nodesvc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"mybft/internal/common"
+)
+
+// 消息准入的默认限制，可由环境变量覆盖：
+// MYBFT_MAX_BODY_BYTES 为 POST 入口的请求体上限；MYBFT_PEER_RATE/MYBFT_PEER_BURST 为每个发送方
+// 每秒可处理的共识消息数与突发量（0 关闭限流）；MYBFT_MAX_VIEW_AHEAD 为可接受的消息领先本地 view 的上限。
+const (
+	defaultMaxBodyBytes = 8 << 20
+	defaultPeerRate     = 2000
+	defaultMaxViewAhead = 64
+	// 每个高度下单个发送方至多占用的去重记录数：正常情况下每个节点每个高度只发几类消息。
+	maxDedupPerSender = 8
+)
+
+// 消息被拒绝的原因，对应 mybft_node_messages_rejected_total 的 reason 标签。
+const (
+	rejectBodyTooLarge    = "body_too_large"
+	rejectMalformed       = "malformed"
+	rejectUnknownSender   = "unknown_sender"
+	rejectUnknownType     = "unknown_type"
+	rejectUnauthenticated = "unauthenticated"
+	rejectRateLimited     = "rate_limited"
+	rejectViewWindow      = "view_window"
+	rejectNotLeader       = "not_leader"
+	rejectDedupFull       = "dedup_full"
+)
+
+type admissionConfig struct {
+	maxBodyBytes int64
+	peerRate     float64
+	peerBurst    float64
+	maxViewAhead int
+}
+
+func nonNegativeEnv(key string, def int) int {
+	if raw := os.Getenv(key); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return def
+}
+
+// 读取消息准入配置；view 窗口至少覆盖 HotStuff 流水线窗口。
+func admissionConfigFromEnv(pipelineWindow int) admissionConfig {
+	rate := nonNegativeEnv("MYBFT_PEER_RATE", defaultPeerRate)
+	return admissionConfig{
+		maxBodyBytes: int64(nonNegativeEnv("MYBFT_MAX_BODY_BYTES", defaultMaxBodyBytes)),
+		peerRate:     float64(rate),
+		peerBurst:    float64(nonNegativeEnv("MYBFT_PEER_BURST", rate)),
+		maxViewAhead: max(nonNegativeEnv("MYBFT_MAX_VIEW_AHEAD", defaultMaxViewAhead), pipelineWindow+2),
+	}
+}
+
+// peerLimiter 为每个发送方维护一个令牌桶，键由 limitKey 给出。
+type peerLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newPeerLimiter(rate, burst float64) *peerLimiter {
+	return &peerLimiter{rate: rate, burst: max(burst, 1), buckets: map[string]*tokenBucket{}}
+}
+
+// allow 为发送方 key 扣除一个令牌，rate 为 0 时不限流。
+func (l *peerLimiter) allow(key string) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// reject 记录一次被拒绝的消息。
+func (s *Service) reject(reason string) {
+	s.metrics.rejected.Inc(reason)
+}
+
+// limitBody 限制 POST 入口的请求体大小，超出时读取返回错误。
+func (s *Service) limitBody(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.admission.maxBodyBytes)
+}
+
+// bodyErrorStatus 把读取或解码请求体的错误映射为响应码：超出上限为 413，其余为 400。
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// precheckSender 在解码后、认证与进入共识前做不需要 s.mu 的廉价检查：发送方为已知节点、
+// 消息类型属于共识消息。返回拒绝原因，通过时为空。
+func (s *Service) precheckSender(msg common.ConsensusMessage) string {
+	switch {
+	case s.peers.key(msg.From) == "":
+		return rejectUnknownSender
+	case !isProposal(msg.Type) && !isVote(msg.Type) && !isQC(msg.Type):
+		return rejectUnknownType
+	}
+	return ""
+}
+
+// limitKey 返回认证通过后限流所用的键：开启 TLS 或 MAC 时 From 已由传输层认证，按节点编号限流；
+// 否则 From 只是声称的身份，按远端主机限流，伪造他人 From 的请求不会耗尽该节点的配额。
+func (s *Service) limitKey(r *http.Request, from int) string {
+	if s.identity != nil || s.authMode == authMAC {
+		return fmt.Sprintf("node:%d", from)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// precheckView 在验签与写入任何状态前检查消息的 view：领先本地超过窗口的消息只用于触发追赶，
+// 投票须发给该 view 的 leader。返回拒绝原因，调用方需持有 s.mu。
+func (s *Service) precheckView(msg common.ConsensusMessage) string {
+	local := s.view
+	if s.pipelineWindow > 0 {
+		local = s.nextProposalView
+	}
+	if msg.View > local+s.admission.maxViewAhead || msg.Height > local+s.admission.maxViewAhead {
+		s.startCatchUp()
+		return rejectViewWindow
+	}
+	if isVote(msg.Type) && !s.isLeader(msg.View) {
+		return rejectNotLeader
+	}
+	return ""
+}
+
+// admitDedup 记录消息的去重键：重复消息返回 false；单个发送方在该高度的记录达到上限时
+// 同样拒绝，避免按不同摘要构造的消息使去重表无限增长。调用方需持有 s.mu。
+func (s *Service) admitDedup(hs *heightState, msg common.ConsensusMessage) bool {
+	dk := common.DedupKey(msg)
+	if _, ok := hs.Dedup[dk]; ok {
+		return false
+	}
+	if hs.DedupBySender[msg.From] >= maxDedupPerSender {
+		s.reject(rejectDedupFull)
+		return false
+	}
+	hs.Dedup[dk] = struct{}{}
+	hs.DedupBySender[msg.From]++
+	return true
+}
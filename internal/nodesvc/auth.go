@@ -0,0 +1,103 @@
+package nodesvc
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"mybft/internal/common"
+	"mybft/internal/crypto"
+)
+
+// 消息认证方式：sig 为每张投票逐条验签；mac 为点对点消息由成对密钥的 MAC 认证，
+// 签名份额只在组成证书时校验。
+const (
+	authSignature = "sig"
+	authMAC       = "mac"
+)
+
+// macHeader 携带发送方用成对密钥对请求体计算的 MAC。
+const macHeader = "X-Mybft-Mac"
+
+// 读取 MYBFT_AUTH，默认 sig。
+func authModeFromEnv() (string, error) {
+	switch mode := os.Getenv("MYBFT_AUTH"); mode {
+	case "", authSignature:
+		return authSignature, nil
+	case authMAC:
+		return authMAC, nil
+	default:
+		return "", fmt.Errorf("invalid MYBFT_AUTH: %s (want sig|mac)", mode)
+	}
+}
+
+// loadMACKey 用本节点的 X25519 私钥与节点 id 的公钥（Redis Node:<id> 的 dh_sk/dh_pk）协商成对 MAC 密钥。
+func (s *Service) loadMACKey(id int) error {
+	if s.dhKey == "" {
+		sk, err := s.rdb.HGet(fmt.Sprintf("Node:%d", s.selfID), "dh_sk")
+		if err != nil || sk == "" {
+			return fmt.Errorf("load dh key Node:%d: %v (rerun genkey)", s.selfID, err)
+		}
+		s.dhKey = sk
+	}
+	pk, err := s.rdb.HGet(fmt.Sprintf("Node:%d", id), "dh_pk")
+	if err != nil || pk == "" {
+		return fmt.Errorf("load dh public key Node:%d: %v", id, err)
+	}
+	key, err := crypto.PairwiseKey(s.dhKey, pk, s.selfID, id)
+	if err != nil {
+		return fmt.Errorf("derive mac key with node %d: %w", id, err)
+	}
+	s.peers.setMACKey(id, key)
+	return nil
+}
+
+// setMAC 在 MAC 模式下为发往节点 to 的请求体附上认证码。
+func (s *Service) setMAC(req *http.Request, to int, body []byte) {
+	if s.authMode != authMAC {
+		return
+	}
+	req.Header.Set(macHeader, crypto.MAC(s.peers.macKey(to), body))
+}
+
+// authenticMAC 在 MAC 模式下用与 from 的成对密钥校验请求体，签名模式下不做检查。
+func (s *Service) authenticMAC(r *http.Request, from int, body []byte) bool {
+	if s.authMode != authMAC {
+		return true
+	}
+	key := s.peers.macKey(from)
+	start := time.Now()
+	ok := key != nil && crypto.VerifyMAC(key, body, r.Header.Get(macHeader))
+	s.metrics.authSeconds.Add(time.Since(start).Seconds(), authMAC)
+	s.metrics.macVerify.Inc(verifyResult(ok))
+	if !ok {
+		log.Printf("node=%d event=mac_rejected from=%d remote=%s", s.selfID, from, r.RemoteAddr)
+	}
+	return ok
+}
+
+// acceptShare 校验投票携带的签名份额，由 acceptVote 在记录投票前调用。MAC 模式下投票已由传输层认证，消息路径上不再逐条验签，
+// 份额留到组成证书时由 certifyShares 校验。
+func (s *Service) acceptShare(msg common.ConsensusMessage, m []byte) bool {
+	if s.authMode == authMAC {
+		return true
+	}
+	return s.verifyShare(msg.From, m, msg.SigShare)
+}
+
+// certifyShares 在份额达到门限、组成证书前调用：MAC 模式下逐个校验份额并剔除无效者，
+// 返回剩余份额是否仍达到门限；签名模式下份额到达时已校验，直接返回 true。
+func (s *Service) certifyShares(shares map[int]string, voteType string, view, height int, target string) bool {
+	if s.authMode != authMAC {
+		return true
+	}
+	for id, sig := range shares {
+		if !s.verifyShare(id, crypto.VoteMessage(voteType, view, height, target, id), sig) {
+			log.Printf("node=%d event=invalid_share type=%s view=%d from=%d", s.selfID, voteType, view, id)
+			delete(shares, id)
+		}
+	}
+	return s.sharesPower(shares) >= s.thresholds(view).T
+}
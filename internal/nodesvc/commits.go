@@ -0,0 +1,360 @@
+package nodesvc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"mybft/internal/app"
+	"mybft/internal/common"
+	"mybft/internal/storage"
+)
+
+// 节点在内存中保留的最近提交区块数；更早的高度从区块库读取。
+const commitLogSize = 1024
+
+const (
+	defaultCommitsLimit   = 100
+	maxCommitsWait        = 60 * time.Second
+	commitStreamHeartbeat = 15 * time.Second
+)
+
+// commitLog 是最近提交区块的环形记录，使用独立的锁，查询与订阅不与共识争用 s.mu。
+// 每次追加都会关闭并替换 notify，唤醒所有等待新提交的订阅者。
+type commitLog struct {
+	mu          sync.RWMutex
+	entries     []common.CommitEntry
+	latest      int
+	notify      chan struct{}
+	subscribers int
+}
+
+func newCommitLog(latest int) *commitLog {
+	return &commitLog{entries: make([]common.CommitEntry, 0, commitLogSize), latest: latest, notify: make(chan struct{})}
+}
+
+func (l *commitLog) append(entry common.CommitEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == commitLogSize {
+		copy(l.entries, l.entries[1:])
+		l.entries = l.entries[:len(l.entries)-1]
+	}
+	l.entries = append(l.entries, entry)
+	if entry.Height > l.latest {
+		l.latest = entry.Height
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// since 返回内存中高度不小于 from 的至多 limit 条记录；from 早于保留窗口时 ok 为 false。
+func (l *commitLog) since(from, limit int) (resp common.CommitsResponse, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	resp = common.CommitsResponse{Latest: l.latest, Entries: []common.CommitEntry{}}
+	if len(l.entries) == 0 {
+		return resp, from > l.latest
+	}
+	resp.Oldest = l.entries[0].Height
+	if from < resp.Oldest {
+		return resp, false
+	}
+	for _, entry := range l.entries {
+		if entry.Height < from {
+			continue
+		}
+		if len(resp.Entries) == limit {
+			break
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	return resp, true
+}
+
+// wait 返回下一次追加时关闭的通道；应在读取记录之前获取，避免漏掉其间的提交。
+func (l *commitLog) wait() <-chan struct{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.notify
+}
+
+// advance 在安装快照后把最新高度前移到快照高度，之前的高度不在本节点提供。
+func (l *commitLog) advance(height int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if height > l.latest {
+		l.latest = height
+	}
+}
+
+func (l *commitLog) latestHeight() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.latest
+}
+
+func (l *commitLog) subscribe(delta int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers += delta
+	return l.subscribers
+}
+
+// stageCommit 暂存刚执行完的区块及逐笔结果码，等 markCommitted 附上提交证明后发布，调用方需持有 s.mu。
+func (s *Service) stageCommit(height int, blockID string, tx []string, result app.FinalizeBlockResult) {
+	entry := common.CommitEntry{Height: height, BlockID: blockID, CommittedAt: time.Now().UnixNano(), Txs: make([]common.CommittedTx, len(tx))}
+	for i, line := range tx {
+		entry.Txs[i] = common.CommittedTx{ID: common.TxID(line), Tx: line}
+		if i < len(result.TxResults) {
+			entry.Txs[i].Code = result.TxResults[i].Code
+		}
+	}
+	s.executedCommits[height] = entry
+}
+
+// publishCommit 为已执行区块附上提交证明，持久化到区块库并通知订阅者，调用方需持有 s.mu。
+func (s *Service) publishCommit(height int, qc common.QuorumCert, justify *common.QuorumCert) {
+	entry, ok := s.executedCommits[height]
+	if !ok {
+		return
+	}
+	delete(s.executedCommits, height)
+	entry.QC = qc
+	entry.Justify = justify
+	entry.Proposer = s.leaderID(height)
+	if s.stores != nil {
+		if err := s.stores.Blocks.SaveCommit(commitRecord(entry)); err != nil {
+			log.Printf("node=%d save commit height=%d: %v", s.selfID, height, err)
+		}
+	}
+	s.commits.append(entry)
+}
+
+func commitRecord(entry common.CommitEntry) storage.CommitRecord {
+	record := storage.CommitRecord{
+		Height:      entry.Height,
+		BlockID:     entry.BlockID,
+		Proposer:    entry.Proposer,
+		QCType:      entry.QC.Type,
+		QCView:      entry.QC.View,
+		QC:          entry.QC.QC,
+		QCSigners:   entry.QC.Signers,
+		Tx:          make([]string, len(entry.Txs)),
+		Codes:       make([]int, len(entry.Txs)),
+		CommittedAt: entry.CommittedAt,
+	}
+	if j := entry.Justify; j != nil {
+		record.JustifyBlockID, record.JustifyView, record.JustifyQC, record.JustifySigners = j.BlockID, j.View, j.QC, j.Signers
+	}
+	for i, tx := range entry.Txs {
+		record.Tx[i], record.Codes[i] = tx.Tx, tx.Code
+	}
+	return record
+}
+
+func commitEntryFromRecord(record storage.CommitRecord) common.CommitEntry {
+	entry := common.CommitEntry{
+		Height:      record.Height,
+		BlockID:     record.BlockID,
+		Proposer:    record.Proposer,
+		QC:          common.QuorumCert{Type: record.QCType, BlockID: record.BlockID, View: record.QCView, Height: record.Height, QC: record.QC, Signers: record.QCSigners},
+		CommittedAt: record.CommittedAt,
+		Txs:         make([]common.CommittedTx, len(record.Tx)),
+	}
+	if record.JustifyQC != "" {
+		entry.Justify = &common.QuorumCert{Type: "HSQC", BlockID: record.JustifyBlockID, View: record.JustifyView, Height: record.JustifyView, QC: record.JustifyQC, Signers: record.JustifySigners}
+	}
+	for i, tx := range record.Tx {
+		entry.Txs[i] = common.CommittedTx{ID: common.TxID(tx), Tx: tx}
+		if i < len(record.Codes) {
+			entry.Txs[i].Code = record.Codes[i]
+		}
+	}
+	return entry
+}
+
+// commitsSince 优先从内存窗口取提交记录，from 早于窗口时回落到区块库。
+func (s *Service) commitsSince(from, limit int) (common.CommitsResponse, error) {
+	resp, ok := s.commits.since(from, limit)
+	if ok || s.stores == nil {
+		return resp, nil
+	}
+	records, err := s.stores.Blocks.ListCommits(from, limit)
+	if err != nil {
+		return resp, err
+	}
+	resp.Entries = make([]common.CommitEntry, len(records))
+	for i, record := range records {
+		resp.Entries[i] = commitEntryFromRecord(record)
+	}
+	if len(records) > 0 && (resp.Oldest == 0 || records[0].Height < resp.Oldest) {
+		resp.Oldest = records[0].Height
+	}
+	return resp, nil
+}
+
+// HandleCommits 返回 from 高度起的提交通知（?from=H&limit=N）；带 wait=10s 时为长轮询，
+// 暂无新提交则最多等待该时长。
+func (s *Service) HandleCommits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	from, limit := 0, defaultCommitsLimit
+	var wait time.Duration
+	q := r.URL.Query()
+	if raw := q.Get("from"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		from = v
+	}
+	if raw := q.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		limit = min(v, commitLogSize)
+	}
+	if raw := q.Get("wait"); raw != "" {
+		v, err := time.ParseDuration(raw)
+		if err != nil || v < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		wait = min(v, maxCommitsWait)
+	}
+	deadline := time.After(wait)
+	for {
+		notify := s.commits.wait()
+		resp, err := s.commitsSince(from, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(resp.Entries) > 0 || wait == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			wait = 0
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// HandleCommitStream 以 server-sent events 推送提交通知（GET /commits/stream?from=H），
+// 每个事件的 id 为高度；重连时按 Last-Event-ID 从下一高度续传，两者都缺省时只推送之后的新提交。
+// 所需高度已不可得（如节点由快照引导）时先发送 gap 事件再继续。
+func (s *Service) HandleCommitStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	from := s.commits.latestHeight() + 1
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		from = v
+	} else if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		from = v + 1
+	}
+	from = max(from, 1)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	s.metrics.commitSubscribers.Set(float64(s.commits.subscribe(1)))
+	defer func() { s.metrics.commitSubscribers.Set(float64(s.commits.subscribe(-1))) }()
+	heartbeat := time.NewTicker(commitStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		notify := s.commits.wait()
+		resp, err := s.commitsSince(from, defaultCommitsLimit)
+		if err != nil {
+			fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+			flusher.Flush()
+			return
+		}
+		for _, entry := range resp.Entries {
+			if entry.Height > from {
+				if _, err := fmt.Fprintf(w, "event: gap\ndata: {\"from\":%d,\"to\":%d}\n\n", from, entry.Height-1); err != nil {
+					return
+				}
+			}
+			raw, _ := json.Marshal(entry)
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: commit\ndata: %s\n\n", entry.Height, raw); err != nil {
+				return
+			}
+			from = entry.Height + 1
+		}
+		if len(resp.Entries) > 0 {
+			flusher.Flush()
+			if len(resp.Entries) == defaultCommitsLimit {
+				continue
+			}
+		}
+		select {
+		case <-notify:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// HandleQuery 在已提交状态上执行应用查询（?path=...&data=...）。
+func (s *Service) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	querier, ok := s.app.(app.Querier)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	raw, err := querier.Query(r.URL.Query().Get("path"), r.URL.Query().Get("data"))
+	if errors.Is(err, app.ErrUnknownQuery) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(raw)
+}
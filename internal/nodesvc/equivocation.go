@@ -0,0 +1,210 @@
+package nodesvc
+
+import (
+	"log"
+
+	"mybft/internal/common"
+	"mybft/internal/crypto"
+	"mybft/internal/membership"
+	"mybft/internal/storage"
+)
+
+// 节点为检测同 view 冲突提案与重复投票而保留的最近 view 数（相对最高已提交高度）。
+const proposalMemory = 64
+
+// 各算法的 QC 消息类型及其聚合的投票类型。
+var qcVoteTypes = map[string]string{
+	"CommitProof": "Prepare",
+	"HSQC":        "HSVote",
+	"FHSCommitQC": "FHSVote",
+	"HPQC":        "HPPrepareVote",
+}
+
+// 各算法的投票消息类型；投票只发给该 view 的 leader。
+func isVote(msgType string) bool {
+	switch msgType {
+	case "Prepare", "HSVote", "FHSVote", "HPPrepareVote":
+		return true
+	}
+	return false
+}
+
+func isQC(msgType string) bool {
+	_, ok := qcVoteTypes[msgType]
+	return ok
+}
+
+// voteKey 标识一个节点在某 view 下的一张票。
+type voteKey struct {
+	msgType string
+	view    int
+	from    int
+}
+
+func proposalSigningBytes(msg common.ConsensusMessage) []byte {
+	return crypto.ProposalMessage(msg.Type, msg.View, msg.Height, msg.Digest, msg.ParentID, msg.JustifyID, msg.From)
+}
+
+func qcSigningBytes(msg common.ConsensusMessage) []byte {
+	return crypto.QCMessage(msg.Type, msg.View, msg.Height, quorumCertFromMessage(msg).BlockID, msg.QC, msg.Signers, msg.From)
+}
+
+// signProposal 由 leader 在广播前对提案签名，签名放在 SigFull。
+func (s *Service) signProposal(msg *common.ConsensusMessage) {
+	msg.SigFull = crypto.Sign(s.peers.key(s.selfID), proposalSigningBytes(*msg))
+}
+
+// signQC 由组成 QC 的 leader 在广播前对 QC 消息签名，签名放在 SigFull。
+func (s *Service) signQC(msg *common.ConsensusMessage) {
+	msg.SigFull = crypto.Sign(s.peers.key(s.selfID), qcSigningBytes(*msg))
+}
+
+func (s *Service) validProposalSignature(msg common.ConsensusMessage) bool {
+	return msg.SigFull != "" && crypto.Verify(s.peers.key(msg.From), proposalSigningBytes(msg), msg.SigFull)
+}
+
+func (s *Service) validQCSignature(msg common.ConsensusMessage) bool {
+	return msg.SigFull != "" && crypto.Verify(s.peers.key(msg.From), qcSigningBytes(msg), msg.SigFull)
+}
+
+func (s *Service) validVote(msg common.ConsensusMessage) bool {
+	return crypto.Verify(s.peers.key(msg.From), crypto.VoteMessage(msg.Type, msg.View, msg.Height, s.messageBlockID(msg), msg.From), msg.SigShare)
+}
+
+// validQC 按签名者重算各份额并比对聚合值（演示用 HMAC 方案下每个节点持有全部密钥），
+// 签名者须为该 view 的验证者、互不重复且投票权重之和达到该 view 的门限。
+func (s *Service) validQC(msg common.ConsensusMessage) bool {
+	return s.validQCFor(s.members, msg)
+}
+
+// validQCFor 按给定的成员集合校验 QC，快照引导时用快照中的集合校验。
+func (s *Service) validQCFor(members *membership.Set, msg common.ConsensusMessage) bool {
+	voteType, ok := qcVoteTypes[msg.Type]
+	if !ok || s.power(msg.Signers) < common.CalcThresholds(s.power(members.Validators(msg.View))).T {
+		return false
+	}
+	target := s.messageBlockID(msg)
+	seen := map[int]bool{}
+	shares := make([]string, 0, len(msg.Signers))
+	for _, id := range msg.Signers {
+		if !members.Contains(msg.View, id) || seen[id] {
+			return false
+		}
+		seen[id] = true
+		shares = append(shares, crypto.Sign(s.peers.key(id), crypto.VoteMessage(voteType, msg.View, msg.Height, target, id)))
+	}
+	return s.verifyAggregate(shares, msg.QC)
+}
+
+func sameProposal(a, b common.ConsensusMessage) bool {
+	return a.Digest == b.Digest && a.ParentID == b.ParentID && a.JustifyID == b.JustifyID
+}
+
+// acceptProposal 在提案进入共识流程前检查：发送方是该 view 的 leader、签名有效，
+// 且与本节点此前收到的同 view 提案一致。两条签名有效的提案摘要不同时记为 leader 作恶证据，
+// 第二条提案被丢弃，副本因此在每个 view 至多为一个提案投票。提案打包的证据须逐条验证通过，
+// 成员变更交易须语法正确。
+// 调用方需持有 s.mu。
+func (s *Service) acceptProposal(msg common.ConsensusMessage) bool {
+	if leader := s.leaderID(msg.View); msg.From != leader {
+		log.Printf("node=%d event=proposal_wrong_leader view=%d from=%d leader=%d", s.selfID, msg.View, msg.From, leader)
+		return false
+	}
+	ok := s.validProposalSignature(msg)
+	s.metrics.sigVerify.Inc("proposal", verifyResult(ok))
+	if !ok {
+		log.Printf("node=%d event=proposal_bad_signature view=%d from=%d", s.selfID, msg.View, msg.From)
+		return false
+	}
+	first, seen := s.proposalsSeen[msg.View]
+	if !seen {
+		s.proposalsSeen[msg.View] = msg
+	} else if !sameProposal(first, msg) {
+		s.metrics.equivocations.Inc()
+		log.Printf("node=%d event=equivocation_detected view=%d leader=%d first=%s second=%s", s.selfID, msg.View, msg.From, first.Digest, msg.Digest)
+		s.reportEvidence(s.conflictEvidence(storage.EvidenceConflictingProposal, first, msg))
+		return false
+	}
+	if err := validReconfig(msg.Reconfig); err != nil {
+		log.Printf("node=%d event=proposal_bad_reconfig view=%d from=%d err=%v", s.selfID, msg.View, msg.From, err)
+		return false
+	}
+	if len(msg.Evidence) > maxBlockEvidence {
+		log.Printf("node=%d event=proposal_bad_evidence view=%d from=%d count=%d", s.selfID, msg.View, msg.From, len(msg.Evidence))
+		return false
+	}
+	for _, ev := range msg.Evidence {
+		if err := s.verifyEvidence(ev); err != nil {
+			log.Printf("node=%d event=proposal_bad_evidence view=%d from=%d evidence=%s err=%v", s.selfID, msg.View, msg.From, ev.ID(), err)
+			return false
+		}
+	}
+	for _, ev := range msg.Evidence {
+		s.addEvidence(ev, "block")
+	}
+	return true
+}
+
+// acceptVote 检查 leader 收到的投票：先校验签名份额（见 acceptShare），未通过的票不记录，
+// 伪造的票因此占不住发送方在该 view 的位置；同一节点在同一 view 下投给不同区块的两张有效票记为重复投票证据，
+// 第二张票被丢弃。调用方需持有 s.mu。
+func (s *Service) acceptVote(msg common.ConsensusMessage) bool {
+	// 投票的签名对象为 BlockID（缺省为 Digest），两者不一致的票不是诚实节点发出的。
+	if msg.BlockID != "" && msg.BlockID != msg.Digest {
+		return false
+	}
+	if !s.acceptShare(msg, crypto.VoteMessage(msg.Type, msg.View, msg.Height, s.messageBlockID(msg), msg.From)) {
+		return false
+	}
+	key := voteKey{msgType: msg.Type, view: msg.View, from: msg.From}
+	first, seen := s.votesSeen[key]
+	if !seen {
+		s.votesSeen[key] = msg
+		return true
+	}
+	if s.messageBlockID(first) == s.messageBlockID(msg) {
+		return true
+	}
+	// MAC 模式下份额未逐条验签；证据须可独立验证，两张票都有效才上报。
+	if !s.validVote(first) || !s.validVote(msg) {
+		return false
+	}
+	log.Printf("node=%d event=duplicate_vote_detected view=%d voter=%d first=%s second=%s", s.selfID, msg.View, msg.From, s.messageBlockID(first), s.messageBlockID(msg))
+	s.reportEvidence(s.conflictEvidence(storage.EvidenceDuplicateVote, first, msg))
+	return false
+}
+
+// acceptQC 检查 QC 消息：发送方须为该 view 的 leader 且签名有效；聚合值与签名者不符时
+// 记为 leader 广播无效 QC 的证据并丢弃。调用方需持有 s.mu。
+func (s *Service) acceptQC(msg common.ConsensusMessage) bool {
+	if leader := s.leaderID(msg.View); msg.From != leader {
+		log.Printf("node=%d event=qc_wrong_leader view=%d from=%d leader=%d", s.selfID, msg.View, msg.From, leader)
+		return false
+	}
+	ok := s.validQCSignature(msg)
+	s.metrics.sigVerify.Inc("qc", verifyResult(ok))
+	if !ok {
+		log.Printf("node=%d event=qc_bad_signature view=%d from=%d", s.selfID, msg.View, msg.From)
+		return false
+	}
+	if s.validQC(msg) {
+		return true
+	}
+	log.Printf("node=%d event=invalid_qc_detected view=%d leader=%d signers=%v", s.selfID, msg.View, msg.From, msg.Signers)
+	s.reportEvidence(s.singleEvidence(storage.EvidenceInvalidQC, msg))
+	return false
+}
+
+// pruneSeen 丢弃远低于已提交高度的提案与投票记录，调用方需持有 s.mu。
+func (s *Service) pruneSeen(height int) {
+	for view := range s.proposalsSeen {
+		if view <= height-proposalMemory {
+			delete(s.proposalsSeen, view)
+		}
+	}
+	for key := range s.votesSeen {
+		if key.view <= height-proposalMemory {
+			delete(s.votesSeen, key)
+		}
+	}
+}
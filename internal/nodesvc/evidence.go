@@ -0,0 +1,282 @@
+package nodesvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"mybft/internal/common"
+	"mybft/internal/storage"
+)
+
+// 单个区块最多打包的证据条数。
+const maxBlockEvidence = 8
+
+func evidenceFromRecord(r storage.EvidenceRecord) common.Evidence {
+	return common.Evidence{Kind: r.Kind, Offender: r.Offender, View: r.View, Height: r.Height, Reporter: r.Reporter, First: r.First, Second: r.Second}
+}
+
+func evidenceRecord(e common.Evidence) storage.EvidenceRecord {
+	return storage.EvidenceRecord{
+		Kind:       e.Kind,
+		Offender:   e.Offender,
+		View:       e.View,
+		Height:     e.Height,
+		Reporter:   e.Reporter,
+		First:      e.First,
+		Second:     e.Second,
+		DetectedAt: time.Now().UnixNano(),
+	}
+}
+
+func evidenceRecords(evidence []common.Evidence) []storage.EvidenceRecord {
+	if len(evidence) == 0 {
+		return nil
+	}
+	records := make([]storage.EvidenceRecord, len(evidence))
+	for i, e := range evidence {
+		records[i] = evidenceRecord(e)
+	}
+	return records
+}
+
+// conflictEvidence 由作恶节点签名的两条冲突消息构造证据。
+func (s *Service) conflictEvidence(kind string, first, second common.ConsensusMessage) common.Evidence {
+	ev := s.singleEvidence(kind, first)
+	ev.Second, _ = json.Marshal(second)
+	return ev
+}
+
+func (s *Service) singleEvidence(kind string, msg common.ConsensusMessage) common.Evidence {
+	raw, _ := json.Marshal(msg)
+	return common.Evidence{Kind: kind, Offender: msg.From, View: msg.View, Height: msg.Height, Reporter: s.selfID, First: raw}
+}
+
+// verifyEvidence 独立验证一条证据：消息须由作恶节点签名且确实构成所述作恶，
+// 任何节点都不能凭伪造的证据诬陷诚实节点。
+func (s *Service) verifyEvidence(ev common.Evidence) error {
+	if s.peers.key(ev.Offender) == "" {
+		return fmt.Errorf("unknown offender %d", ev.Offender)
+	}
+	var first, second common.ConsensusMessage
+	if err := json.Unmarshal(ev.First, &first); err != nil {
+		return fmt.Errorf("decode first message: %w", err)
+	}
+	if first.From != ev.Offender || first.View != ev.View {
+		return errors.New("first message does not match offender and view")
+	}
+	if ev.Kind == storage.EvidenceInvalidQC {
+		if !isQC(first.Type) || !s.validQCSignature(first) {
+			return errors.New("not a signed qc message")
+		}
+		if s.validQC(first) {
+			return errors.New("qc is valid")
+		}
+		return nil
+	}
+	if err := json.Unmarshal(ev.Second, &second); err != nil {
+		return fmt.Errorf("decode second message: %w", err)
+	}
+	if second.Type != first.Type || second.From != first.From || second.View != first.View {
+		return errors.New("messages are not from the same sender, view and type")
+	}
+	switch ev.Kind {
+	case storage.EvidenceConflictingProposal:
+		if !isProposal(first.Type) || !s.validProposalSignature(first) || !s.validProposalSignature(second) {
+			return errors.New("not a pair of signed proposals")
+		}
+		if sameProposal(first, second) {
+			return errors.New("proposals do not conflict")
+		}
+	case storage.EvidenceDuplicateVote:
+		if !isVote(first.Type) || !s.validVote(first) || !s.validVote(second) {
+			return errors.New("not a pair of signed votes")
+		}
+		if s.messageBlockID(first) == s.messageBlockID(second) {
+			return errors.New("votes do not conflict")
+		}
+	default:
+		return fmt.Errorf("unknown evidence kind %q", ev.Kind)
+	}
+	return nil
+}
+
+// loadPendingEvidence 启动时把尚未打包进已提交区块的证据恢复到待打包集合。
+func (s *Service) loadPendingEvidence() {
+	if s.stores == nil {
+		return
+	}
+	records, err := s.stores.Blocks.ListEvidence()
+	if err != nil {
+		log.Printf("node=%d load evidence: %v", s.selfID, err)
+		return
+	}
+	for _, r := range records {
+		if r.IncludedHeight == 0 {
+			ev := evidenceFromRecord(r)
+			s.evidencePending[ev.ID()] = ev
+		}
+	}
+	s.metrics.evidencePending.Set(float64(len(s.evidencePending)))
+}
+
+// reportEvidence 处理本节点发现的作恶：保存证据并转发给其他节点，调用方需持有 s.mu。
+func (s *Service) reportEvidence(ev common.Evidence) {
+	if s.addEvidence(ev, "local") {
+		s.gossipEvidence(ev)
+	}
+}
+
+// addEvidence 保存已验证的证据并加入待打包集合，返回是否为本节点首次收到；
+// source 为 local（本节点发现）、gossip（同伴转发）或 block（随提案到达）。调用方需持有 s.mu。
+func (s *Service) addEvidence(ev common.Evidence, source string) bool {
+	if s.stores != nil {
+		added, err := s.stores.Blocks.SaveEvidence(evidenceRecord(ev))
+		if err != nil {
+			log.Printf("node=%d save evidence %s: %v", s.selfID, ev.ID(), err)
+			return false
+		}
+		if !added {
+			return false
+		}
+	} else if _, ok := s.evidencePending[ev.ID()]; ok {
+		return false
+	}
+	s.evidencePending[ev.ID()] = ev
+	s.metrics.evidence.Inc(ev.Kind, source)
+	s.metrics.evidencePending.Set(float64(len(s.evidencePending)))
+	log.Printf("node=%d event=evidence_added kind=%s offender=%d view=%d reporter=%d source=%s", s.selfID, ev.Kind, ev.Offender, ev.View, ev.Reporter, source)
+	return true
+}
+
+// 把本节点发现的证据转发给其他节点；收到方验证后保存，不再转发。
+func (s *Service) gossipEvidence(ev common.Evidence) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	for _, addr := range s.peers.others(s.selfID) {
+		go func(addr string) {
+			resp, err := s.httpClient.Post(s.peerURL(addr, "/evidence"), "application/json", bytes.NewReader(body))
+			if err != nil {
+				log.Printf("node=%d gossip evidence to %s: %v", s.selfID, addr, err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}(addr)
+	}
+}
+
+// evidenceForProposal 挑选待打包的证据（按 ID 排序，至多 maxBlockEvidence 条），
+// 跳过父链上尚未提交区块已打包的证据，调用方需持有 s.mu。
+func (s *Service) evidenceForProposal(parentID string) []common.Evidence {
+	if len(s.evidencePending) == 0 {
+		return nil
+	}
+	packed := map[string]struct{}{}
+	for cur := parentID; cur != ""; {
+		block, ok := s.hotstuffBlocks[cur]
+		if !ok || block.Committed {
+			break
+		}
+		for _, ev := range block.Block.Evidence {
+			packed[ev.ID()] = struct{}{}
+		}
+		cur = block.Block.ParentBlockID
+	}
+	ids := make([]string, 0, len(s.evidencePending))
+	for id := range s.evidencePending {
+		if _, ok := packed[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) > maxBlockEvidence {
+		ids = ids[:maxBlockEvidence]
+	}
+	evidence := make([]common.Evidence, len(ids))
+	for i, id := range ids {
+		evidence[i] = s.evidencePending[id]
+	}
+	return evidence
+}
+
+// commitEvidence 把已提交区块打包的证据标记为已上链并移出待打包集合，调用方需持有 s.mu。
+func (s *Service) commitEvidence(height int, evidence []common.Evidence) {
+	if len(evidence) == 0 {
+		return
+	}
+	for _, ev := range evidence {
+		delete(s.evidencePending, ev.ID())
+		s.metrics.evidenceCommitted.Inc(ev.Kind)
+		log.Printf("node=%d event=evidence_committed height=%d kind=%s offender=%d view=%d", s.selfID, height, ev.Kind, ev.Offender, ev.View)
+		if s.stores == nil {
+			continue
+		}
+		if err := s.stores.Blocks.MarkEvidenceIncluded(evidenceRecord(ev), height); err != nil {
+			log.Printf("node=%d mark evidence %s included height=%d: %v", s.selfID, ev.ID(), height, err)
+		}
+	}
+	s.metrics.evidencePending.Set(float64(len(s.evidencePending)))
+}
+
+// HandleEvidence：GET 返回本地保存的证据（可按 offender、kind 过滤），
+// POST 接收同伴转发的证据，验证通过后保存。
+func (s *Service) HandleEvidence(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listEvidence(w, r)
+	case http.MethodPost:
+		s.limitBody(w, r)
+		var ev common.Evidence
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			w.WriteHeader(bodyErrorStatus(err))
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.verifyEvidence(ev); err != nil {
+			log.Printf("node=%d event=evidence_rejected evidence=%s reporter=%d err=%v", s.selfID, ev.ID(), ev.Reporter, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.addEvidence(ev, "gossip")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Service) listEvidence(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	offender := 0
+	if raw := q.Get("offender"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		offender = v
+	}
+	records, err := s.stores.Blocks.ListEvidence()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	out := make([]storage.EvidenceRecord, 0, len(records))
+	for _, rec := range records {
+		if (offender == 0 || rec.Offender == offender) && (q.Get("kind") == "" || rec.Kind == q.Get("kind")) {
+			out = append(out, rec)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
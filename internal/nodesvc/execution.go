@@ -0,0 +1,115 @@
+package nodesvc
+
+import (
+	"log"
+
+	"mybft/internal/app"
+)
+
+// speculativeBlock 是未提交区块的推测执行结果。parentID 为空时 state 直接叠加在高度 base 的
+// 已提交状态上，否则叠加在父块的推测状态上；父块提交后改基到已提交状态。
+// txs 记录推测时的交易笔数，提交时内容不一致（父块当时尚无交易内容）则不复用。
+type speculativeBlock struct {
+	height   int
+	parentID string
+	base     int
+	txs      int
+	state    app.SpecState
+}
+
+// processProposal 在 parent 链的待提交状态上校验整批交易，调用方需持有 s.mu。
+// 应用支持推测执行时，结果按区块保存，供子块校验与本块提交复用；否则交给 ProcessProposal 重放待提交链。
+func (s *Service) processProposal(height int, blockID, parentID string, tx []string) bool {
+	spec, ok := s.app.(app.Speculator)
+	if !ok {
+		return s.app.ProcessProposal(app.ProposalRequest{Height: height, BlockID: blockID, Txs: tx, Pending: s.pendingPayloads(parentID)})
+	}
+	return s.speculate(spec, height, blockID, parentID, tx)
+}
+
+func (s *Service) speculate(spec app.Speculator, height int, blockID, parentID string, tx []string) bool {
+	if _, ok := s.speculative[blockID]; ok {
+		return true
+	}
+	parentKey, parent, ok := s.speculativeParent(spec, parentID)
+	if !ok {
+		return false
+	}
+	state, ok := spec.Speculate(parent, tx)
+	if !ok {
+		return false
+	}
+	entry := &speculativeBlock{height: height, parentID: parentKey, txs: len(tx), state: state}
+	if parentKey == "" {
+		entry.base = s.app.Info().LastHeight
+	}
+	s.speculative[blockID] = entry
+	s.metrics.speculativeBlocks.Set(float64(len(s.speculative)))
+	return true
+}
+
+// speculativeParent 返回子块应叠加的推测状态：父块已提交（或未知）时为已提交状态；
+// 父块尚无推测结果（如本节点自己的提案）时先沿父链补做推测执行。
+func (s *Service) speculativeParent(spec app.Speculator, parentID string) (string, app.SpecState, bool) {
+	block, ok := s.hotstuffBlocks[parentID]
+	if parentID == "" || !ok || block.Committed {
+		return "", nil, true
+	}
+	if entry, ok := s.speculative[parentID]; ok {
+		return parentID, entry.state, true
+	}
+	if !s.speculate(spec, block.Block.Height, parentID, block.Block.ParentBlockID, block.Block.Tx) {
+		return "", nil, false
+	}
+	return parentID, s.speculative[parentID].state, true
+}
+
+// finalizeBlock 把已提交区块交给应用定稿：推测状态恰好叠加在当前已提交状态上时直接复用，
+// 否则在已提交状态上完整执行，调用方需持有 s.mu。
+func (s *Service) finalizeBlock(height int, blockID string, tx []string) (app.FinalizeBlockResult, error) {
+	req := app.FinalizeBlockRequest{Height: height, BlockID: blockID, Txs: tx}
+	if spec, ok := s.app.(app.Speculator); ok {
+		entry, ok := s.speculative[blockID]
+		if ok && entry.height == height && entry.txs == len(tx) && entry.parentID == "" && entry.base == s.app.Info().LastHeight {
+			s.metrics.blocksFinalized.Inc("speculative")
+			return spec.FinalizeSpeculative(req, entry.state)
+		}
+	}
+	s.metrics.blocksFinalized.Inc("full")
+	return s.app.FinalizeBlock(req)
+}
+
+// settleSpeculative 在区块提交后把其子块改基到已提交状态，并丢弃不再可能提交的分叉：
+// 高度不高于已提交高度的、基于更早已提交状态的，以及父块已被丢弃的推测结果。
+func (s *Service) settleSpeculative(height int, blockID string) {
+	delete(s.speculative, blockID)
+	for _, entry := range s.speculative {
+		if entry.parentID == blockID {
+			entry.state.Rebase()
+			entry.parentID, entry.base = "", height
+		}
+	}
+	discarded := 0
+	for changed := true; changed; {
+		changed = false
+		for id, entry := range s.speculative {
+			_, parentKept := s.speculative[entry.parentID]
+			if entry.height <= height || (entry.parentID == "" && entry.base < height) || (entry.parentID != "" && !parentKept) {
+				delete(s.speculative, id)
+				discarded++
+				changed = true
+			}
+		}
+	}
+	if discarded > 0 {
+		s.metrics.speculativeDiscarded.Add(float64(discarded))
+		log.Printf("node=%d event=speculation_discarded height=%d blocks=%d", s.selfID, height, discarded)
+	}
+	s.metrics.speculativeBlocks.Set(float64(len(s.speculative)))
+}
+
+// resetSpeculative 丢弃全部推测结果（如安装快照后已提交状态整体替换），调用方需持有 s.mu。
+func (s *Service) resetSpeculative() {
+	s.speculative = map[string]*speculativeBlock{}
+	s.metrics.speculativeBlocks.Set(0)
+}
@@ -0,0 +1,115 @@
+package nodesvc
+
+import (
+	"errors"
+	"log"
+	"sort"
+
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
+	"mybft/internal/common"
+	"mybft/internal/election"
+	"mybft/internal/snapshot"
+)
+
+// 选主周期至少要比节点间提交进度的最大差距多一个 view：HotStuff 三链提交落后 3 个 view，
+// 流水线模式再加上窗口大小。
+func minElectionWindow(pipelineWindow int) int {
+	return pipelineWindow + 4
+}
+
+// loadElectionHistory 从区块库回放最近三个选主周期的提交记录；由快照引导、
+// 本地缺少快照之前的记录时先从本地最新快照恢复选主历史。
+func (s *Service) loadElectionHistory(window int) {
+	if s.stores == nil || s.committedHeight == 0 {
+		return
+	}
+	from := max(1, s.committedHeight-3*window+1)
+	records, err := s.stores.Blocks.ListCommits(from, s.committedHeight-from+1)
+	if err != nil {
+		log.Printf("node=%d load election history: %v", s.selfID, err)
+		return
+	}
+	if len(records) == 0 || records[0].Height > from {
+		s.restoreElectionFromSnapshot()
+	}
+	for _, r := range records {
+		entry := commitEntryFromRecord(r)
+		s.election.Observe(electionRecord(s.alg, entry.Height, entry.Proposer, entry.QC, entry.Justify))
+	}
+}
+
+func (s *Service) restoreElectionFromSnapshot() {
+	meta, err := s.stores.Snapshots.LoadLatestSnapshotMeta()
+	if errors.Is(err, goleveldb.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("node=%d load snapshot meta: %v", s.selfID, err)
+		return
+	}
+	chunks := make([][]byte, meta.Chunks)
+	for i := range chunks {
+		if chunks[i], err = s.stores.Snapshots.LoadSnapshotChunk(meta.Height, i); err != nil {
+			log.Printf("node=%d load snapshot chunk height=%d index=%d: %v", s.selfID, meta.Height, i, err)
+			return
+		}
+	}
+	record, err := snapshot.Decode(meta, chunks)
+	if err != nil {
+		log.Printf("node=%d decode snapshot height=%d: %v", s.selfID, meta.Height, err)
+		return
+	}
+	if err := s.election.Restore(record.Election); err != nil {
+		log.Printf("node=%d restore election history height=%d: %v", s.selfID, meta.Height, err)
+	}
+}
+
+// observeLeader 把已提交高度的提议者与签名者交给选主策略，调用方需持有 s.mu。
+func (s *Service) observeLeader(height int, qc common.QuorumCert, justify *common.QuorumCert) {
+	s.election.Observe(electionRecord(s.alg, height, s.leaderID(height), qc, justify))
+}
+
+// electionRecord 只取链上数据：HotStuff 用区块自身携带的 justify QC（同一区块在各节点一致，
+// 不取决于本地先收到哪份 QC），其他算法用 leader 广播、随提交记录保存的提交证明。
+func electionRecord(alg string, height, proposer int, qc common.QuorumCert, justify *common.QuorumCert) election.Record {
+	rec := election.Record{Height: height, Proposer: proposer}
+	switch {
+	case alg != "hotstuff":
+		rec.Signers, rec.QC = qc.Signers, qc.QC
+	case justify != nil:
+		rec.Signers, rec.QC = justify.Signers, justify.QC
+	}
+	return rec
+}
+
+// blockJustify 返回已提交 HotStuff 区块自身携带的 justify QC；其他算法或区块未登记时为 nil。调用方需持有 s.mu。
+func (s *Service) blockJustify(blockID string) *common.QuorumCert {
+	if s.alg != "hotstuff" {
+		return nil
+	}
+	b, ok := s.hotstuffBlocks[blockID]
+	if !ok || b.Block.JustifyQC == "" {
+		return nil
+	}
+	return &common.QuorumCert{Type: "HSQC", BlockID: b.Block.JustifyBlockID, View: b.Block.JustifyView, Height: b.Block.JustifyView, QC: b.Block.JustifyQC, Signers: b.Block.JustifySigners}
+}
+
+// signerIDs 返回已收集签名份额的节点编号（升序），随 QC 一起广播。
+func signerIDs(shares map[int]string) []int {
+	ids := make([]int, 0, len(shares))
+	for id := range shares {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// 各算法的提案消息类型；提案只接受来自该 view leader 的节点。
+func isProposal(msgType string) bool {
+	switch msgType {
+	case "PrePrepare", "HSProposal", "FHSProposal", "HPProposal":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,630 @@
+package nodesvc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
+	"mybft/internal/common"
+	"mybft/internal/membership"
+	"mybft/internal/storage"
+)
+
+// 单个区块最多打包的成员变更交易数。
+const maxBlockReconfig = 4
+
+// 追赶时每次向同伴查询的提交记录数。
+const catchUpBatch = 100
+
+// peerBook 保存同伴的密钥、地址与（MAC 模式下）成对 MAC 密钥。成员变更在共识流程中更新它，而交易与证据转发等路径不持有 s.mu，
+// 因此使用独立的读写锁。
+type peerBook struct {
+	mu      sync.RWMutex
+	keys    map[int]string
+	addrs   map[int]string
+	macKeys map[int][]byte
+}
+
+func newPeerBook() *peerBook {
+	return &peerBook{keys: map[int]string{}, addrs: map[int]string{}, macKeys: map[int][]byte{}}
+}
+
+func (b *peerBook) key(id int) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.keys[id]
+}
+
+func (b *peerBook) addr(id int) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.addrs[id]
+}
+
+func (b *peerBook) set(id int, key, addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.keys[id], b.addrs[id] = key, addr
+}
+
+func (b *peerBook) macKey(id int) []byte {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.macKeys[id]
+}
+
+func (b *peerBook) setMACKey(id int, key []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.macKeys[id] = key
+}
+
+// others 返回除 self 外所有已知同伴的地址。
+func (b *peerBook) others(self int) map[int]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make(map[int]string, len(b.addrs))
+	for id, addr := range b.addrs {
+		if id != self {
+			out[id] = addr
+		}
+	}
+	return out
+}
+
+// loadMembership 从状态库恢复验证者集合的变更历史，没有记录时为 cluster:config 的 1..N。
+func (s *Service) loadMembership() {
+	s.members = membership.Genesis(s.cfg.N)
+	if s.stores == nil {
+		return
+	}
+	raw, err := s.stores.State.LoadMembership()
+	if errors.Is(err, goleveldb.ErrNotFound) {
+		return
+	}
+	if err == nil {
+		err = s.members.Restore(raw)
+	}
+	if err != nil {
+		log.Printf("node=%d load membership: %v", s.selfID, err)
+	}
+}
+
+func (s *Service) persistMembership() {
+	if s.stores == nil {
+		return
+	}
+	raw, err := s.members.Export()
+	if err == nil {
+		err = s.stores.State.SaveMembership(raw)
+	}
+	if err != nil {
+		log.Printf("node=%d save membership: %v", s.selfID, err)
+	}
+}
+
+// syncPeers 为曾出现在任一集合中的节点（及本节点）加载密钥与地址：密钥来自 Redis Node:<id>，
+// 地址优先使用变更交易指定的地址，否则为 127.0.0.1:(basePort+id)；MAC 模式下同时协商成对 MAC 密钥。
+// 只访问 Redis 与各自带锁的 members/peers，不需要 s.mu；启动后经 refreshPeers 调用。
+func (s *Service) syncPeers() error {
+	ids := append(s.members.Known(), s.selfID)
+	for _, id := range ids {
+		addr := s.members.Addr(id)
+		if addr == "" {
+			addr = fmt.Sprintf("127.0.0.1:%d", s.cfg.BasePort+id)
+		}
+		key := s.peers.key(id)
+		if key == "" {
+			sk, err := s.rdb.HGet(fmt.Sprintf("Node:%d", id), "threshold_sk")
+			if err != nil || sk == "" {
+				return fmt.Errorf("load key Node:%d: %v", id, err)
+			}
+			key = sk
+		}
+		s.peers.set(id, key, addr)
+		if s.authMode == authMAC && s.peers.macKey(id) == nil {
+			if err := s.loadMACKey(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// refreshPeers 在成员变更后异步加载同伴密钥与地址：Redis 读取与 MAC 密钥协商不占用 s.mu，
+// 结果只在 peerBook 的锁内写入。变更在 reconfigLag 个高度后才生效，加载在此之前完成。
+func (s *Service) refreshPeers() {
+	go func() {
+		s.peerSync.Lock()
+		defer s.peerSync.Unlock()
+		if err := s.syncPeers(); err != nil {
+			log.Printf("node=%d sync peers: %v", s.selfID, err)
+		}
+	}()
+}
+
+// validators 返回 view 下的验证者集合。
+func (s *Service) validators(view int) []int {
+	return s.members.Validators(view)
+}
+
+// thresholds 按 view 下验证者的总投票权重计算门限，成员变更生效后 QC 门限随之改变；
+// 未配置权重时每个节点权重为 1，门限即按节点数计算。
+func (s *Service) thresholds(view int) common.Thresholds {
+	return common.CalcThresholds(s.power(s.validators(view)))
+}
+
+// power 汇总一组节点的投票权重。
+func (s *Service) power(ids []int) int {
+	return common.TotalPower(s.cfg.Weights, ids)
+}
+
+// sharesPower 汇总已收到签名份额的节点的投票权重：heightState.Prepared/Voted 按节点记录份额，
+// 是否达到门限按份额所属节点的权重而非份额个数判断。
+func (s *Service) sharesPower(shares map[int]string) int {
+	return s.power(signerIDs(shares))
+}
+
+// canVote 判断本节点在 view 下是否为验证者；非验证者只跟随提交，不投票。
+func (s *Service) canVote(view int) bool {
+	return s.members.Contains(view, s.selfID)
+}
+
+// audience 返回消息的接收方：view 下的验证者，以及已安排加入的节点（使其在生效前跟上进度）。
+func (s *Service) audience(view int) []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, id := range append(s.validators(view), s.members.Latest().Validators...) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// addReconfigTx 检查外部提交的成员变更交易并放入待打包集合；添加的节点须已在 Redis 中有密钥。
+func (s *Service) addReconfigTx(tx string) error {
+	c, err := membership.ParseTx(tx)
+	if err != nil {
+		return err
+	}
+	if err := s.members.Check(c); err != nil {
+		return err
+	}
+	if c.Op == membership.OpAdd {
+		if sk, err := s.rdb.HGet(fmt.Sprintf("Node:%d", c.ID), "threshold_sk"); err != nil || sk == "" {
+			return fmt.Errorf("no key for Node:%d", c.ID)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconfigPending[tx] = struct{}{}
+	return nil
+}
+
+// reconfigForProposal 挑选待打包的成员变更交易（按文本排序，至多 maxBlockReconfig 条），
+// 跳过父链上尚未提交区块已打包的交易，调用方需持有 s.mu。
+func (s *Service) reconfigForProposal(parentID string) []string {
+	if len(s.reconfigPending) == 0 {
+		return nil
+	}
+	packed := map[string]struct{}{}
+	for cur := parentID; cur != ""; {
+		block, ok := s.hotstuffBlocks[cur]
+		if !ok || block.Committed {
+			break
+		}
+		for _, tx := range block.Block.Reconfig {
+			packed[tx] = struct{}{}
+		}
+		cur = block.Block.ParentBlockID
+	}
+	var txs []string
+	for tx := range s.reconfigPending {
+		if _, ok := packed[tx]; !ok {
+			txs = append(txs, tx)
+		}
+	}
+	sort.Strings(txs)
+	if len(txs) > maxBlockReconfig {
+		txs = txs[:maxBlockReconfig]
+	}
+	return txs
+}
+
+// validReconfig 检查提案打包的成员变更交易：条数不超过上限且语法正确；
+// 变更能否应用在提交时按已提交的集合判定。
+func validReconfig(txs []string) error {
+	if len(txs) > maxBlockReconfig {
+		return fmt.Errorf("%d reconfig txs exceed limit %d", len(txs), maxBlockReconfig)
+	}
+	for _, tx := range txs {
+		if _, err := membership.ParseTx(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconfigLag 为成员变更从提交到生效的高度数：与选主周期的下限相同，
+// 保证生效时所有诚实节点都已提交该变更。
+func (s *Service) reconfigLag() int {
+	return minElectionWindow(s.pipelineWindow)
+}
+
+// applyReconfig 在区块提交时按顺序安排其中的成员变更，自 height+reconfigLag 起生效；
+// 无效的变更被跳过，所有节点得出相同结果。调用方需持有 s.mu。
+func (s *Service) applyReconfig(height int, txs []string) {
+	if len(txs) == 0 {
+		return
+	}
+	from := height + s.reconfigLag()
+	for _, tx := range txs {
+		delete(s.reconfigPending, tx)
+		c, err := membership.ParseTx(tx)
+		if err == nil {
+			err = s.members.Schedule(from, c)
+		}
+		if err != nil {
+			log.Printf("node=%d event=reconfig_rejected height=%d tx=%q err=%v", s.selfID, height, tx, err)
+			continue
+		}
+		log.Printf("node=%d event=reconfig_scheduled height=%d op=%s id=%d from=%d validators=%v", s.selfID, height, c.Op, c.ID, from, s.members.Latest().Validators)
+	}
+	s.persistMembership()
+	s.refreshPeers()
+	s.metrics.validators.Set(float64(len(s.validators(s.view))))
+}
+
+// fetchBlock 从指定节点的区块库拉取区块内容（交易、打包的证据与成员变更及 justify QC），按摘要校验内容，
+// 并用记录中的提案者签名校验父块与 justify 的指向。HotStuff 区块须带 justify QC，且 QC 须有效并证明父块
+// （流水线模式下 justify 可为更早的祖先，由 fetchAncestors 接上链后检查）。
+func (s *Service) fetchBlock(from int, blockID string) (common.Block, error) {
+	var record storage.BlockRecord
+	if err := s.getPeerJSON(s.peerURL(s.peers.addr(from), "/block?id="+blockID), &record); err != nil {
+		return common.Block{}, err
+	}
+	var evidence []common.Evidence
+	for _, r := range record.Evidence {
+		evidence = append(evidence, evidenceFromRecord(r))
+	}
+	if record.Digest != blockID || common.BlockDigest(record.View, record.Height, record.Tx, evidence, record.Reconfig) != blockID {
+		return common.Block{}, fmt.Errorf("block %s digest mismatch", blockID)
+	}
+	proposal := common.ConsensusMessage{
+		Type:      record.MessageType,
+		View:      record.View,
+		Height:    record.Height,
+		From:      record.From,
+		Digest:    record.Digest,
+		ParentID:  record.ParentBlockID,
+		JustifyID: record.JustifyBlockID,
+		SigFull:   record.SigFull,
+	}
+	if !isProposal(record.MessageType) || !s.members.Contains(record.View, record.From) || !s.validProposalSignature(proposal) {
+		return common.Block{}, fmt.Errorf("block %s lacks a valid proposer signature", blockID)
+	}
+	if s.alg == "hotstuff" {
+		if err := s.checkFetchedJustify(record); err != nil {
+			return common.Block{}, fmt.Errorf("block %s: %w", blockID, err)
+		}
+	}
+	return common.Block{
+		BlockID:        blockID,
+		ParentBlockID:  record.ParentBlockID,
+		JustifyBlockID: record.JustifyBlockID,
+		JustifyView:    record.JustifyView,
+		JustifyQC:      record.JustifyQC,
+		JustifySigners: record.JustifySigners,
+		Digest:         record.Digest,
+		View:           record.View,
+		Height:         record.Height,
+		Proposer:       record.From,
+		Tx:             record.Tx,
+		Evidence:       evidence,
+		Reconfig:       record.Reconfig,
+	}, nil
+}
+
+// checkFetchedJustify 检查拉取到的 HotStuff 区块的 justify QC：不可缺失、view 早于区块，
+// 逐高度推进时须证明父块；justify 不在区块摘要内，QC 须单独校验。
+func (s *Service) checkFetchedJustify(record storage.BlockRecord) error {
+	if record.JustifyQC == "" || record.JustifyBlockID == "" || record.ParentBlockID == "" {
+		return errors.New("missing justify qc")
+	}
+	if record.JustifyView >= record.View {
+		return fmt.Errorf("justify view %d not below block view %d", record.JustifyView, record.View)
+	}
+	if s.pipelineWindow == 0 && record.JustifyBlockID != record.ParentBlockID {
+		return fmt.Errorf("justify qc certifies %s, not parent %s", record.JustifyBlockID, record.ParentBlockID)
+	}
+	if record.JustifyBlockID == "genesis" {
+		if record.JustifyQC != "genesis-qc" {
+			return errors.New("invalid genesis justify qc")
+		}
+		return nil
+	}
+	qc := common.ConsensusMessage{Type: "HSQC", View: record.JustifyView, Height: record.JustifyView, BlockID: record.JustifyBlockID, Digest: record.JustifyBlockID, QC: record.JustifyQC, Signers: record.JustifySigners}
+	if !s.validQC(qc) {
+		return errors.New("invalid justify qc")
+	}
+	return nil
+}
+
+// startFetchAncestors 在 HotStuff 提案的父块未知时后台向提案者拉取祖先区块，同一时间只运行一次，
+// 拉取期间不持有 s.mu。调用方需持有 s.mu。
+func (s *Service) startFetchAncestors(msg common.ConsensusMessage) {
+	if s.fetchingAncestors {
+		return
+	}
+	s.fetchingAncestors = true
+	go s.fetchAncestorsAndResume(msg)
+}
+
+// fetchAncestorsAndResume 在锁外拉取提案 msg 缺失的祖先链，取回后重新加锁：父块仍缺失时登记祖先，
+// 再重新检查提案的 view 并按原路径处理（流水线模式下处理以父块为父暂存的提案）。
+func (s *Service) fetchAncestorsAndResume(msg common.ConsensusMessage) {
+	chain, err := s.fetchAncestors(msg.From, msg.ParentID, msg.Height)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetchingAncestors = false
+	if err != nil {
+		log.Printf("node=%d fetch ancestors of view=%d from=%d: %v", s.selfID, msg.View, msg.From, err)
+		return
+	}
+	if _, ok := s.hotstuffBlocks[msg.ParentID]; !ok && !s.registerAncestors(msg.From, chain) {
+		return
+	}
+	if s.pipelineWindow > 0 {
+		s.processOrphans(msg.ParentID)
+		return
+	}
+	// 拉取期间本地可能已推进到其他 view，此时提案已过期。
+	if msg.Height != s.height || msg.View != s.view {
+		s.metrics.staleDropped.Inc(msg.Type)
+		return
+	}
+	s.processHotStuff(msg, s.getHeightState(msg.Height))
+}
+
+// fetchAncestors 自 parentID 起向提案者逐个拉取祖先区块，直到接上本地已有的区块，返回由新到旧的链；
+// 追赶后的节点借此补齐已提交区块之上尚未提交的链。只在查询本地区块时短暂持有 s.mu，调用方不得持有 s.mu。
+func (s *Service) fetchAncestors(from int, parentID string, height int) ([]common.Block, error) {
+	var chain []common.Block
+	for cur := parentID; ; {
+		s.mu.Lock()
+		_, known := s.hotstuffBlocks[cur]
+		s.mu.Unlock()
+		if known {
+			return chain, nil
+		}
+		if cur == "" || len(chain) >= maxPipelineBuffered {
+			return nil, fmt.Errorf("no known ancestor within %d blocks", len(chain))
+		}
+		block, err := s.fetchBlock(from, cur)
+		if err != nil {
+			return nil, err
+		}
+		if height--; block.Height != height {
+			return nil, fmt.Errorf("ancestor %s has height %d, want %d", cur, block.Height, height)
+		}
+		chain = append(chain, block)
+		cur = block.ParentBlockID
+	}
+}
+
+// registerAncestors 登记 fetchAncestors 取回的链：最早的祖先须接上本地已有的区块（拉取期间可能已被清理），
+// 自它起逐个登记，每个区块的 justify 须在其已登记的父链上。调用方需持有 s.mu。
+func (s *Service) registerAncestors(from int, chain []common.Block) bool {
+	if len(chain) == 0 {
+		return false
+	}
+	if _, ok := s.hotstuffBlocks[chain[len(chain)-1].ParentBlockID]; !ok {
+		log.Printf("node=%d fetch ancestors from=%d: chain no longer connects to a known block", s.selfID, from)
+		return false
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if !s.extendsHotStuff(chain[i].ParentBlockID, chain[i].JustifyBlockID) {
+			log.Printf("node=%d fetch ancestor %s from=%d: justify %s not on parent chain", s.selfID, chain[i].BlockID, from, chain[i].JustifyBlockID)
+			return false
+		}
+		s.registerHotStuffBlock(chain[i])
+	}
+	log.Printf("node=%d event=ancestors_fetched from=%d blocks=%d", s.selfID, from, len(chain))
+	return true
+}
+
+// followsProposal 判断逐高度推进的 HotStuff 副本是否应跟随提案跳到其 view：追赶后本地高度停在已提交高度之后
+// （正常推进时落后三链提交两个高度），而集群的链尖至多领先两个未提交区块，缺失的祖先在校验提案时向提案者拉取。
+// 调用方需持有 s.mu。
+func (s *Service) followsProposal(msg common.ConsensusMessage) bool {
+	return s.alg == "hotstuff" && msg.Type == "HSProposal" && msg.View == msg.Height && msg.Height > s.height &&
+		s.committedHeight > 0 && s.height == s.committedHeight+1 && msg.Height-s.committedHeight <= 3
+}
+
+// startCatchUp 在收到远超本地高度的消息时后台追赶，同一时间只运行一次，调用方需持有 s.mu。
+func (s *Service) startCatchUp() {
+	if s.catchingUp {
+		return
+	}
+	s.catchingUp = true
+	go s.catchUp()
+}
+
+// catchUp 追赶集群已提交的区块，完成后若本节点是当前高度的 leader 则提议；追赶期间收到的高于本地高度的
+// 提案与 QC 在追赶结束后按高度重新处理，避免本节点因错过链尖而无法在之后的 view 担任 leader。
+func (s *Service) catchUp() {
+	synced := s.syncCommits()
+	s.mu.Lock()
+	s.catchingUp = false
+	pending := s.catchUpBuffered
+	s.catchUpBuffered = nil
+	if synced == 0 {
+		pending = nil
+	} else {
+		s.initPipeline()
+		s.leaderMode = s.isLeader(s.view)
+		if s.pipelineWindow > 0 {
+			s.maybeProposePipelined()
+		} else if _, proposed := s.proposedAt[s.height]; s.leaderMode && !proposed {
+			go s.proposeCurrentHeight()
+		}
+	}
+	s.mu.Unlock()
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Height < pending[j].Height })
+	for _, msg := range pending {
+		s.process(msg)
+	}
+}
+
+// bufferDuringCatchUp 暂存追赶期间收到的高于本地高度的提案与 QC，调用方需持有 s.mu。
+func (s *Service) bufferDuringCatchUp(msg common.ConsensusMessage) {
+	if s.catchingUp && (isProposal(msg.Type) || isQC(msg.Type)) && msg.Height > s.height && len(s.catchUpBuffered) < maxPipelineBuffered {
+		s.catchUpBuffered = append(s.catchUpBuffered, msg)
+	}
+}
+
+// syncCommits 向验证者查询提交记录，同一高度的区块 ID 须由投票权重至少为 q 的节点一致给出才采用，拉取区块并校验摘要后
+// 按高度顺序执行，返回回放的区块数。新加入的节点借此回放到自己成为验证者的高度，回放中的成员变更照常生效。
+func (s *Service) syncCommits() int {
+	synced := 0
+	for {
+		s.mu.Lock()
+		from := s.committedHeight + 1
+		peers, q := s.audience(from), s.thresholds(from).Q
+		s.mu.Unlock()
+		entries, sources := s.confirmedCommits(from, peers, q)
+		for _, entry := range entries {
+			block, err := s.fetchConfirmedBlock(entry, sources[entry.Height])
+			if err != nil {
+				log.Printf("node=%d event=catch_up_failed height=%d err=%v", s.selfID, entry.Height, err)
+				return synced
+			}
+			s.mu.Lock()
+			if entry.Height != s.committedHeight+1 {
+				s.mu.Unlock()
+				return synced
+			}
+			s.replayCommit(block, entry.QC)
+			s.mu.Unlock()
+			synced++
+		}
+		if len(entries) < catchUpBatch {
+			return synced
+		}
+	}
+}
+
+// confirmedCommits 返回自 from 起连续的、各高度区块 ID 由权重至少为 q 的同伴一致给出的提交记录，
+// 以及每个高度给出该区块的同伴。
+func (s *Service) confirmedCommits(from int, peers []int, q int) ([]common.CommitEntry, map[int][]int) {
+	votes := map[string][]int{}
+	byKey := map[string]common.CommitEntry{}
+	for _, id := range peers {
+		if id == s.selfID {
+			continue
+		}
+		var resp common.CommitsResponse
+		if err := s.getPeerJSON(s.peerURL(s.peers.addr(id), fmt.Sprintf("/commits?from=%d&limit=%d", from, catchUpBatch)), &resp); err != nil {
+			continue
+		}
+		for _, entry := range resp.Entries {
+			key := fmt.Sprintf("%d:%s", entry.Height, entry.BlockID)
+			votes[key] = append(votes[key], id)
+			if _, ok := byKey[key]; !ok {
+				byKey[key] = entry
+			}
+		}
+	}
+	var entries []common.CommitEntry
+	sources := map[int][]int{}
+	for h := from; ; h++ {
+		found := false
+		for key, ids := range votes {
+			if entry := byKey[key]; entry.Height == h && s.power(ids) >= q {
+				entries = append(entries, entry)
+				sources[h] = ids
+				found = true
+				break
+			}
+		}
+		if !found {
+			return entries, sources
+		}
+	}
+}
+
+func (s *Service) fetchConfirmedBlock(entry common.CommitEntry, sources []int) (common.Block, error) {
+	var lastErr error
+	for _, id := range sources {
+		block, err := s.fetchBlock(id, entry.BlockID)
+		if err == nil && block.Height != entry.Height {
+			err = fmt.Errorf("block %s has height %d, want %d", entry.BlockID, block.Height, entry.Height)
+		}
+		if err == nil {
+			return block, nil
+		}
+		lastErr = err
+	}
+	return common.Block{}, lastErr
+}
+
+// replayCommit 执行追赶得到的已提交区块并推进提交指针与当前高度，调用方需持有 s.mu。
+func (s *Service) replayCommit(block common.Block, qc common.QuorumCert) {
+	s.executeCommitted(block)
+	if s.alg == "hotstuff" {
+		cert := qc
+		s.hotstuffBlocks[block.BlockID] = &hotstuffBlock{Block: block, QC: &cert, Committed: true, Executed: true}
+		if qc.View > s.hotstuffHighQC.View {
+			s.hotstuffHighQC = qc
+			s.hotstuffLockedQC = qc
+		}
+	}
+	s.markCommitted(block.Height, block.BlockID, qc)
+	if block.Height >= s.height {
+		s.height = block.Height + 1
+		s.view = s.height
+		s.persistPosition()
+	}
+	log.Printf("node=%d event=block_synced height=%d block=%s", s.selfID, block.Height, block.BlockID)
+}
+
+// HandleMembership 返回当前 view 下的验证者集合及各自投票权重、门限与已安排的变更历史。
+func (s *Service) HandleMembership(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	view := s.view
+	s.mu.Unlock()
+	history, err := s.members.Export()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	validators := s.validators(view)
+	power := make(map[int]int, len(validators))
+	for _, id := range validators {
+		power[id] = s.power([]int{id})
+	}
+	th := s.thresholds(view)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		View       int             `json:"view"`
+		Validators []int           `json:"validators"`
+		Power      map[int]int     `json:"power"`
+		TotalPower int             `json:"total_power"`
+		T          int             `json:"t"`
+		Q          int             `json:"q"`
+		History    json.RawMessage `json:"history"`
+	}{View: view, Validators: validators, Power: power, TotalPower: th.N, T: th.T, Q: th.Q, History: history})
+}
@@ -0,0 +1,158 @@
+package nodesvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"mybft/internal/app"
+	"mybft/internal/common"
+	"mybft/internal/membership"
+	"mybft/internal/mempool"
+)
+
+const defaultMempoolSize = 10000
+
+// 交易池容量，来自 MYBFT_MEMPOOL_SIZE。
+func mempoolSizeFromEnv() int {
+	if raw := os.Getenv("MYBFT_MEMPOOL_SIZE"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			return v
+		}
+	}
+	return defaultMempoolSize
+}
+
+// 交易池为空时是否由应用生成模拟负载，MYBFT_SYNTHETIC_LOAD=0 关闭（仅使用外部提交的交易）。
+func syntheticLoadFromEnv() bool {
+	return os.Getenv("MYBFT_SYNTHETIC_LOAD") != "0"
+}
+
+// HandleTx 接收外部提交的单笔（tx）或批量（txs）交易，CheckTx 通过后入池并转发给其他节点；
+// 成员变更交易（reconfig ...）不经过应用，放入单独的待打包集合。
+func (s *Service) HandleTx(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.limitBody(w, r)
+	var req common.TxSubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(bodyErrorStatus(err))
+		return
+	}
+	s.submitTxs(w, req, true)
+}
+
+// HandleTxGossip 接收同伴转发的交易，只入池不再转发。与共识消息入口一样做身份认证
+// （TLS 证书或 MAC）并按发送节点限流。
+func (s *Service) HandleTxGossip(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.limitBody(w, r)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(bodyErrorStatus(err))
+		return
+	}
+	var req common.TxSubmitRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.authenticPeer(r, "tx_gossip", req.From) || !s.authenticMAC(r, req.From, body) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if !s.limiter.allow(s.limitKey(r, req.From)) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	s.submitTxs(w, req, false)
+}
+
+func (s *Service) submitTxs(w http.ResponseWriter, req common.TxSubmitRequest, gossip bool) {
+	txs := req.Txs
+	if req.Tx != "" {
+		txs = append([]string{req.Tx}, txs...)
+	}
+	if len(txs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	resp := common.TxSubmitResponse{Results: make([]common.TxSubmitResult, 0, len(txs))}
+	accepted := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		result := common.TxSubmitResult{ID: common.TxID(tx)}
+		if membership.IsTx(tx) {
+			if err := s.addReconfigTx(tx); err != nil {
+				result.Code, result.Log = app.CodeRejected, err.Error()
+			} else {
+				accepted = append(accepted, tx)
+			}
+			resp.Results = append(resp.Results, result)
+			continue
+		}
+		_, check, err := s.mempool.Add(tx)
+		switch {
+		case err == nil:
+			accepted = append(accepted, tx)
+		case errors.Is(err, mempool.ErrCheckTx):
+			result.Code, result.Log = check.Code, check.Log
+		default:
+			result.Code, result.Log = app.CodeRejected, err.Error()
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	if gossip && len(accepted) > 0 {
+		s.gossipTxs(accepted)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// 把新入池的交易批量转发给其他节点，使任意节点都能接收交易。
+func (s *Service) gossipTxs(txs []string) {
+	body, err := json.Marshal(common.TxSubmitRequest{Txs: txs, From: s.selfID})
+	if err != nil {
+		return
+	}
+	for id, addr := range s.peers.others(s.selfID) {
+		go func(id int, addr string) {
+			req, err := http.NewRequest(http.MethodPost, s.peerURL(addr, "/tx/gossip"), bytes.NewReader(body))
+			if err != nil {
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			s.setMAC(req, id, body)
+			resp, err := s.httpClient.Do(req)
+			if err != nil {
+				log.Printf("node=%d gossip tx to %s: %v", s.selfID, addr, err)
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				log.Printf("node=%d gossip tx to %s: %s", s.selfID, addr, resp.Status)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}(id, addr)
+	}
+}
+
+// 待提交区块中已包含的交易，leader 从交易池取批时跳过它们。
+func pendingTxIDs(pending [][]string) map[string]struct{} {
+	ids := map[string]struct{}{}
+	for _, txs := range pending {
+		for _, tx := range txs {
+			ids[common.TxID(tx)] = struct{}{}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,108 @@
+package nodesvc
+
+import (
+	"time"
+
+	"mybft/internal/crypto"
+	"mybft/internal/metrics"
+)
+
+// nodeMetrics 汇总节点对外暴露在 /metrics 的指标。
+type nodeMetrics struct {
+	registry             *metrics.Registry
+	view                 *metrics.Gauge
+	height               *metrics.Gauge
+	committedHeight      *metrics.Gauge
+	sent                 *metrics.CounterVec
+	sentBytes            *metrics.CounterVec
+	received             *metrics.CounterVec
+	sigVerify            *metrics.CounterVec
+	qcsFormed            *metrics.CounterVec
+	staleDropped         *metrics.CounterVec
+	commitLatency        *metrics.Histogram
+	commitSubscribers    *metrics.Gauge
+	uncommittedBlocks    *metrics.Gauge
+	speculativeBlocks    *metrics.Gauge
+	speculativeDiscarded *metrics.Counter
+	blocksFinalized      *metrics.CounterVec
+	equivocations        *metrics.Counter
+	evidence             *metrics.CounterVec
+	evidencePending      *metrics.Gauge
+	evidenceCommitted    *metrics.CounterVec
+	validators           *metrics.Gauge
+	nonValidatorDropped  *metrics.CounterVec
+	peerAuthRejected     *metrics.CounterVec
+	macVerify            *metrics.CounterVec
+	authSeconds          *metrics.CounterVec
+	rejected             *metrics.CounterVec
+}
+
+func newNodeMetrics() *nodeMetrics {
+	r := metrics.NewRegistry()
+	return &nodeMetrics{
+		registry:             r,
+		view:                 r.NewGauge("mybft_node_view", "Current consensus view."),
+		height:               r.NewGauge("mybft_node_height", "Current consensus height."),
+		committedHeight:      r.NewGauge("mybft_node_committed_height", "Highest committed height."),
+		sent:                 r.NewCounterVec("mybft_node_messages_sent_total", "Consensus messages sent, by message type.", "type"),
+		sentBytes:            r.NewCounterVec("mybft_node_message_bytes_sent_total", "Encoded consensus message bytes sent, by message type.", "type"),
+		received:             r.NewCounterVec("mybft_node_messages_received_total", "Consensus messages received, by message type.", "type"),
+		sigVerify:            r.NewCounterVec("mybft_node_signature_verifications_total", "Signature verifications, by kind and result.", "kind", "result"),
+		qcsFormed:            r.NewCounterVec("mybft_node_qcs_formed_total", "Quorum certificates formed by this node as leader, by QC type.", "type"),
+		staleDropped:         r.NewCounterVec("mybft_node_stale_messages_dropped_total", "Messages dropped for a height or view other than the current one, by message type.", "type"),
+		commitLatency:        r.NewHistogram("mybft_node_commit_latency_seconds", "Time from the first message seen for a height to its local commit.", metrics.DefaultLatencyBuckets),
+		commitSubscribers:    r.NewGauge("mybft_node_commit_subscribers", "Open commit notification streams."),
+		uncommittedBlocks:    r.NewGauge("mybft_node_uncommitted_blocks", "Proposed HotStuff blocks above the highest committed one (pipeline mode)."),
+		speculativeBlocks:    r.NewGauge("mybft_node_speculative_blocks", "Uncommitted blocks holding speculative execution state."),
+		speculativeDiscarded: r.NewCounter("mybft_node_speculative_blocks_discarded_total", "Speculative block states discarded with an abandoned fork."),
+		blocksFinalized:      r.NewCounterVec("mybft_node_blocks_finalized_total", "Committed blocks finalized, by whether speculative state was reused or the block was executed in full.", "mode"),
+		equivocations:        r.NewCounter("mybft_node_equivocations_detected_total", "Pairs of conflicting signed proposals seen for the same view."),
+		evidence:             r.NewCounterVec("mybft_node_evidence_total", "Verified misbehavior evidence stored, by kind and source (local, gossip, block).", "kind", "source"),
+		evidencePending:      r.NewGauge("mybft_node_evidence_pending", "Stored evidence not yet included in a committed block."),
+		evidenceCommitted:    r.NewCounterVec("mybft_node_evidence_committed_total", "Evidence included in committed blocks, by kind.", "kind"),
+		validators:           r.NewGauge("mybft_node_validators", "Validators in the current view."),
+		nonValidatorDropped:  r.NewCounterVec("mybft_node_non_validator_messages_dropped_total", "Messages dropped because the sender is not a validator for the message view, by message type.", "type"),
+		peerAuthRejected:     r.NewCounterVec("mybft_node_peer_auth_rejected_total", "Requests rejected because the TLS peer certificate is missing or does not match the claimed node, by endpoint.", "endpoint"),
+		macVerify:            r.NewCounterVec("mybft_node_mac_verifications_total", "Transport MAC verifications in MYBFT_AUTH=mac mode, by result.", "result"),
+		authSeconds:          r.NewCounterVec("mybft_node_auth_seconds_total", "CPU time spent authenticating consensus messages, by method (sig: vote signature shares, mac: transport MACs on every message).", "method"),
+		rejected:             r.NewCounterVec("mybft_node_messages_rejected_total", "Consensus messages rejected before entering the protocol, by reason.", "reason"),
+	}
+}
+
+func verifyResult(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "fail"
+}
+
+// verifyShare 校验单个签名份额并计数。
+func (s *Service) verifyShare(from int, msg []byte, sig string) bool {
+	start := time.Now()
+	ok := crypto.Verify(s.peers.key(from), msg, sig)
+	s.metrics.authSeconds.Add(time.Since(start).Seconds(), authSignature)
+	s.metrics.sigVerify.Inc("share", verifyResult(ok))
+	return ok
+}
+
+// verifyAggregate 校验聚合签名并计数。
+func (s *Service) verifyAggregate(shares []string, proof string) bool {
+	ok := crypto.VerifyAggregate(shares, proof)
+	s.metrics.sigVerify.Inc("aggregate", verifyResult(ok))
+	return ok
+}
+
+// observePosition 同步当前高度与 view 指标，调用方需持有 s.mu。
+func (s *Service) observePosition() {
+	s.metrics.height.Set(float64(s.height))
+	s.metrics.view.Set(float64(s.view))
+	s.metrics.committedHeight.Set(float64(s.committedHeight))
+}
+
+// observeCommit 记录从首次收到该高度消息到本地提交的时延，调用方需持有 s.mu。
+func (s *Service) observeCommit(height int) {
+	if hs, ok := s.state[height]; ok && !hs.FirstSeen.IsZero() {
+		s.metrics.commitLatency.Observe(time.Since(hs.FirstSeen).Seconds())
+	}
+	s.metrics.committedHeight.Set(float64(s.committedHeight))
+}
@@ -0,0 +1,42 @@
+package nodesvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"mybft/internal/common"
+)
+
+// markPhase 记录本节点在某高度首次进入某阶段的时间，调用方需持有 s.mu。
+func (s *Service) markPhase(height, view int, phase string) {
+	hs := s.getHeightState(height)
+	if hs.View == 0 {
+		hs.View = view
+	}
+	if hs.Phases == nil {
+		hs.Phases = map[string]int64{}
+	}
+	if _, ok := hs.Phases[phase]; !ok {
+		hs.Phases[phase] = time.Now().UnixNano()
+	}
+}
+
+// reportPhases 在某高度执行后把各阶段时间戳上报给 client 的 /phase，调用方需持有 s.mu。
+func (s *Service) reportPhases(height int) {
+	hs, ok := s.state[height]
+	if !ok || hs.View == 0 {
+		return
+	}
+	req := common.PhaseReport{Height: height, View: hs.View, From: s.selfID, Leader: s.leaderID(hs.View), Alg: s.alg, Phases: map[string]int64{}}
+	for phase, at := range hs.Phases {
+		req.Phases[phase] = at
+	}
+	go func() {
+		body, _ := json.Marshal(req)
+		resp, err := s.httpClient.Post(s.clientURL+"/phase", "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+}
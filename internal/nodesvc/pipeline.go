@@ -0,0 +1,408 @@
+package nodesvc
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
+	"mybft/internal/common"
+	"mybft/internal/crypto"
+)
+
+// 链式提交需要三个连续带 QC 的区块，窗口至少为 3。
+const minPipelineWindow = 3
+
+// 乱序到达的提案与 QC 的缓存上限。
+const maxPipelineBuffered = 256
+
+// HotStuff 流水线窗口，来自 MYBFT_HOTSTUFF_PIPELINE：允许的未提交区块数（含正在提议的区块），
+// 0 或未设置时沿用逐高度推进的流程，小于 3 的正值按 3 处理。
+func pipelineWindowFromEnv() int {
+	raw := os.Getenv("MYBFT_HOTSTUFF_PIPELINE")
+	if raw == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		return 0
+	}
+	return max(v, minPipelineWindow)
+}
+
+// initPipeline 从已加载的 highQC、投票与提交位置恢复流水线游标，调用方需持有 s.mu 或尚未对外服务。
+func (s *Service) initPipeline() {
+	if s.pipelineWindow == 0 {
+		return
+	}
+	s.hotstuffTip = s.hotstuffHighQC.BlockID
+	s.nextProposalView = s.hotstuffHighQC.View + 1
+	s.highestCommittedView = s.committedHeight
+	s.highestVotedView = s.view - 1
+	if s.stores != nil {
+		if _, err := s.stores.State.LoadVote(s.view); err == nil {
+			s.highestVotedView = s.view
+		} else if !errors.Is(err, goleveldb.ErrNotFound) {
+			log.Printf("node=%d load vote view=%d: %v", s.selfID, s.view, err)
+		}
+	}
+	s.observePipeline()
+}
+
+// 流水线模式下只接受 view 与高度一致、且落在 (已提交 view, 下一提案 view + 窗口] 内的消息。
+func (s *Service) pipelineAccepts(msg common.ConsensusMessage) bool {
+	return msg.View == msg.Height && msg.Height > s.highestCommittedView && msg.Height <= s.nextProposalView+s.pipelineWindow
+}
+
+// processHotStuffPipelined 是流水线模式的链式 HotStuff：副本收到提案即推进到该 view，
+// 下一任 leader 在未提交区块数不超过窗口时直接在最新区块上提议，不再等待 advanceHeight。
+func (s *Service) processHotStuffPipelined(msg common.ConsensusMessage, hs *heightState) {
+	switch msg.Type {
+	case "HSProposal":
+		s.onPipelinedProposal(msg)
+	case "HSVote":
+		if !s.isLeader(msg.View) {
+			return
+		}
+		blockID := s.messageBlockID(msg)
+		hs.Voted[msg.From] = msg.SigShare
+		if s.sharesPower(hs.Voted) < s.thresholds(msg.View).T || hs.Done || !s.certifyShares(hs.Voted, "HSVote", msg.View, msg.Height, blockID) {
+			return
+		}
+		shares := make([]string, 0, len(hs.Voted))
+		for _, sig := range hs.Voted {
+			shares = append(shares, sig)
+		}
+		qcMsg := common.ConsensusMessage{
+			Type:    "HSQC",
+			View:    msg.View,
+			Height:  msg.Height,
+			From:    s.selfID,
+			BlockID: blockID,
+			Digest:  blockID,
+			QC:      crypto.Aggregate(shares),
+			Signers: signerIDs(hs.Voted),
+		}
+		s.signQC(&qcMsg)
+		hs.Done = true
+		s.metrics.qcsFormed.Inc(qcMsg.Type)
+		s.markPhase(msg.Height, msg.View, common.PhaseQuorumReached)
+		s.persistQC(qcMsg)
+		s.recordHotStuffQC(quorumCertFromMessage(qcMsg))
+		s.broadcast(qcMsg)
+		s.maybeProposePipelined()
+	case "HSQC":
+		blockID := s.messageBlockID(msg)
+		if hs.Done || blockID == "" || msg.QC == "" {
+			return
+		}
+		hs.Done = true
+		s.markPhase(msg.Height, msg.View, common.PhaseQCReceived)
+		s.persistQC(msg)
+		s.recordHotStuffQC(quorumCertFromMessage(msg))
+		s.maybeProposePipelined()
+	}
+}
+
+func (s *Service) onPipelinedProposal(msg common.ConsensusMessage) {
+	block := common.Block{
+		BlockID:        s.messageBlockID(msg),
+		ParentBlockID:  msg.ParentID,
+		JustifyBlockID: msg.JustifyID,
+		JustifyView:    msg.JustifyView,
+		JustifyQC:      msg.JustifyQC,
+		JustifySigners: msg.JustifySigners,
+		Digest:         msg.Digest,
+		View:           msg.View,
+		Height:         msg.Height,
+		Proposer:       msg.From,
+		Tx:             append([]string(nil), msg.Tx...),
+		Evidence:       msg.Evidence,
+		Reconfig:       msg.Reconfig,
+	}
+	if common.BlockDigest(msg.View, msg.Height, msg.Tx, msg.Evidence, msg.Reconfig) != msg.Digest || block.BlockID == "" || block.ParentBlockID == "" {
+		return
+	}
+	if _, ok := s.hotstuffBlocks[block.ParentBlockID]; !ok {
+		// 父块提案尚未到达：暂存，父块登记后再处理；落后超过一个 view 时另在锁外向提案者拉取祖先。
+		if len(s.hotstuffOrphans) < maxPipelineBuffered {
+			s.hotstuffOrphans[block.ParentBlockID] = append(s.hotstuffOrphans[block.ParentBlockID], msg)
+		}
+		if msg.Height > s.nextProposalView+1 {
+			s.startFetchAncestors(msg)
+		}
+		return
+	}
+	if !s.validatePipelinedProposal(block) {
+		return
+	}
+	s.markPhase(msg.Height, msg.View, common.PhaseProposalReceived)
+	s.registerHotStuffBlock(block)
+	s.persistProposal(msg)
+	s.advancePipelineTip(block)
+	if msg.JustifyQC != "" && block.JustifyBlockID != "genesis" {
+		if justify, ok := s.hotstuffBlocks[block.JustifyBlockID]; ok {
+			s.recordHotStuffQC(common.QuorumCert{Type: "HSQC", BlockID: block.JustifyBlockID, View: msg.JustifyView, Height: justify.Block.Height, QC: msg.JustifyQC, Signers: msg.JustifySigners})
+		}
+	}
+	s.updateLockedQCFromProposal(block, msg)
+	s.votePipelined(block, msg)
+	if qc, ok := s.hotstuffEarlyQCs[block.BlockID]; ok {
+		delete(s.hotstuffEarlyQCs, block.BlockID)
+		s.recordHotStuffQC(qc)
+	}
+	s.processOrphans(block.BlockID)
+	s.maybeProposePipelined()
+}
+
+// processOrphans 在 blockID 登记后处理以它为父块暂存的提案，调用方需持有 s.mu。
+func (s *Service) processOrphans(blockID string) {
+	if orphans, ok := s.hotstuffOrphans[blockID]; ok {
+		delete(s.hotstuffOrphans, blockID)
+		for _, child := range orphans {
+			if s.pipelineAccepts(child) {
+				s.onPipelinedProposal(child)
+			}
+		}
+	}
+}
+
+// 流水线提案须紧接父块高度，justify 为父链上的祖先（可落后于父块），并满足锁定规则。
+func (s *Service) validatePipelinedProposal(block common.Block) bool {
+	parent := s.hotstuffBlocks[block.ParentBlockID]
+	if parent.Block.Height+1 != block.Height {
+		return false
+	}
+	if block.JustifyBlockID == "" || block.JustifyView >= block.View || !s.extendsHotStuff(block.ParentBlockID, block.JustifyBlockID) {
+		return false
+	}
+	if s.hotstuffLockedQC.BlockID != "" && s.hotstuffLockedQC.BlockID != "genesis" {
+		if block.JustifyView < s.hotstuffLockedQC.View && !s.extendsHotStuff(block.ParentBlockID, s.hotstuffLockedQC.BlockID) {
+			return false
+		}
+	}
+	return true
+}
+
+// votePipelined 只为高于 highestVotedView 且延伸自己认可区块（已投票、已有 QC 或已提交）的提案投票。
+func (s *Service) votePipelined(block common.Block, msg common.ConsensusMessage) {
+	if block.View <= s.highestVotedView {
+		return
+	}
+	parent := s.hotstuffBlocks[block.ParentBlockID]
+	if parent.QC == nil && !parent.Committed && s.hotstuffVoted[parent.Block.View] != parent.Block.BlockID {
+		return
+	}
+	if !s.processProposal(block.Height, block.BlockID, block.ParentBlockID, msg.Tx) || !s.canVote(msg.View) {
+		return
+	}
+	m := crypto.VoteMessage("HSVote", msg.View, msg.Height, block.BlockID, s.selfID)
+	sig := crypto.Sign(s.peers.key(s.selfID), m)
+	s.hotstuffVoted[msg.View] = block.BlockID
+	s.highestVotedView = msg.View
+	s.persistVote(msg.View, block.BlockID)
+	s.sendTo(s.leaderID(msg.View), common.ConsensusMessage{
+		Type:     "HSVote",
+		View:     msg.View,
+		Height:   msg.Height,
+		From:     s.selfID,
+		BlockID:  block.BlockID,
+		Digest:   block.BlockID,
+		SigShare: sig,
+	})
+	s.markPhase(msg.Height, msg.View, common.PhaseVoteSent)
+}
+
+// advancePipelineTip 把最新提案区块作为链尖，推进本地 view/高度与下一提案 view。
+func (s *Service) advancePipelineTip(block common.Block) {
+	if block.View < s.nextProposalView {
+		return
+	}
+	s.nextProposalView = block.View + 1
+	s.hotstuffTip = block.BlockID
+	s.height, s.view = block.Height, block.View
+	s.persistPosition()
+	s.observePipeline()
+}
+
+// recordHotStuffQC 记录区块的 QC（区块未到达时暂存），必要时更新 highQC 并检查三链提交。
+func (s *Service) recordHotStuffQC(qc common.QuorumCert) {
+	block, ok := s.hotstuffBlocks[qc.BlockID]
+	if !ok {
+		if len(s.hotstuffEarlyQCs) < maxPipelineBuffered {
+			s.hotstuffEarlyQCs[qc.BlockID] = qc
+		}
+		return
+	}
+	if block.QC == nil {
+		cert := qc
+		block.QC = &cert
+	}
+	if qc.View > s.hotstuffHighQC.View {
+		s.hotstuffHighQC = qc
+		s.persistHighQC(common.ConsensusMessage{Type: qc.Type, View: qc.View, Height: qc.Height, From: s.selfID, BlockID: qc.BlockID, Digest: qc.BlockID, QC: qc.QC})
+	}
+	s.commitHotStuffPipelined()
+}
+
+// commitHotStuffPipelined 自 highQC 区块沿父链向下寻找高度连续且都带 QC 的三个区块（祖父、父、自身），
+// 按高度顺序提交其中的祖父块及之前所有未提交的祖先。
+func (s *Service) commitHotStuffPipelined() {
+	for cur := s.hotstuffBlocks[s.hotstuffHighQC.BlockID]; cur != nil && !cur.Committed; cur = s.hotstuffBlocks[cur.Block.ParentBlockID] {
+		parent := s.hotstuffBlocks[cur.Block.ParentBlockID]
+		if parent == nil || parent.Committed {
+			return
+		}
+		grand := s.hotstuffBlocks[parent.Block.ParentBlockID]
+		if grand == nil || grand.Committed {
+			return
+		}
+		if cur.QC == nil || parent.QC == nil || grand.QC == nil {
+			continue
+		}
+		if parent.Block.Height+1 != cur.Block.Height || grand.Block.Height+1 != parent.Block.Height {
+			continue
+		}
+		s.commitHotStuffUpTo(grand, parent)
+		return
+	}
+}
+
+// commitHotStuffUpTo 提交 target 及其之前所有未提交的祖先（由低到高）：链式 HotStuff 中提交一个区块即提交整条祖先链，
+// 因 view 跳跃而未单独提交的祖先也在此执行。祖先缺失时不提交，交由追赶补齐。child 为 target 在链上的子块，
+// 各区块的提交证明优先取子块携带的 justify QC。调用方需持有 s.mu。
+func (s *Service) commitHotStuffUpTo(target, child *hotstuffBlock) {
+	var chain []*hotstuffBlock
+	cur := target
+	for cur != nil && !cur.Committed {
+		chain = append(chain, cur)
+		cur = s.hotstuffBlocks[cur.Block.ParentBlockID]
+	}
+	if cur == nil {
+		log.Printf("node=%d event=commit_gap height=%d block=%s", s.selfID, target.Block.Height, target.Block.BlockID)
+		s.startCatchUp()
+		return
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		b := chain[i]
+		b.Committed = true
+		if !b.Executed {
+			s.executeCommitted(b.Block)
+			b.Executed = true
+		}
+		next := child
+		if i > 0 {
+			next = chain[i-1]
+		}
+		s.markCommitted(b.Block.Height, b.Block.BlockID, committedHotStuffQC(b, next))
+		s.highestCommittedView = b.Block.View
+		go s.reportEnd(b.Block.Height)
+	}
+	if s.pipelineWindow > 0 {
+		s.observePipeline()
+	}
+}
+
+// committedHotStuffQC 返回区块 b 的提交证明：子块 next 的 justify 指向 b 时取这份链上 QC，
+// 各节点对同一区块一致；否则取本地收到的 QC。
+func committedHotStuffQC(b, next *hotstuffBlock) common.QuorumCert {
+	if next != nil && next.Block.JustifyBlockID == b.Block.BlockID && next.Block.JustifyQC != "" {
+		return common.QuorumCert{Type: "HSQC", BlockID: b.Block.BlockID, View: next.Block.JustifyView, Height: b.Block.Height, QC: next.Block.JustifyQC, Signers: next.Block.JustifySigners}
+	}
+	if b.QC != nil {
+		return *b.QC
+	}
+	return common.QuorumCert{Type: "HSQC", BlockID: b.Block.BlockID, View: b.Block.View, Height: b.Block.Height}
+}
+
+// maybeProposePipelined 在本节点是下一提案 view 的 leader、已认可链尖区块且未提交区块数不超过窗口时异步提议，调用方需持有 s.mu。
+func (s *Service) maybeProposePipelined() {
+	v := s.nextProposalView
+	if s.pipelineProposing || !s.isLeader(v) {
+		return
+	}
+	tip := s.hotstuffBlocks[s.hotstuffTip]
+	if tip == nil || tip.Block.View != v-1 {
+		return
+	}
+	if tip.QC == nil && !tip.Committed && s.hotstuffVoted[tip.Block.View] != tip.Block.BlockID {
+		return
+	}
+	if !s.extendsHotStuff(tip.Block.BlockID, s.hotstuffHighQC.BlockID) {
+		return
+	}
+	if v-s.highestCommittedView > s.pipelineWindow {
+		if s.pipelineStalledView != v {
+			s.pipelineStalledView = v
+			log.Printf("node=%d event=pipeline_backpressure view=%d committed_view=%d window=%d", s.selfID, v, s.highestCommittedView, s.pipelineWindow)
+		}
+		return
+	}
+	s.pipelineProposing = true
+	go s.proposePipelined(v, tip.Block.BlockID, s.hotstuffHighQC)
+}
+
+// proposePipelined 以 parentID 为父块、justify 为 highQC 构造并广播 view 的提案。
+func (s *Service) proposePipelined(view int, parentID string, justify common.QuorumCert) {
+	limits := s.batchPolicy.Limits()
+	s.waitForBatch(limits)
+	s.mu.Lock()
+	pending := s.pendingPayloads(parentID)
+	evidence := s.evidenceForProposal(parentID)
+	reconfig := s.reconfigForProposal(parentID)
+	s.mu.Unlock()
+	tx := s.prepareProposal(view, pending, limits)
+	digest := common.BlockDigest(view, view, tx, evidence, reconfig)
+	s.mu.Lock()
+	s.proposedAt[view] = time.Now()
+	s.mu.Unlock()
+	s.callStart(view, view, s.batchPolicy.Info(limits, tx))
+	msg := common.ConsensusMessage{
+		Type:           "HSProposal",
+		View:           view,
+		Height:         view,
+		From:           s.selfID,
+		BlockID:        digest,
+		ParentID:       parentID,
+		JustifyID:      justify.BlockID,
+		JustifyQC:      justify.QC,
+		JustifyView:    justify.View,
+		JustifySigners: justify.Signers,
+		Digest:         digest,
+		Tx:             tx,
+		Evidence:       evidence,
+		Reconfig:       reconfig,
+	}
+	s.signProposal(&msg)
+	block := common.Block{
+		BlockID:        digest,
+		ParentBlockID:  parentID,
+		JustifyBlockID: justify.BlockID,
+		JustifyView:    justify.View,
+		JustifyQC:      justify.QC,
+		JustifySigners: justify.Signers,
+		Digest:         digest,
+		View:           view,
+		Height:         view,
+		Proposer:       s.selfID,
+		Tx:             append([]string(nil), tx...),
+		Evidence:       evidence,
+		Reconfig:       reconfig,
+	}
+	s.mu.Lock()
+	s.registerHotStuffBlock(block)
+	s.persistProposal(msg)
+	s.advancePipelineTip(block)
+	s.pipelineProposing = false
+	s.markPhase(view, view, common.PhaseProposalSent)
+	s.mu.Unlock()
+	s.broadcast(msg)
+}
+
+// observePipeline 更新未提交区块数指标（链尖 view 与最高已提交 view 之差）。
+func (s *Service) observePipeline() {
+	s.metrics.uncommittedBlocks.Set(float64(s.nextProposalView - 1 - s.highestCommittedView))
+}
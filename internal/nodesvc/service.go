@@ -7,21 +7,26 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	goleveldb "github.com/syndtr/goleveldb/leveldb"
 
+	"mybft/internal/app"
+	"mybft/internal/batching"
 	"mybft/internal/common"
 	"mybft/internal/crypto"
+	"mybft/internal/election"
+	"mybft/internal/membership"
+	"mybft/internal/mempool"
 	"mybft/internal/redisx"
 	"mybft/internal/storage"
 	leveldbstore "mybft/internal/storage/leveldb"
+	"mybft/internal/tlsx"
+	"mybft/internal/workload"
 )
 
 type heightState struct {
@@ -31,7 +36,17 @@ type heightState struct {
 	Committed      map[int]string
 	Voted          map[int]string
 	Dedup          map[string]struct{}
+	DedupBySender  map[int]int
 	Done           bool
+	// ProposalEvidence 为提案打包的证据，随区块提交标记为已上链。
+	ProposalEvidence []common.Evidence
+	// ProposalReconfig 为提案打包的成员变更交易，随区块提交安排生效。
+	ProposalReconfig []string
+	// FirstSeen 为首次收到该高度消息的时间，用于统计提交时延。
+	FirstSeen time.Time
+	// View 与 Phases 记录该高度所在 view 及各流水线阶段的时间戳，执行后上报 client。
+	View   int
+	Phases map[string]int64
 }
 
 type hotstuffBlock struct {
@@ -41,33 +56,70 @@ type hotstuffBlock struct {
 	Executed  bool
 }
 
-type simulatedTx struct {
-	From   int
-	To     int
-	Amount int
-	Nonce  int
-	Fee    int
-}
-
 type Service struct {
 	mu               sync.Mutex
 	rdb              *redisx.Client
 	selfID           int
 	alg              string
 	cfg              redisx.ClusterConfig
-	th               common.Thresholds
 	height           int
 	view             int
 	leaderMode       bool
-	keys             map[int]string
-	peerAddrs        map[int]string
 	clientURL        string
 	state            map[int]*heightState
 	stores           *leveldbstore.NodeStores
+	app              app.Application
+	mempool          *mempool.Mempool
+	syntheticLoad    bool
+	batchPolicy      *batching.Policy
+	proposedAt       map[int]time.Time
 	hotstuffBlocks   map[string]*hotstuffBlock
 	hotstuffHighQC   common.QuorumCert
 	hotstuffLockedQC common.QuorumCert
 	hotstuffVoted    map[int]string
+	committedHeight  int
+	committedBlockID string
+	committedQC      common.QuorumCert
+	snapshotInterval int
+	metrics          *nodeMetrics
+	netDelay         time.Duration
+	commits          *commitLog
+	executedCommits  map[int]common.CommitEntry
+	speculative      map[string]*speculativeBlock
+	election         election.LeaderElection
+	proposalsSeen    map[int]common.ConsensusMessage
+	votesSeen        map[voteKey]common.ConsensusMessage
+	evidencePending  map[string]common.Evidence
+	// 验证者集合、同伴密钥与地址及待打包的成员变更交易，见 membership.go。
+	members         *membership.Set
+	peers           *peerBook
+	peerSync        sync.Mutex
+	reconfigPending map[string]struct{}
+	catchingUp      bool
+	catchUpBuffered []common.ConsensusMessage
+	// fetchingAncestors 表示正在锁外拉取 HotStuff 提案缺失的祖先区块，同一时间只运行一次。
+	fetchingAncestors bool
+	// HotStuff 流水线状态，仅在 pipelineWindow > 0 时使用（见 pipeline.go）。
+	pipelineWindow       int
+	pipelineProposing    bool
+	pipelineStalledView  int
+	nextProposalView     int
+	highestVotedView     int
+	highestCommittedView int
+	hotstuffTip          string
+	hotstuffOrphans      map[string][]common.ConsensusMessage
+	hotstuffEarlyQCs     map[string]common.QuorumCert
+	// scheme/identity/httpClient 为节点间与上报 client 的传输；identity 仅在 MYBFT_TLS=1 时非空。
+	scheme         string
+	identity       *tlsx.Identity
+	httpClient     *http.Client
+	peerHTTPClient *http.Client
+	// authMode 为 MYBFT_AUTH 指定的消息认证方式；dhKey 为 MAC 模式下本节点的 X25519 私钥。
+	authMode string
+	dhKey    string
+	// admission 与 limiter 为共识消息入口的大小、速率与 view 窗口限制。
+	admission admissionConfig
+	limiter   *peerLimiter
 }
 
 // 初始化节点服务：加载集群配置、密钥与同伴地址。
@@ -84,42 +136,100 @@ func New(rdb *redisx.Client, selfID int, alg string) (*Service, error) {
 	if err != nil {
 		return nil, fmt.Errorf("open node stores: %w", err)
 	}
+	wl, err := workload.ConfigFromEnv()
+	if err != nil {
+		_ = stores.Close()
+		return nil, err
+	}
+	application, err := app.New(os.Getenv("MYBFT_APP"), app.Stores{Ledger: stores.Ledger, KV: stores.KV}, workload.New(wl))
+	if err != nil {
+		_ = stores.Close()
+		return nil, fmt.Errorf("open app: %w", err)
+	}
 	s := &Service{
-		rdb:            rdb,
-		selfID:         selfID,
-		alg:            alg,
-		cfg:            cfg,
-		th:             common.CalcThresholds(cfg.N),
-		height:         1,
-		view:           1,
-		keys:           map[int]string{},
-		peerAddrs:      map[int]string{},
-		state:          map[int]*heightState{},
-		clientURL:      "http://" + cfg.ClientAddr,
-		stores:         stores,
-		hotstuffBlocks: map[string]*hotstuffBlock{},
-		hotstuffVoted:  map[int]string{},
-	}
-	for i := 1; i <= cfg.N; i++ {
-		sk, err := rdb.HGet(fmt.Sprintf("Node:%d", i), "threshold_sk")
-		if err != nil {
-			return nil, fmt.Errorf("load key Node:%d: %w", i, err)
-		}
-		s.keys[i] = sk
-		s.peerAddrs[i] = fmt.Sprintf("127.0.0.1:%d", cfg.BasePort+i)
+		rdb:              rdb,
+		selfID:           selfID,
+		alg:              alg,
+		cfg:              cfg,
+		height:           1,
+		view:             1,
+		peers:            newPeerBook(),
+		reconfigPending:  map[string]struct{}{},
+		state:            map[int]*heightState{},
+		stores:           stores,
+		app:              application,
+		mempool:          mempool.New(mempoolSizeFromEnv(), application),
+		syntheticLoad:    syntheticLoadFromEnv(),
+		batchPolicy:      batching.NewPolicy(batching.ConfigFromEnv()),
+		proposedAt:       map[int]time.Time{},
+		metrics:          newNodeMetrics(),
+		hotstuffBlocks:   map[string]*hotstuffBlock{},
+		hotstuffVoted:    map[int]string{},
+		snapshotInterval: snapshotIntervalFromEnv(),
+		netDelay:         netDelayFromEnv(),
+		executedCommits:  map[int]common.CommitEntry{},
+		speculative:      map[string]*speculativeBlock{},
+		proposalsSeen:    map[int]common.ConsensusMessage{},
+		votesSeen:        map[voteKey]common.ConsensusMessage{},
+		evidencePending:  map[string]common.Evidence{},
+		hotstuffOrphans:  map[string][]common.ConsensusMessage{},
+		hotstuffEarlyQCs: map[string]common.QuorumCert{},
+	}
+	if err := s.initTransport(); err != nil {
+		_ = stores.Close()
+		return nil, err
+	}
+	s.clientURL = s.peerURL(cfg.ClientAddr, "")
+	if s.authMode, err = authModeFromEnv(); err != nil {
+		_ = stores.Close()
+		return nil, err
+	}
+	s.loadMembership()
+	if err := s.syncPeers(); err != nil {
+		_ = stores.Close()
+		return nil, err
 	}
 	s.initHotStuffState()
 	s.loadPersistedPosition()
 	s.loadPersistedHotStuffState()
+	s.commits = newCommitLog(s.committedHeight)
+	if alg == "hotstuff" {
+		s.pipelineWindow = pipelineWindowFromEnv()
+		s.initPipeline()
+	}
+	s.admission = admissionConfigFromEnv(s.pipelineWindow)
+	s.limiter = newPeerLimiter(s.admission.peerRate, s.admission.peerBurst)
+	electionCfg, err := election.ConfigFromEnv()
+	if err != nil {
+		_ = stores.Close()
+		return nil, err
+	}
+	electionCfg.Window = max(electionCfg.Window, minElectionWindow(s.pipelineWindow))
+	if s.election, err = election.New(electionCfg, s.members.Validators); err != nil {
+		_ = stores.Close()
+		return nil, err
+	}
+	s.loadElectionHistory(electionCfg.Window)
+	s.loadPendingEvidence()
 	s.persistPosition()
+	s.metrics.validators.Set(float64(len(s.validators(s.view))))
 	s.leaderMode = s.isLeader(s.view)
 	return s, nil
 }
 
+// 注入的单向网络时延，来自 MYBFT_NET_DELAY_MS，用于实验中模拟广域网。
+func netDelayFromEnv() time.Duration {
+	if raw := os.Getenv("MYBFT_NET_DELAY_MS"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			return time.Duration(v) * time.Millisecond
+		}
+	}
+	return 0
+}
+
 // 判断当前节点在指定 view 下是否为 leader。
 func (s *Service) isLeader(view int) bool {
-	leader := ((view - 1) % s.cfg.N) + 1
-	return s.selfID == leader
+	return s.selfID == s.leaderID(view)
 }
 
 // 若当前为 leader，延迟后触发首轮提案。
@@ -128,24 +238,56 @@ func (s *Service) StartIfLeader() {
 		time.Sleep(600 * time.Millisecond)
 		s.mu.Lock()
 		isL := s.isLeader(s.view)
+		pipelined := s.pipelineWindow > 0
+		if pipelined {
+			s.maybeProposePipelined()
+		}
 		s.mu.Unlock()
-		if isL {
+		if isL && !pipelined {
 			s.proposeCurrentHeight()
 		}
 	}()
 }
 
-// 节点消息入口：解码共识消息并进入流程处理。
+// 节点消息入口：限制请求体大小后解码共识消息，检查发送方、认证后限流，再进入流程处理。
+// 开启 TLS 时发送方证书须与消息的 From 一致，MAC 模式下请求体须带有与 From 成对密钥计算的 MAC。
 func (s *Service) HandleMessage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+	s.limitBody(w, r)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		status := bodyErrorStatus(err)
+		if status == http.StatusRequestEntityTooLarge {
+			s.reject(rejectBodyTooLarge)
+		}
+		w.WriteHeader(status)
+		return
+	}
 	var msg common.ConsensusMessage
-	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+	if err := json.Unmarshal(body, &msg); err != nil {
+		s.reject(rejectMalformed)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if reason := s.precheckSender(msg); reason != "" {
+		s.reject(reason)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.authenticPeer(r, "message", msg.From) || !s.authenticMAC(r, msg.From, body) {
+		s.reject(rejectUnauthenticated)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if !s.limiter.allow(s.limitKey(r, msg.From)) {
+		s.reject(rejectRateLimited)
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	s.metrics.received.Inc(msg.Type)
 	s.process(msg)
 	w.WriteHeader(http.StatusOK)
 }
@@ -154,15 +296,43 @@ func (s *Service) HandleMessage(w http.ResponseWriter, r *http.Request) {
 func (s *Service) process(msg common.ConsensusMessage) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if msg.Height != s.height || msg.View != s.view {
+	if !s.members.Contains(msg.View, msg.From) {
+		s.metrics.nonValidatorDropped.Inc(msg.Type)
+		return
+	}
+	if reason := s.precheckView(msg); reason != "" {
+		s.reject(reason)
+		return
+	}
+	switch {
+	case isProposal(msg.Type) && !s.acceptProposal(msg),
+		isVote(msg.Type) && !s.acceptVote(msg),
+		isQC(msg.Type) && !s.acceptQC(msg):
+		return
+	}
+	if s.pipelineWindow > 0 {
+		if !s.pipelineAccepts(msg) {
+			s.metrics.staleDropped.Inc(msg.Type)
+			if msg.Height > s.nextProposalView+s.pipelineWindow {
+				s.startCatchUp()
+			}
+			return
+		}
+	} else if s.followsProposal(msg) {
+		s.height, s.view = msg.Height, msg.View
+		s.persistPosition()
+	} else if msg.Height != s.height || msg.View != s.view {
+		s.metrics.staleDropped.Inc(msg.Type)
+		if msg.Height > s.height+1 {
+			s.startCatchUp()
+		}
+		s.bufferDuringCatchUp(msg)
 		return
 	}
 	hs := s.getHeightState(msg.Height)
-	dk := common.DedupKey(msg)
-	if _, ok := hs.Dedup[dk]; ok {
+	if !s.admitDedup(hs, msg) {
 		return
 	}
-	hs.Dedup[dk] = struct{}{}
 
 	switch s.alg {
 	case "sbft":
@@ -180,61 +350,65 @@ func (s *Service) process(msg common.ConsensusMessage) {
 func (s *Service) processSBFT(msg common.ConsensusMessage, hs *heightState) {
 	switch msg.Type {
 	case "PrePrepare":
-		if common.Digest(msg.View, msg.Height, msg.Tx) != msg.Digest {
+		if common.BlockDigest(msg.View, msg.Height, msg.Tx, msg.Evidence, msg.Reconfig) != msg.Digest {
 			return
 		}
+		s.markPhase(msg.Height, msg.View, common.PhaseProposalReceived)
 		hs.ProposalDigest = msg.Digest
 		hs.ProposalTx = msg.Tx
+		hs.ProposalEvidence = msg.Evidence
+		hs.ProposalReconfig = msg.Reconfig
 		s.persistProposal(msg)
-		if !s.validateAndExecuteLoad(msg.Tx) {
+		if !s.processProposal(msg.Height, msg.Digest, "", msg.Tx) || !s.canVote(msg.View) {
 			return
 		}
 		m := crypto.VoteMessage("Prepare", msg.View, msg.Height, msg.Digest, s.selfID)
-		sig := crypto.Sign(s.keys[s.selfID], m)
+		sig := crypto.Sign(s.peers.key(s.selfID), m)
 		s.persistVote(msg.View, msg.Digest)
 		share := common.ConsensusMessage{Type: "Prepare", View: msg.View, Height: msg.Height, From: s.selfID, Digest: msg.Digest, SigShare: sig}
 		s.sendTo(s.leaderID(msg.View), share)
+		s.markPhase(msg.Height, msg.View, common.PhaseVoteSent)
 	case "Prepare":
 		if !s.isLeader(msg.View) {
 			return
 		}
-		m := crypto.VoteMessage("Prepare", msg.View, msg.Height, msg.Digest, msg.From)
-		if !crypto.Verify(s.keys[msg.From], m, msg.SigShare) {
-			return
-		}
 		hs.Prepared[msg.From] = msg.SigShare
 		s.persistPrepare(msg)
-		if len(hs.Prepared) >= s.th.T && !hs.Done {
+		if s.sharesPower(hs.Prepared) >= s.thresholds(msg.View).T && !hs.Done && s.certifyShares(hs.Prepared, "Prepare", msg.View, msg.Height, msg.Digest) {
 			shares := make([]string, 0, len(hs.Prepared))
 			for _, sig := range hs.Prepared {
 				shares = append(shares, sig)
 			}
 			proof := crypto.Aggregate(shares)
-			if !crypto.VerifyAggregate(shares, proof) {
+			if !s.verifyAggregate(shares, proof) {
 				return
 			}
-			commitProof := common.ConsensusMessage{Type: "CommitProof", View: msg.View, Height: msg.Height, From: s.selfID, Digest: msg.Digest, QC: proof}
+			commitProof := common.ConsensusMessage{Type: "CommitProof", View: msg.View, Height: msg.Height, From: s.selfID, Digest: msg.Digest, QC: proof, Signers: signerIDs(hs.Prepared)}
+			s.signQC(&commitProof)
 			hs.Done = true
+			s.metrics.qcsFormed.Inc(commitProof.Type)
+			s.markPhase(msg.Height, msg.View, common.PhaseQuorumReached)
 			s.persistQC(commitProof)
-			s.persistCommittedBlock(commitProof.Digest)
+			s.commitProposal(hs, msg.Height, commitProof.Digest, s.selfID, quorumCertFromMessage(commitProof))
 			s.broadcast(commitProof)
-			go s.reportEnd(msg.Height)
-			go s.advanceHeight()
 		}
 	case "CommitProof":
 		if hs.Done || msg.QC == "" {
 			return
 		}
 		hs.Done = true
+		s.markPhase(msg.Height, msg.View, common.PhaseQCReceived)
 		s.persistQC(msg)
-		s.persistCommittedBlock(msg.Digest)
-		go s.reportEnd(msg.Height)
-		go s.advanceHeight()
+		s.commitProposal(hs, msg.Height, msg.Digest, msg.From, quorumCertFromMessage(msg))
 	}
 }
 
 // HotStuff 链式流程：proposal 携带 parent/highQC，三链形成后提交祖先块。
 func (s *Service) processHotStuff(msg common.ConsensusMessage, hs *heightState) {
+	if s.pipelineWindow > 0 {
+		s.processHotStuffPipelined(msg, hs)
+		return
+	}
 	switch msg.Type {
 	case "HSProposal":
 		block := common.Block{
@@ -242,26 +416,31 @@ func (s *Service) processHotStuff(msg common.ConsensusMessage, hs *heightState)
 			ParentBlockID:  msg.ParentID,
 			JustifyBlockID: msg.JustifyID,
 			JustifyView:    msg.JustifyView,
+			JustifyQC:      msg.JustifyQC,
+			JustifySigners: msg.JustifySigners,
 			Digest:         msg.Digest,
 			View:           msg.View,
 			Height:         msg.Height,
 			Proposer:       msg.From,
 			Tx:             append([]string(nil), msg.Tx...),
+			Evidence:       msg.Evidence,
+			Reconfig:       msg.Reconfig,
 		}
 		if !s.validateHotStuffProposal(block, msg) {
 			return
 		}
+		s.markPhase(msg.Height, msg.View, common.PhaseProposalReceived)
 		s.registerHotStuffBlock(block)
 		s.persistProposal(msg)
 		s.updateLockedQCFromProposal(block, msg)
-		if !s.validateAndExecuteLoad(msg.Tx) {
+		if !s.processProposal(block.Height, block.BlockID, block.ParentBlockID, msg.Tx) || !s.canVote(msg.View) {
 			return
 		}
 		if votedBlock, ok := s.hotstuffVoted[msg.View]; ok && votedBlock != block.BlockID {
 			return
 		}
 		m := crypto.VoteMessage("HSVote", msg.View, msg.Height, block.BlockID, s.selfID)
-		sig := crypto.Sign(s.keys[s.selfID], m)
+		sig := crypto.Sign(s.peers.key(s.selfID), m)
 		s.hotstuffVoted[msg.View] = block.BlockID
 		s.persistVote(msg.View, block.BlockID)
 		vote := common.ConsensusMessage{
@@ -274,17 +453,14 @@ func (s *Service) processHotStuff(msg common.ConsensusMessage, hs *heightState)
 			SigShare: sig,
 		}
 		s.sendTo(s.leaderID(msg.View), vote)
+		s.markPhase(msg.Height, msg.View, common.PhaseVoteSent)
 	case "HSVote":
 		if !s.isLeader(msg.View) {
 			return
 		}
 		blockID := s.messageBlockID(msg)
-		m := crypto.VoteMessage("HSVote", msg.View, msg.Height, blockID, msg.From)
-		if !crypto.Verify(s.keys[msg.From], m, msg.SigShare) {
-			return
-		}
 		hs.Voted[msg.From] = msg.SigShare
-		if len(hs.Voted) >= s.th.T && !hs.Done {
+		if s.sharesPower(hs.Voted) >= s.thresholds(msg.View).T && !hs.Done && s.certifyShares(hs.Voted, "HSVote", msg.View, msg.Height, blockID) {
 			shares := make([]string, 0, len(hs.Voted))
 			for _, sig := range hs.Voted {
 				shares = append(shares, sig)
@@ -298,8 +474,12 @@ func (s *Service) processHotStuff(msg common.ConsensusMessage, hs *heightState)
 				BlockID: blockID,
 				Digest:  blockID,
 				QC:      qc,
+				Signers: signerIDs(hs.Voted),
 			}
+			s.signQC(&qcMsg)
 			hs.Done = true
+			s.metrics.qcsFormed.Inc(qcMsg.Type)
+			s.markPhase(msg.Height, msg.View, common.PhaseQuorumReached)
 			s.persistQC(qcMsg)
 			s.updateHotStuffHighQC(qcMsg)
 			s.persistHighQC(qcMsg)
@@ -315,6 +495,7 @@ func (s *Service) processHotStuff(msg common.ConsensusMessage, hs *heightState)
 			return
 		}
 		hs.Done = true
+		s.markPhase(msg.Height, msg.View, common.PhaseQCReceived)
 		s.persistQC(msg)
 		s.updateHotStuffHighQC(msg)
 		s.persistHighQC(msg)
@@ -327,120 +508,150 @@ func (s *Service) processHotStuff(msg common.ConsensusMessage, hs *heightState)
 func (s *Service) processOneVote(msg common.ConsensusMessage, hs *heightState, proposalType, voteType, qcType string) {
 	switch msg.Type {
 	case proposalType:
-		if common.Digest(msg.View, msg.Height, msg.Tx) != msg.Digest {
+		if common.BlockDigest(msg.View, msg.Height, msg.Tx, msg.Evidence, msg.Reconfig) != msg.Digest {
 			return
 		}
+		s.markPhase(msg.Height, msg.View, common.PhaseProposalReceived)
 		hs.ProposalDigest = msg.Digest
 		hs.ProposalTx = msg.Tx
+		hs.ProposalEvidence = msg.Evidence
+		hs.ProposalReconfig = msg.Reconfig
 		s.persistProposal(msg)
-		if !s.validateAndExecuteLoad(msg.Tx) {
+		if !s.processProposal(msg.Height, msg.Digest, "", msg.Tx) || !s.canVote(msg.View) {
 			return
 		}
 		m := crypto.VoteMessage(voteType, msg.View, msg.Height, msg.Digest, s.selfID)
-		sig := crypto.Sign(s.keys[s.selfID], m)
+		sig := crypto.Sign(s.peers.key(s.selfID), m)
 		s.persistVote(msg.View, msg.Digest)
 		vote := common.ConsensusMessage{Type: voteType, View: msg.View, Height: msg.Height, From: s.selfID, Digest: msg.Digest, SigShare: sig}
 		s.sendTo(s.leaderID(msg.View), vote)
+		s.markPhase(msg.Height, msg.View, common.PhaseVoteSent)
 	case voteType:
 		if !s.isLeader(msg.View) {
 			return
 		}
-		m := crypto.VoteMessage(voteType, msg.View, msg.Height, msg.Digest, msg.From)
-		if !crypto.Verify(s.keys[msg.From], m, msg.SigShare) {
-			return
-		}
 		hs.Voted[msg.From] = msg.SigShare
-		if len(hs.Voted) >= s.th.T && !hs.Done {
+		if s.sharesPower(hs.Voted) >= s.thresholds(msg.View).T && !hs.Done && s.certifyShares(hs.Voted, voteType, msg.View, msg.Height, msg.Digest) {
 			shares := make([]string, 0, len(hs.Voted))
 			for _, sig := range hs.Voted {
 				shares = append(shares, sig)
 			}
 			qc := crypto.Aggregate(shares)
-			qcMsg := common.ConsensusMessage{Type: qcType, View: msg.View, Height: msg.Height, From: s.selfID, Digest: msg.Digest, QC: qc}
+			qcMsg := common.ConsensusMessage{Type: qcType, View: msg.View, Height: msg.Height, From: s.selfID, Digest: msg.Digest, QC: qc, Signers: signerIDs(hs.Voted)}
+			s.signQC(&qcMsg)
 			hs.Done = true
+			s.metrics.qcsFormed.Inc(qcMsg.Type)
+			s.markPhase(msg.Height, msg.View, common.PhaseQuorumReached)
 			s.persistQC(qcMsg)
 			s.persistHighQC(qcMsg)
-			s.persistCommittedBlock(qcMsg.Digest)
+			s.commitProposal(hs, msg.Height, qcMsg.Digest, s.selfID, quorumCertFromMessage(qcMsg))
 			s.broadcast(qcMsg)
-			go s.reportEnd(msg.Height)
-			go s.advanceHeight()
 		}
 	case qcType:
 		if hs.Done {
 			return
 		}
 		hs.Done = true
+		s.markPhase(msg.Height, msg.View, common.PhaseQCReceived)
 		s.persistQC(msg)
 		s.persistHighQC(msg)
-		s.persistCommittedBlock(msg.Digest)
-		go s.reportEnd(msg.Height)
-		go s.advanceHeight()
+		s.commitProposal(hs, msg.Height, msg.Digest, msg.From, quorumCertFromMessage(msg))
 	}
 }
 
-// 计算给定 view 的 leader ID。
+// 计算给定 view 的 leader ID，由 MYBFT_LEADER_POLICY 选定的选主策略决定。
 func (s *Service) leaderID(view int) int {
-	return ((view - 1) % s.cfg.N) + 1
+	return s.election.Leader(view)
 }
 
 // 生成当前高度的提案并广播。
 func (s *Service) proposeCurrentHeight() {
+	limits := s.batchPolicy.Limits()
+	s.waitForBatch(limits)
 	s.mu.Lock()
 	height := s.height
 	view := s.view
 	highQC := s.hotstuffHighQC
+	parentID := ""
+	if s.alg == "hotstuff" {
+		parentID = highQC.BlockID
+	}
+	pending := s.pendingPayloads(parentID)
+	evidence := s.evidenceForProposal(parentID)
+	reconfig := s.reconfigForProposal(parentID)
 	s.mu.Unlock()
-	tx := generateTx(height)
-	digest := common.Digest(view, height, tx)
-	s.callStart(height, view, len(tx))
+	tx := s.prepareProposal(height, pending, limits)
+	digest := common.BlockDigest(view, height, tx, evidence, reconfig)
+	s.mu.Lock()
+	s.proposedAt[height] = time.Now()
+	s.mu.Unlock()
+	s.callStart(height, view, s.batchPolicy.Info(limits, tx))
 	var msg common.ConsensusMessage
 	switch s.alg {
 	case "sbft":
-		msg = common.ConsensusMessage{Type: "PrePrepare", View: view, Height: height, From: s.selfID, Digest: digest, Tx: tx}
+		msg = common.ConsensusMessage{Type: "PrePrepare", View: view, Height: height, From: s.selfID, Digest: digest, Tx: tx, Evidence: evidence, Reconfig: reconfig}
 	case "hotstuff":
 		msg = common.ConsensusMessage{
-			Type:        "HSProposal",
-			View:        view,
-			Height:      height,
-			From:        s.selfID,
-			BlockID:     digest,
-			ParentID:    highQC.BlockID,
-			JustifyID:   highQC.BlockID,
-			JustifyQC:   highQC.QC,
-			JustifyView: highQC.View,
-			Digest:      digest,
-			Tx:          tx,
+			Type:           "HSProposal",
+			View:           view,
+			Height:         height,
+			From:           s.selfID,
+			BlockID:        digest,
+			ParentID:       highQC.BlockID,
+			JustifyID:      highQC.BlockID,
+			JustifyQC:      highQC.QC,
+			JustifyView:    highQC.View,
+			JustifySigners: highQC.Signers,
+			Digest:         digest,
+			Tx:             tx,
+			Evidence:       evidence,
+			Reconfig:       reconfig,
 		}
+	case "fast-hotstuff":
+		msg = common.ConsensusMessage{Type: "FHSProposal", View: view, Height: height, From: s.selfID, Digest: digest, Tx: tx, Evidence: evidence, Reconfig: reconfig}
+	case "hpbft":
+		msg = common.ConsensusMessage{Type: "HPProposal", View: view, Height: height, From: s.selfID, Digest: digest, Tx: tx, Evidence: evidence, Reconfig: reconfig}
+	}
+	s.signProposal(&msg)
+	s.mu.Lock()
+	if s.alg == "hotstuff" {
+		// 签名之后再登记与落盘：/block 返回的记录须带提案者签名，拉取区块的节点据此校验。
 		s.registerHotStuffBlock(common.Block{
 			BlockID:        digest,
 			ParentBlockID:  highQC.BlockID,
 			JustifyBlockID: highQC.BlockID,
 			JustifyView:    highQC.View,
+			JustifyQC:      highQC.QC,
+			JustifySigners: highQC.Signers,
 			Digest:         digest,
 			View:           view,
 			Height:         height,
 			Proposer:       s.selfID,
 			Tx:             append([]string(nil), tx...),
+			Evidence:       evidence,
+			Reconfig:       reconfig,
 		})
 		s.persistProposal(msg)
-	case "fast-hotstuff":
-		msg = common.ConsensusMessage{Type: "FHSProposal", View: view, Height: height, From: s.selfID, Digest: digest, Tx: tx}
-	case "hpbft":
-		msg = common.ConsensusMessage{Type: "HPProposal", View: view, Height: height, From: s.selfID, Digest: digest, Tx: tx}
 	}
+	s.markPhase(height, view, common.PhaseProposalSent)
+	s.mu.Unlock()
 	s.broadcast(msg)
 }
 
 // 向 client 上报 /start（记录延迟起点）。
-func (s *Service) callStart(height, view, batch int) {
-	body, _ := json.Marshal(common.StartRequest{Height: height, View: view, Start: time.Now().UnixNano(), Batch: batch})
-	_, _ = http.Post(s.clientURL+"/start", "application/json", bytes.NewReader(body))
+func (s *Service) callStart(height, view int, batch common.BatchInfo) {
+	body, _ := json.Marshal(common.StartRequest{Height: height, View: view, Start: time.Now().UnixNano(), Batch: batch, Alg: s.alg})
+	_, _ = s.httpClient.Post(s.clientURL+"/start", "application/json", bytes.NewReader(body))
 }
 
-// 向 client 上报 /end（记录延迟终点）。
+// 向 client 上报 /end（记录延迟终点），附带该高度的状态根供 client 比对分叉。
 func (s *Service) reportEnd(height int) {
-	body, _ := json.Marshal(common.EndRequest{Height: height, From: s.selfID, End: time.Now().UnixNano(), View: s.view})
-	_, _ = http.Post(s.clientURL+"/end", "application/json", bytes.NewReader(body))
+	req := common.EndRequest{Height: height, From: s.selfID, End: time.Now().UnixNano(), View: s.view}
+	if root, err := s.app.StateRoot(height); err == nil {
+		req.StateRoot = root.Root
+	}
+	body, _ := json.Marshal(req)
+	_, _ = s.httpClient.Post(s.clientURL+"/end", "application/json", bytes.NewReader(body))
 }
 
 // 推进高度与 view，并在成为 leader 时触发下一轮提案。
@@ -463,16 +674,16 @@ func (s *Service) advanceHeight() {
 	}
 }
 
-// 向所有节点广播共识消息。
+// 向 msg.View 下的验证者及已安排加入的节点广播共识消息。
 func (s *Service) broadcast(msg common.ConsensusMessage) {
-	for i := 1; i <= s.cfg.N; i++ {
-		s.sendTo(i, msg)
+	for _, id := range s.audience(msg.View) {
+		s.sendTo(id, msg)
 	}
 }
 
 // 发送消息到指定节点，按算法路由到对应 HTTP 路径。
 func (s *Service) sendTo(id int, msg common.ConsensusMessage) {
-	addr := s.peerAddrs[id]
+	addr := s.peers.addr(id)
 	var prefix string
 	switch s.alg {
 	case "sbft":
@@ -485,8 +696,19 @@ func (s *Service) sendTo(id int, msg common.ConsensusMessage) {
 		prefix = "/hpbft/message"
 	}
 	b, _ := json.Marshal(msg)
+	s.metrics.sent.Inc(msg.Type)
+	s.metrics.sentBytes.Add(float64(len(b)), msg.Type)
 	go func() {
-		resp, err := http.Post("http://"+addr+prefix, "application/json", bytes.NewReader(b))
+		if s.netDelay > 0 {
+			time.Sleep(s.netDelay)
+		}
+		req, err := http.NewRequest(http.MethodPost, s.peerURL(addr, prefix), bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		s.setMAC(req, id, b)
+		resp, err := s.httpClient.Do(req)
 		if err != nil {
 			return
 		}
@@ -499,7 +721,7 @@ func (s *Service) sendTo(id int, msg common.ConsensusMessage) {
 func (s *Service) getHeightState(height int) *heightState {
 	hs, ok := s.state[height]
 	if !ok {
-		hs = &heightState{Prepared: map[int]string{}, Committed: map[int]string{}, Voted: map[int]string{}, Dedup: map[string]struct{}{}}
+		hs = &heightState{Prepared: map[int]string{}, Committed: map[int]string{}, Voted: map[int]string{}, Dedup: map[string]struct{}{}, DedupBySender: map[int]int{}, FirstSeen: time.Now()}
 		s.state[height] = hs
 	}
 	return hs
@@ -523,12 +745,12 @@ func (s *Service) loadPersistedHotStuffState() {
 		return
 	}
 	if qc, err := s.stores.State.LoadHighQC(); err == nil && qc.BlockID != "" {
-		s.hotstuffHighQC = common.QuorumCert{Type: qc.QCType, BlockID: qc.BlockID, View: qc.View, Height: qc.Height, QC: qc.QC}
+		s.hotstuffHighQC = quorumCertFromRecord(qc)
 	} else if err != nil && !errors.Is(err, goleveldb.ErrNotFound) {
 		log.Printf("node=%d load high qc: %v", s.selfID, err)
 	}
 	if qc, err := s.stores.State.LoadLockedQC(); err == nil && qc.BlockID != "" {
-		s.hotstuffLockedQC = common.QuorumCert{Type: qc.QCType, BlockID: qc.BlockID, View: qc.View, Height: qc.Height, QC: qc.QC}
+		s.hotstuffLockedQC = quorumCertFromRecord(qc)
 	} else if err != nil && !errors.Is(err, goleveldb.ErrNotFound) {
 		log.Printf("node=%d load locked qc: %v", s.selfID, err)
 	}
@@ -556,22 +778,33 @@ func (s *Service) registerHotStuffBlock(block common.Block) {
 		if existing.Block.JustifyView == 0 && block.JustifyView != 0 {
 			existing.Block.JustifyView = block.JustifyView
 		}
+		if existing.Block.JustifyQC == "" && block.JustifyQC != "" {
+			existing.Block.JustifyQC = block.JustifyQC
+			existing.Block.JustifySigners = block.JustifySigners
+		}
 		if len(existing.Block.Tx) == 0 && len(block.Tx) > 0 {
 			existing.Block.Tx = append([]string(nil), block.Tx...)
 		}
+		if len(existing.Block.Evidence) == 0 && len(block.Evidence) > 0 {
+			existing.Block.Evidence = block.Evidence
+		}
+		if len(existing.Block.Reconfig) == 0 && len(block.Reconfig) > 0 {
+			existing.Block.Reconfig = block.Reconfig
+		}
 		return
 	}
 	s.hotstuffBlocks[block.BlockID] = &hotstuffBlock{Block: block}
 }
 
 func (s *Service) validateHotStuffProposal(block common.Block, msg common.ConsensusMessage) bool {
-	if common.Digest(msg.View, msg.Height, msg.Tx) != msg.Digest {
+	if common.BlockDigest(msg.View, msg.Height, msg.Tx, msg.Evidence, msg.Reconfig) != msg.Digest {
 		return false
 	}
 	if block.BlockID == "" || block.ParentBlockID == "" {
 		return false
 	}
 	if _, ok := s.hotstuffBlocks[block.ParentBlockID]; !ok {
+		s.startFetchAncestors(msg)
 		return false
 	}
 	if block.JustifyBlockID != "" && block.JustifyBlockID != block.ParentBlockID {
@@ -607,12 +840,20 @@ func (s *Service) updateLockedQCFromProposal(block common.Block, msg common.Cons
 	if msg.JustifyQC == "" || msg.JustifyView < s.hotstuffLockedQC.View {
 		return
 	}
+	justifyID, height := block.JustifyBlockID, block.Height-1
+	if justifyID == "" {
+		justifyID = block.ParentBlockID
+	}
+	if justify, ok := s.hotstuffBlocks[justifyID]; ok {
+		height = justify.Block.Height
+	}
 	qc := common.QuorumCert{
 		Type:    "HSQC",
-		BlockID: block.ParentBlockID,
+		BlockID: justifyID,
 		View:    msg.JustifyView,
-		Height:  block.Height - 1,
+		Height:  height,
 		QC:      msg.JustifyQC,
+		Signers: msg.JustifySigners,
 	}
 	s.hotstuffLockedQC = qc
 	s.persistLockedQC(qc)
@@ -620,13 +861,7 @@ func (s *Service) updateLockedQCFromProposal(block common.Block, msg common.Cons
 
 func (s *Service) updateHotStuffHighQC(msg common.ConsensusMessage) {
 	blockID := s.messageBlockID(msg)
-	qc := common.QuorumCert{
-		Type:    msg.Type,
-		BlockID: blockID,
-		View:    msg.View,
-		Height:  msg.Height,
-		QC:      msg.QC,
-	}
+	qc := quorumCertFromMessage(msg)
 	if qc.View <= s.hotstuffHighQC.View {
 		return
 	}
@@ -646,19 +881,62 @@ func (s *Service) commitHotStuffAncestor(blockID string) {
 		return
 	}
 	grandParent, ok := s.hotstuffBlocks[parent.Block.ParentBlockID]
-	if !ok || grandParent.Block.BlockID == "genesis" {
+	if !ok || grandParent.Committed {
 		return
 	}
-	if grandParent.Committed {
+	s.commitHotStuffUpTo(grandParent, parent)
+}
+
+// 记录已提交的高度、区块与对应 QC，并按间隔生成状态快照。
+func (s *Service) markCommitted(height int, blockID string, qc common.QuorumCert) {
+	if height > s.committedHeight {
+		s.committedHeight = height
+		s.committedBlockID = blockID
+		s.committedQC = qc
+	}
+	justify := s.blockJustify(blockID)
+	s.observeCommit(height)
+	s.observeLeader(height, qc, justify)
+	s.pruneSeen(height)
+	s.publishCommit(height, qc, justify)
+	s.persistCommittedBlock(blockID, height)
+	s.maybeSnapshot()
+}
+
+// HandleStateRoot 返回指定高度（缺省为最新）提交后的账本状态根。
+func (s *Service) HandleStateRoot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	grandParent.Committed = true
-	if !grandParent.Executed {
-		_ = s.validateAndExecuteLoad(grandParent.Block.Tx)
-		grandParent.Executed = true
+	info := s.app.Info()
+	root := storage.StateRootRecord{Height: info.LastHeight, Root: info.AppHash}
+	if raw := r.URL.Query().Get("height"); raw != "" {
+		height, err := strconv.Atoi(raw)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		root, err = s.app.StateRoot(height)
+		if errors.Is(err, goleveldb.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(root)
+}
+
+func quorumCertFromMessage(msg common.ConsensusMessage) common.QuorumCert {
+	blockID := msg.BlockID
+	if blockID == "" {
+		blockID = msg.Digest
 	}
-	s.persistCommittedBlock(grandParent.Block.BlockID)
-	go s.reportEnd(grandParent.Block.Height)
+	return common.QuorumCert{Type: msg.Type, BlockID: blockID, View: msg.View, Height: msg.Height, QC: msg.QC, Signers: msg.Signers}
 }
 
 func (s *Service) loadPersistedPosition() {
@@ -675,9 +953,25 @@ func (s *Service) loadPersistedPosition() {
 	} else if err != nil && !errors.Is(err, goleveldb.ErrNotFound) {
 		log.Printf("node=%d load current height: %v", s.selfID, err)
 	}
+	if height, err := s.stores.State.LoadLastCommittedHeight(); err == nil && height > 0 {
+		s.committedHeight = height
+	} else if err != nil && !errors.Is(err, goleveldb.ErrNotFound) {
+		log.Printf("node=%d load committed height: %v", s.selfID, err)
+	}
+	if blockID, err := s.stores.State.LoadLastCommittedBlock(); err == nil {
+		s.committedBlockID = blockID
+	} else if !errors.Is(err, goleveldb.ErrNotFound) {
+		log.Printf("node=%d load committed block: %v", s.selfID, err)
+	}
+	if s.committedBlockID != "" {
+		if qc, err := s.stores.Blocks.GetQC(s.committedBlockID); err == nil {
+			s.committedQC = quorumCertFromRecord(qc)
+		}
+	}
 }
 
 func (s *Service) persistPosition() {
+	s.observePosition()
 	if s.stores == nil {
 		return
 	}
@@ -694,16 +988,23 @@ func (s *Service) persistProposal(msg common.ConsensusMessage) {
 		return
 	}
 	record := storage.BlockRecord{
-		BlockID:       s.messageBlockID(msg),
-		ParentBlockID: msg.ParentID,
-		Alg:           s.alg,
-		MessageType:   msg.Type,
-		Digest:        msg.Digest,
-		View:          msg.View,
-		Height:        msg.Height,
-		From:          msg.From,
-		Tx:            append([]string(nil), msg.Tx...),
-		CreatedAt:     time.Now().UnixNano(),
+		BlockID:        s.messageBlockID(msg),
+		ParentBlockID:  msg.ParentID,
+		JustifyBlockID: msg.JustifyID,
+		JustifyView:    msg.JustifyView,
+		JustifyQC:      msg.JustifyQC,
+		JustifySigners: msg.JustifySigners,
+		Alg:            s.alg,
+		MessageType:    msg.Type,
+		Digest:         msg.Digest,
+		View:           msg.View,
+		Height:         msg.Height,
+		From:           msg.From,
+		Tx:             append([]string(nil), msg.Tx...),
+		Evidence:       evidenceRecords(msg.Evidence),
+		Reconfig:       msg.Reconfig,
+		SigFull:        msg.SigFull,
+		CreatedAt:      time.Now().UnixNano(),
 	}
 	if err := s.stores.Blocks.SaveBlock(record); err != nil {
 		log.Printf("node=%d save block %s: %v", s.selfID, record.BlockID, err)
@@ -750,6 +1051,7 @@ func (s *Service) persistQC(msg common.ConsensusMessage) {
 		Height:    msg.Height,
 		From:      msg.From,
 		QC:        msg.QC,
+		Signers:   msg.Signers,
 		CreatedAt: time.Now().UnixNano(),
 	}
 	if err := s.stores.Blocks.SaveQC(record); err != nil {
@@ -775,6 +1077,7 @@ func (s *Service) persistHighQC(msg common.ConsensusMessage) {
 		Height:    msg.Height,
 		From:      msg.From,
 		QC:        msg.QC,
+		Signers:   msg.Signers,
 		CreatedAt: time.Now().UnixNano(),
 	}
 	if err := s.stores.State.SaveHighQC(record); err != nil {
@@ -795,6 +1098,7 @@ func (s *Service) persistLockedQC(qc common.QuorumCert) {
 		Height:    qc.Height,
 		From:      s.selfID,
 		QC:        qc.QC,
+		Signers:   qc.Signers,
 		CreatedAt: time.Now().UnixNano(),
 	}
 	if err := s.stores.State.SaveLockedQC(record); err != nil {
@@ -802,134 +1106,157 @@ func (s *Service) persistLockedQC(qc common.QuorumCert) {
 	}
 }
 
-func (s *Service) persistCommittedBlock(blockID string) {
+func (s *Service) persistCommittedBlock(blockID string, height int) {
 	if s.stores == nil {
 		return
 	}
 	if err := s.stores.State.SaveLastCommittedBlock(blockID); err != nil {
 		log.Printf("node=%d save committed block %s: %v", s.selfID, blockID, err)
 	}
+	if err := s.stores.State.SaveLastCommittedHeight(height); err != nil {
+		log.Printf("node=%d save committed height=%d: %v", s.selfID, height, err)
+	}
 }
 
-// validateAndExecuteLoad 对批量交易做字段、nonce、余额检查，并执行状态变更模拟。
-func (s *Service) validateAndExecuteLoad(tx []string) bool {
-	const accountCount = 1000
-	const initialBalance = 100000
-
-	balances := make([]int, accountCount+1)
-	nextNonce := make([]int, accountCount+1)
-	for i := 1; i <= accountCount; i++ {
-		balances[i] = initialBalance
+// 收集 parent 链上尚未提交区块的交易（由旧到新），作为应用的待提交状态。
+func (s *Service) pendingPayloads(parentID string) [][]string {
+	var pending [][]string
+	for cur := parentID; cur != ""; {
+		block, ok := s.hotstuffBlocks[cur]
+		if !ok || block.Committed {
+			break
+		}
+		pending = append(pending, block.Block.Tx)
+		cur = block.Block.ParentBlockID
+	}
+	for i, j := 0, len(pending)-1; i < j; i, j = i+1, j-1 {
+		pending[i], pending[j] = pending[j], pending[i]
 	}
-	seen := make(map[string]struct{}, len(tx))
-	totalFees := 0
+	return pending
+}
 
-	for _, line := range tx {
-		stx, ok := parseSimulatedTx(line)
-		if !ok {
-			return false
+// 构造提案交易：优先从交易池取至多 MaxTxs 笔；交易池为空且允许模拟负载时由应用生成，
+// 交给 PrepareProposal 过滤后再按 MaxBytes 截断。
+func (s *Service) prepareProposal(height int, pending [][]string, limits batching.Limits) []string {
+	candidates := s.mempool.Reap(limits.MaxTxs, pendingTxIDs(pending))
+	if len(candidates) == 0 && s.syntheticLoad {
+		if gen, ok := s.app.(app.LoadGenerator); ok {
+			candidates = gen.GenerateTxs(height, limits.MaxTxs, pending)
 		}
-		if stx.From < 1 || stx.From > accountCount || stx.To < 1 || stx.To > accountCount || stx.From == stx.To {
-			return false
-		}
-		if stx.Amount <= 0 || stx.Fee < 0 || stx.Nonce <= 0 {
-			return false
-		}
-		dedupKey := fmt.Sprintf("%d:%d", stx.From, stx.Nonce)
-		if _, exists := seen[dedupKey]; exists {
-			return false
-		}
-		seen[dedupKey] = struct{}{}
-		if stx.Nonce != nextNonce[stx.From]+1 {
-			return false
+	}
+	tx := s.app.PrepareProposal(app.ProposalRequest{Height: height, Txs: candidates, Pending: pending, MaxTxs: limits.MaxTxs})
+	return batching.Truncate(tx, limits.MaxTxs, limits.MaxBytes)
+}
+
+// 在 MaxWait 内等待交易池攒够 MaxTxs 笔，超时则按已有交易出块；交易入池时被唤醒重新检查。
+func (s *Service) waitForBatch(limits batching.Limits) {
+	if limits.MaxWait <= 0 {
+		return
+	}
+	timer := time.NewTimer(limits.MaxWait)
+	defer timer.Stop()
+	for {
+		arrived := s.mempool.Arrivals()
+		if s.mempool.Size() >= limits.MaxTxs {
+			return
 		}
-		cost := stx.Amount + stx.Fee
-		if balances[stx.From] < cost {
-			return false
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return
 		}
-
-		balances[stx.From] -= cost
-		balances[stx.To] += stx.Amount
-		nextNonce[stx.From] = stx.Nonce
-		totalFees += stx.Fee
 	}
+}
 
-	_ = totalFees
-	return true
+// 执行并提交本高度提案，调用方需持有 s.mu。提案晚于提交证明到达时先不提交，
+// 在锁外向提交证明的发送方拉取区块内容后再提交。
+func (s *Service) commitProposal(hs *heightState, height int, digest string, from int, qc common.QuorumCert) {
+	if hs.ProposalDigest != digest {
+		go s.fetchPayloadAndCommit(height, digest, from, qc)
+		return
+	}
+	s.executeCommitted(common.Block{BlockID: digest, Digest: digest, Height: height, Tx: hs.ProposalTx, Evidence: hs.ProposalEvidence, Reconfig: hs.ProposalReconfig})
+	s.markCommitted(height, digest, qc)
+	go s.reportEnd(height)
+	go s.advanceHeight()
 }
 
-func parseSimulatedTx(line string) (simulatedTx, bool) {
-	parts := strings.Fields(line)
-	if len(parts) != 5 {
-		return simulatedTx{}, false
+// fetchPayloadAndCommit 向 from 拉取已有提交证明的区块内容，取回后若该高度仍未提交则执行并提交。
+// 拉取失败时不推进提交指针，缺失的区块由追赶补齐。
+func (s *Service) fetchPayloadAndCommit(height int, digest string, from int, qc common.QuorumCert) {
+	block, err := s.fetchBlock(from, digest)
+	if err == nil && block.Height != height {
+		err = fmt.Errorf("block %s has height %d, want %d", digest, block.Height, height)
 	}
-	from, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return simulatedTx{}, false
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if height <= s.committedHeight {
+		return
 	}
-	to, err := strconv.Atoi(parts[1])
 	if err != nil {
-		return simulatedTx{}, false
+		log.Printf("node=%d event=commit_without_payload height=%d digest=%s err=%v", s.selfID, height, digest, err)
+		s.startCatchUp()
+		return
 	}
-	amount, err := strconv.Atoi(parts[2])
-	if err != nil {
-		return simulatedTx{}, false
+	hs := s.getHeightState(height)
+	hs.ProposalDigest = digest
+	hs.ProposalTx = block.Tx
+	hs.ProposalEvidence = block.Evidence
+	hs.ProposalReconfig = block.Reconfig
+	s.commitProposal(hs, height, digest, from, qc)
+}
+
+// HandleBlock 按 ID 返回本地区块库中的提案记录。
+func (s *Service) HandleBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
-	nonce, err := strconv.Atoi(parts[3])
-	if err != nil {
-		return simulatedTx{}, false
+	record, err := s.stores.Blocks.GetBlock(r.URL.Query().Get("id"))
+	if errors.Is(err, goleveldb.ErrNotFound) {
+		http.NotFound(w, r)
+		return
 	}
-	fee, err := strconv.Atoi(parts[4])
 	if err != nil {
-		return simulatedTx{}, false
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	return simulatedTx{From: from, To: to, Amount: amount, Nonce: nonce, Fee: fee}, true
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(record)
 }
 
-// 生成模拟交易负载：每个高度 100*height 条，包含 amount/nonce/fee 以支持更复杂校验。
-func generateTx(height int) []string {
-	const accountCount = 1000
-	const initialBalance = 100000
-
-	sz := height * 100
-	tx := make([]string, 0, sz)
-	balances := make([]int, accountCount+1)
-	nextNonce := make([]int, accountCount+1)
-	for i := 1; i <= accountCount; i++ {
-		balances[i] = initialBalance
+// 把已提交区块交给应用定稿并 Commit，记录该高度的 app hash 与区块打包的证据，并安排其中的成员变更。
+func (s *Service) executeCommitted(block common.Block) {
+	height, blockID, tx := block.Height, block.BlockID, block.Tx
+	// 证据与成员变更属于共识状态，先于应用执行生效，应用出错也不影响各节点得出相同的集合。
+	s.commitEvidence(height, block.Evidence)
+	s.applyReconfig(height, block.Reconfig)
+	result, err := s.finalizeBlock(height, blockID, tx)
+	if err != nil {
+		log.Printf("node=%d finalize block height=%d: %v", s.selfID, height, err)
+		return
 	}
-	for i := 0; i < sz; i++ {
-		from := 1 + rand.Intn(accountCount)
-		retries := 0
-		for balances[from] < 2 && retries < accountCount {
-			from = 1 + rand.Intn(accountCount)
-			retries++
-		}
-		to := 1 + rand.Intn(accountCount)
-		for to == from {
-			to = 1 + rand.Intn(accountCount)
-		}
-		maxSpend := balances[from]
-		if maxSpend <= 1 {
-			maxSpend = 2
-		}
-		fee := rand.Intn(3) + 1
-		maxAmount := maxSpend - fee
-		if maxAmount < 1 {
-			maxAmount = 1
-			fee = 0
-		}
-		if maxAmount > 50 {
-			maxAmount = 50
+	root, err := s.app.Commit()
+	if err != nil {
+		log.Printf("node=%d commit app height=%d: %v", s.selfID, height, err)
+		return
+	}
+	s.settleSpeculative(height, blockID)
+	s.mempool.Update(tx)
+	s.stageCommit(height, blockID, tx, result)
+	if at, ok := s.proposedAt[height]; ok {
+		s.batchPolicy.Observe(time.Since(at))
+	}
+	for h := range s.proposedAt {
+		if h <= height {
+			delete(s.proposedAt, h)
 		}
-		amount := rand.Intn(maxAmount) + 1
-		nonce := nextNonce[from] + 1
-		balances[from] -= amount + fee
-		balances[to] += amount
-		nextNonce[from] = nonce
-		tx = append(tx, fmt.Sprintf("%d %d %d %d %d", from, to, amount, nonce, fee))
 	}
-	return tx
+	log.Printf("node=%d event=block_executed height=%d applied=%d skipped=%d root=%s", s.selfID, height, result.Applied, result.Skipped, root.Root)
+	if hs, ok := s.state[height]; ok {
+		s.markPhase(height, hs.View, common.PhaseExecuted)
+		s.reportPhases(height)
+	}
 }
 
 // 构建节点 HTTP 路由，只启用当前算法对应的消息入口。
@@ -948,17 +1275,43 @@ func BuildMux(enabled string, handler http.HandlerFunc) *http.ServeMux {
 	return mux
 }
 
+type Options struct {
+	// Bootstrap 为 true 时，启动共识前先从同伴安装最新的已验证快照。
+	Bootstrap bool
+}
+
 // 启动节点 HTTP 服务并进入共识流程。
-func Run(selfID int, alg string) error {
+func Run(selfID int, alg string, opts Options) error {
 	rdb := redisx.NewClient()
 	s, err := New(rdb, selfID, alg)
 	if err != nil {
 		return err
 	}
 	defer s.stores.Close()
+	if opts.Bootstrap {
+		if err := s.Bootstrap(); err != nil {
+			log.Printf("node=%d bootstrap skipped: %v", selfID, err)
+		}
+	}
 	mux := BuildMux(alg, s.HandleMessage)
+	mux.HandleFunc("/snapshot/latest", s.HandleSnapshotLatest)
+	mux.HandleFunc("/snapshot/chunk", s.HandleSnapshotChunk)
+	mux.HandleFunc("/state/root", s.HandleStateRoot)
+	mux.HandleFunc("/block", s.HandleBlock)
+	mux.HandleFunc("/tx", s.HandleTx)
+	mux.HandleFunc("/tx/gossip", s.HandleTxGossip)
+	mux.HandleFunc("/commits", s.HandleCommits)
+	mux.HandleFunc("/commits/stream", s.HandleCommitStream)
+	mux.HandleFunc("/query", s.HandleQuery)
+	mux.HandleFunc("/evidence", s.HandleEvidence)
+	mux.HandleFunc("/membership", s.HandleMembership)
+	mux.HandleFunc("/metrics", s.metrics.registry.Handler())
 	addr := fmt.Sprintf("127.0.0.1:%d", s.cfg.BasePort+selfID)
-	log.Printf("node=%d alg=%s listen=%s N=%d t=%d q=%d", selfID, alg, addr, s.th.N, s.th.T, s.th.Q)
+	th := s.thresholds(s.view)
+	log.Printf("node=%d alg=%s listen=%s N=%d power=%d t=%d q=%d", selfID, alg, addr, len(s.validators(s.view)), th.N, th.T, th.Q)
 	s.StartIfLeader()
-	return http.ListenAndServe(addr, mux)
+	s.mu.Lock()
+	s.startCatchUp()
+	s.mu.Unlock()
+	return s.listen(addr, mux)
 }
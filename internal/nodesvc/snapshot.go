@@ -0,0 +1,386 @@
+package nodesvc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
+	"mybft/internal/common"
+	"mybft/internal/membership"
+	"mybft/internal/snapshot"
+	"mybft/internal/storage"
+)
+
+const (
+	defaultSnapshotInterval = 10
+	snapshotKeep            = 2
+	bootstrapAttempts       = 5
+	// 拉取快照分块时在元数据分块大小之外允许的余量。
+	chunkSlackBytes = 4 << 10
+	// 对端 JSON 响应（快照元数据、区块、提交记录）的大小上限。
+	maxPeerJSONBytes = 64 << 20
+)
+
+// 读取快照间隔（按已提交高度计），MYBFT_SNAPSHOT_INTERVAL=0 表示关闭。
+func snapshotIntervalFromEnv() int {
+	if raw := os.Getenv("MYBFT_SNAPSHOT_INTERVAL"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return defaultSnapshotInterval
+}
+
+// 已提交高度到达快照间隔时，生成快照并写入本地 LevelDB。
+func (s *Service) maybeSnapshot() {
+	if s.stores == nil || s.snapshotInterval <= 0 || s.committedHeight <= 0 {
+		return
+	}
+	if s.committedHeight%s.snapshotInterval != 0 {
+		return
+	}
+	if meta, err := s.stores.Snapshots.LoadLatestSnapshotMeta(); err == nil && meta.Height >= s.committedHeight {
+		return
+	}
+	record, err := s.buildSnapshotRecord()
+	if err != nil {
+		log.Printf("node=%d export app state height=%d: %v", s.selfID, s.committedHeight, err)
+		return
+	}
+	meta, chunks, err := snapshot.Encode(record, snapshot.DefaultChunkSize)
+	if err != nil {
+		log.Printf("node=%d encode snapshot height=%d: %v", s.selfID, record.Height, err)
+		return
+	}
+	if err := s.stores.Snapshots.SaveSnapshot(meta, chunks); err != nil {
+		log.Printf("node=%d save snapshot height=%d: %v", s.selfID, record.Height, err)
+		return
+	}
+	if err := s.stores.Snapshots.PruneSnapshotsBefore(meta.Height - (snapshotKeep-1)*s.snapshotInterval); err != nil {
+		log.Printf("node=%d prune snapshots: %v", s.selfID, err)
+	}
+	log.Printf("node=%d event=snapshot_saved height=%d block=%s chunks=%d hash=%s", s.selfID, meta.Height, meta.BlockID, meta.Chunks, meta.Hash)
+}
+
+func (s *Service) buildSnapshotRecord() (storage.SnapshotRecord, error) {
+	state, err := s.app.ExportState()
+	if err != nil {
+		return storage.SnapshotRecord{}, err
+	}
+	history, err := s.election.Export()
+	if err != nil {
+		return storage.SnapshotRecord{}, err
+	}
+	members, err := s.members.Export()
+	if err != nil {
+		return storage.SnapshotRecord{}, err
+	}
+	info := s.app.Info()
+	return storage.SnapshotRecord{
+		Height:     s.committedHeight,
+		View:       s.committedQC.View,
+		Alg:        s.alg,
+		BlockID:    s.committedBlockID,
+		QC:         s.qcRecord(s.committedQC),
+		HighQC:     s.qcRecord(s.hotstuffHighQC),
+		App:        info.Name,
+		StateRoot:  info.AppHash,
+		AppState:   state,
+		Election:   history,
+		Membership: members,
+	}, nil
+}
+
+// HandleSnapshotLatest 返回本节点最新快照的元数据（高度、哈希、分块哈希）。
+func (s *Service) HandleSnapshotLatest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.stores == nil {
+		http.NotFound(w, r)
+		return
+	}
+	meta, err := s.stores.Snapshots.LoadLatestSnapshotMeta()
+	if errors.Is(err, goleveldb.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(meta)
+}
+
+// HandleSnapshotChunk 按 height/index 返回快照分块原始字节。
+func (s *Service) HandleSnapshotChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.stores == nil {
+		http.NotFound(w, r)
+		return
+	}
+	height, err := strconv.Atoi(r.URL.Query().Get("height"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	chunk, err := s.stores.Snapshots.LoadSnapshotChunk(height, index)
+	if errors.Is(err, goleveldb.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(chunk)
+}
+
+// Bootstrap 从同伴拉取最新快照：同一 (height, hash) 须由投票权重至少为 q 的节点声明才视为已验证，
+// 分块与整体哈希校验通过后安装到本地，再进入共识。
+func (s *Service) Bootstrap() error {
+	var lastErr error
+	for attempt := 1; attempt <= bootstrapAttempts; attempt++ {
+		meta, sources, err := s.discoverSnapshot()
+		if err == nil {
+			s.mu.Lock()
+			local := s.committedHeight
+			s.mu.Unlock()
+			if meta.Height <= local {
+				return fmt.Errorf("local committed height %d is not behind snapshot %d", local, meta.Height)
+			}
+			record, chunks, fetchErr := s.fetchSnapshot(meta, sources)
+			if fetchErr == nil {
+				fetchErr = s.installSnapshot(meta, record, chunks)
+			}
+			if fetchErr == nil {
+				return nil
+			}
+			err = fetchErr
+		}
+		lastErr = err
+		log.Printf("node=%d event=bootstrap_retry attempt=%d err=%v", s.selfID, attempt, err)
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	return lastErr
+}
+
+func (s *Service) discoverSnapshot() (storage.SnapshotMeta, []int, error) {
+	type candidate struct {
+		meta    storage.SnapshotMeta
+		sources []int
+	}
+	candidates := map[string]*candidate{}
+	for id, addr := range s.peers.others(s.selfID) {
+		var meta storage.SnapshotMeta
+		if err := s.getPeerJSON(s.peerURL(addr, "/snapshot/latest"), &meta); err != nil || snapshot.CheckMeta(meta) != nil {
+			continue
+		}
+		key := fmt.Sprintf("%d:%s", meta.Height, meta.Hash)
+		c, ok := candidates[key]
+		if !ok {
+			c = &candidate{meta: meta}
+			candidates[key] = c
+		}
+		c.sources = append(c.sources, id)
+	}
+	q := common.CalcThresholds(s.power(s.members.Latest().Validators)).Q
+	var best *candidate
+	for _, c := range candidates {
+		if s.power(c.sources) < q {
+			continue
+		}
+		if best == nil || c.meta.Height > best.meta.Height {
+			best = c
+		}
+	}
+	if best == nil {
+		return storage.SnapshotMeta{}, nil, fmt.Errorf("no snapshot confirmed by peers with power %d", q)
+	}
+	return best.meta, best.sources, nil
+}
+
+func (s *Service) fetchSnapshot(meta storage.SnapshotMeta, sources []int) (storage.SnapshotRecord, [][]byte, error) {
+	if err := snapshot.CheckMeta(meta); err != nil {
+		return storage.SnapshotRecord{}, nil, err
+	}
+	chunks := make([][]byte, meta.Chunks)
+	for i := 0; i < meta.Chunks; i++ {
+		var lastErr error
+		for _, id := range sources {
+			url := s.peerURL(s.peers.addr(id), fmt.Sprintf("/snapshot/chunk?height=%d&index=%d", meta.Height, i))
+			chunk, err := s.getPeerBytes(url, int64(meta.ChunkSize)+chunkSlackBytes)
+			if err == nil {
+				err = snapshot.VerifyChunk(meta, i, chunk)
+			}
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			chunks[i] = chunk
+			lastErr = nil
+			break
+		}
+		if lastErr != nil {
+			return storage.SnapshotRecord{}, nil, lastErr
+		}
+	}
+	record, err := snapshot.Decode(meta, chunks)
+	return record, chunks, err
+}
+
+// 安装快照：按快照中的验证者集合校验已提交区块的 QC，恢复应用状态并校验 app hash，保存到本地快照库，
+// 再把提交指针、highQC 与当前高度对齐到快照之后。highQC 不计入快照哈希，校验不通过时退回已提交区块的 QC。
+func (s *Service) installSnapshot(meta storage.SnapshotMeta, record storage.SnapshotRecord, chunks [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info := s.app.Info(); record.App != info.Name {
+		return fmt.Errorf("snapshot app %q does not match local app %q", record.App, info.Name)
+	}
+	members := s.members
+	if len(record.Membership) > 0 {
+		members = &membership.Set{}
+		if err := members.Restore(record.Membership); err != nil {
+			return fmt.Errorf("restore snapshot membership: %w", err)
+		}
+	}
+	if record.QC.BlockID != record.BlockID || !s.validQCFor(members, qcMessage(record.QC)) {
+		return fmt.Errorf("snapshot qc for block %s at height %d does not verify", record.BlockID, record.Height)
+	}
+	if s.alg == "hotstuff" && record.HighQC.BlockID != "" && !s.validQCFor(members, qcMessage(record.HighQC)) {
+		log.Printf("node=%d event=snapshot_high_qc_rejected height=%d block=%s", s.selfID, record.Height, record.HighQC.BlockID)
+		record.HighQC = record.QC
+	}
+	if err := s.app.RestoreState(record.Height, record.BlockID, record.AppState, record.StateRoot); err != nil {
+		return fmt.Errorf("restore app state: %w", err)
+	}
+	if s.stores != nil {
+		if err := s.stores.Snapshots.SaveSnapshot(meta, chunks); err != nil {
+			log.Printf("node=%d save installed snapshot height=%d: %v", s.selfID, meta.Height, err)
+		}
+	}
+	s.committedHeight = record.Height
+	s.committedBlockID = record.BlockID
+	s.committedQC = quorumCertFromRecord(record.QC)
+	s.persistCommittedBlock(record.BlockID, record.Height)
+	s.commits.advance(record.Height)
+	s.resetSpeculative()
+	if err := s.election.Restore(record.Election); err != nil {
+		log.Printf("node=%d restore election history height=%d: %v", s.selfID, record.Height, err)
+	}
+	if len(record.Membership) > 0 {
+		if err := s.members.Restore(record.Membership); err != nil {
+			log.Printf("node=%d restore membership height=%d: %v", s.selfID, record.Height, err)
+		}
+		s.persistMembership()
+		s.refreshPeers()
+	}
+
+	next := record.Height + 1
+	if s.alg == "hotstuff" && record.HighQC.BlockID != "" {
+		s.hotstuffBlocks[record.BlockID] = &hotstuffBlock{
+			Block:     common.Block{BlockID: record.BlockID, Digest: record.BlockID, View: record.View, Height: record.Height},
+			QC:        &s.committedQC,
+			Committed: true,
+			Executed:  true,
+		}
+		highQC := quorumCertFromRecord(record.HighQC)
+		if _, ok := s.hotstuffBlocks[highQC.BlockID]; !ok {
+			s.hotstuffBlocks[highQC.BlockID] = &hotstuffBlock{
+				Block: common.Block{BlockID: highQC.BlockID, Digest: highQC.BlockID, View: highQC.View, Height: highQC.Height},
+				QC:    &highQC,
+			}
+		}
+		s.hotstuffHighQC = highQC
+		s.hotstuffLockedQC = s.committedQC
+		if s.stores != nil {
+			if err := s.stores.State.SaveHighQC(record.HighQC); err != nil {
+				log.Printf("node=%d save high qc from snapshot: %v", s.selfID, err)
+			}
+		}
+		s.persistLockedQC(s.committedQC)
+		if highQC.Height >= next {
+			next = highQC.Height + 1
+		}
+	}
+	if next > s.height {
+		s.height = next
+		s.view = next
+		s.state = map[int]*heightState{}
+	}
+	s.persistPosition()
+	s.initPipeline()
+	s.leaderMode = s.isLeader(s.view)
+	log.Printf("node=%d event=snapshot_installed height=%d block=%s next_height=%d", s.selfID, record.Height, record.BlockID, s.height)
+	return nil
+}
+
+func (s *Service) qcRecord(qc common.QuorumCert) storage.QCRecord {
+	return storage.QCRecord{
+		BlockID: qc.BlockID,
+		Alg:     s.alg,
+		QCType:  qc.Type,
+		Digest:  qc.BlockID,
+		View:    qc.View,
+		Height:  qc.Height,
+		QC:      qc.QC,
+		Signers: qc.Signers,
+	}
+}
+
+// qcMessage 把 QC 记录还原为可按 validQC 校验的 QC 消息。
+func qcMessage(qc storage.QCRecord) common.ConsensusMessage {
+	return common.ConsensusMessage{Type: qc.QCType, View: qc.View, Height: qc.Height, BlockID: qc.BlockID, Digest: qc.BlockID, QC: qc.QC, Signers: qc.Signers}
+}
+
+func quorumCertFromRecord(qc storage.QCRecord) common.QuorumCert {
+	return common.QuorumCert{Type: qc.QCType, BlockID: qc.BlockID, View: qc.View, Height: qc.Height, QC: qc.QC, Signers: qc.Signers}
+}
+
+func (s *Service) getPeerJSON(url string, out any) error {
+	raw, err := s.getPeerBytes(url, maxPeerJSONBytes)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// getPeerBytes 读取对端响应体，超过 limit 字节视为错误，避免恶意对端耗尽内存。
+func (s *Service) getPeerBytes(url string, limit int64) ([]byte, error) {
+	resp, err := s.peerHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", url, limit)
+	}
+	return raw, nil
+}
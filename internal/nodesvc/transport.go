@@ -0,0 +1,59 @@
+package nodesvc
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"mybft/internal/tlsx"
+)
+
+const peerRequestTimeout = 5 * time.Second
+
+// initTransport 按 MYBFT_TLS 选择节点间与上报 client 的传输方式：开启时加载本节点证书，
+// 发出的请求出示证书并只信任本地 CA；否则为明文 HTTP。
+func (s *Service) initTransport() error {
+	s.scheme = tlsx.Scheme(tlsx.Enabled())
+	if !tlsx.Enabled() {
+		s.httpClient = &http.Client{}
+		s.peerHTTPClient = &http.Client{Timeout: peerRequestTimeout}
+		return nil
+	}
+	id, err := tlsx.LoadNode(s.rdb, s.selfID)
+	if err != nil {
+		return err
+	}
+	s.identity = id
+	s.httpClient = id.HTTPClient(0)
+	s.peerHTTPClient = id.HTTPClient(peerRequestTimeout)
+	return nil
+}
+
+// peerURL 拼接同伴或 client 的请求地址。
+func (s *Service) peerURL(addr, path string) string {
+	return s.scheme + "://" + addr + path
+}
+
+// authenticPeer 在开启 TLS 时检查请求方证书：from>0 时须为节点 from 的证书，否则须为任一节点的证书。
+// 明文模式下不做检查。
+func (s *Service) authenticPeer(r *http.Request, endpoint string, from int) bool {
+	if s.identity == nil {
+		return true
+	}
+	id, ok := tlsx.PeerNode(r)
+	if ok && (from <= 0 || id == from) {
+		return true
+	}
+	s.metrics.peerAuthRejected.Inc(endpoint)
+	log.Printf("node=%d event=peer_auth_rejected endpoint=%s claimed=%d cert_node=%d remote=%s", s.selfID, endpoint, from, id, r.RemoteAddr)
+	return false
+}
+
+// listen 启动节点 HTTP 服务；开启 TLS 时要求认证的入口由 authenticPeer 检查身份。
+func (s *Service) listen(addr string, mux *http.ServeMux) error {
+	if s.identity == nil {
+		return http.ListenAndServe(addr, mux)
+	}
+	srv := &http.Server{Addr: addr, Handler: mux, TLSConfig: s.identity.ServerConfig()}
+	return srv.ListenAndServeTLS("", "")
+}
@@ -6,6 +6,8 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+
+	"mybft/internal/common"
 )
 
 type Client struct {
@@ -70,6 +72,8 @@ func (c *Client) HIncrBy(key, field string, by int) (int, error) {
 }
 // Del 删除指定键。
 func (c *Client) Del(key string) error { _, err := c.cmd("DEL", key); return err }
+// Ping 检查 Redis 是否可达。
+func (c *Client) Ping() error { _, err := c.cmd("PING"); return err }
 // 向集合写入成员（用于去重）。
 func (c *Client) SAdd(key, member string) (int, error) {
 	v, err := c.cmd("SADD", key, member)
@@ -100,6 +104,8 @@ type ClusterConfig struct {
 	N          int
 	BasePort   int
 	ClientAddr string
+	// Weights 为各节点的投票权重（weights 字段，按编号 1,2,... 逗号分隔），未配置的节点权重为 1。
+	Weights map[int]int
 }
 
 // 读取 cluster:config，供节点初始化端口、N 与客户端地址。
@@ -126,5 +132,12 @@ func ReadClusterConfig(rdb *Client) (ClusterConfig, error) {
 	if ca := m["clientAddr"]; ca != "" {
 		cfg.ClientAddr = ca
 	}
+	if w := m["weights"]; w != "" {
+		weights, err := common.ParseWeights(w)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid cluster:config weights: %v", err)
+		}
+		cfg.Weights = weights
+	}
 	return cfg, nil
 }
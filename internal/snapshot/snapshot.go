@@ -0,0 +1,115 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"mybft/internal/storage"
+)
+
+// DefaultChunkSize 为快照分块大小（64KiB），用于 HTTP 分块传输。
+const DefaultChunkSize = 64 * 1024
+
+// MaxChunks 为可接受的快照分块数上限（按默认分块大小约 1GiB）。
+const MaxChunks = 16 * 1024
+
+// MaxChunkSize 为可接受的分块大小上限，拉取分块时按元数据中的分块大小限制响应体。
+const MaxChunkSize = 1 << 20
+
+// Encode 将快照序列化并切分为固定大小的分块，返回元数据与分块内容。
+// 哈希只覆盖快照内容本身，各节点在同一高度生成的快照哈希一致。
+func Encode(record storage.SnapshotRecord, chunkSize int) (storage.SnapshotMeta, [][]byte, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return storage.SnapshotMeta{}, nil, err
+	}
+	chunks := make([][]byte, 0, len(raw)/chunkSize+1)
+	hashes := make([]string, 0, cap(chunks))
+	for off := 0; off < len(raw); off += chunkSize {
+		end := off + chunkSize
+		if end > len(raw) {
+			end = len(raw)
+		}
+		chunk := raw[off:end]
+		chunks = append(chunks, chunk)
+		hashes = append(hashes, hashHex(chunk))
+	}
+	meta := storage.SnapshotMeta{
+		Height:      record.Height,
+		View:        record.View,
+		BlockID:     record.BlockID,
+		Hash:        hashHex(raw),
+		ChunkSize:   chunkSize,
+		Chunks:      len(chunks),
+		ChunkHashes: hashes,
+		HighQC:      record.HighQC,
+		CreatedAt:   time.Now().UnixNano(),
+	}
+	return meta, chunks, nil
+}
+
+// CheckMeta 在按元数据分配内存、拉取分块之前检查其自洽：分块数在 1..MaxChunks 之内且与分块哈希数一致，
+// 分块大小在 1..MaxChunkSize 之内。
+func CheckMeta(meta storage.SnapshotMeta) error {
+	if meta.Chunks < 1 || meta.Chunks > MaxChunks {
+		return fmt.Errorf("snapshot at height %d has invalid chunk count %d", meta.Height, meta.Chunks)
+	}
+	if meta.ChunkSize < 1 || meta.ChunkSize > MaxChunkSize {
+		return fmt.Errorf("snapshot at height %d has invalid chunk size %d", meta.Height, meta.ChunkSize)
+	}
+	if meta.Chunks != len(meta.ChunkHashes) {
+		return fmt.Errorf("snapshot at height %d lists %d chunk hashes for %d chunks", meta.Height, len(meta.ChunkHashes), meta.Chunks)
+	}
+	return nil
+}
+
+// VerifyChunk 校验单个分块的哈希与元数据一致。
+func VerifyChunk(meta storage.SnapshotMeta, index int, chunk []byte) error {
+	if index < 0 || index >= len(meta.ChunkHashes) {
+		return fmt.Errorf("snapshot chunk %d out of range", index)
+	}
+	if hashHex(chunk) != meta.ChunkHashes[index] {
+		return fmt.Errorf("snapshot chunk %d hash mismatch", index)
+	}
+	return nil
+}
+
+// Decode 拼接分块、校验整体哈希并还原快照内容。
+func Decode(meta storage.SnapshotMeta, chunks [][]byte) (storage.SnapshotRecord, error) {
+	var record storage.SnapshotRecord
+	if err := CheckMeta(meta); err != nil {
+		return record, err
+	}
+	if len(chunks) != meta.Chunks {
+		return record, fmt.Errorf("snapshot has %d chunks, want %d", len(chunks), meta.Chunks)
+	}
+	for i, chunk := range chunks {
+		if err := VerifyChunk(meta, i, chunk); err != nil {
+			return record, err
+		}
+	}
+	raw := bytes.Join(chunks, nil)
+	if hashHex(raw) != meta.Hash {
+		return record, fmt.Errorf("snapshot hash mismatch at height %d", meta.Height)
+	}
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return record, err
+	}
+	record.HighQC = meta.HighQC
+	if record.Height != meta.Height || record.BlockID != meta.BlockID {
+		return record, fmt.Errorf("snapshot content does not match meta at height %d", meta.Height)
+	}
+	return record, nil
+}
+
+func hashHex(raw []byte) string {
+	h := sha256.Sum256(raw)
+	return hex.EncodeToString(h[:])
+}
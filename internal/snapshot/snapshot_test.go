@@ -0,0 +1,125 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mybft/internal/storage"
+)
+
+func testRecord() storage.SnapshotRecord {
+	return storage.SnapshotRecord{
+		Height:    12,
+		View:      12,
+		Alg:       "hotstuff",
+		BlockID:   "b12",
+		QC:        storage.QCRecord{BlockID: "b12", QCType: "HSQC", View: 12, Height: 12, QC: "qc12", Signers: []int{1, 2, 3}},
+		HighQC:    storage.QCRecord{BlockID: "b13", QCType: "HSQC", View: 13, Height: 13, QC: "qc13", Signers: []int{1, 2, 4}},
+		App:       "transfer",
+		StateRoot: "root12",
+		AppState:  json.RawMessage(`{"balances":{"1":5}}`),
+		Election:  json.RawMessage(`[{"height":11,"proposer":3}]`),
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, chunkSize := range []int{0, 7, 64, 1 << 20} {
+		record := testRecord()
+		meta, chunks, err := Encode(record, chunkSize)
+		if err != nil {
+			t.Fatalf("Encode(chunkSize=%d): %v", chunkSize, err)
+		}
+		if meta.Chunks != len(chunks) || len(meta.ChunkHashes) != len(chunks) || meta.Height != record.Height || meta.BlockID != record.BlockID {
+			t.Fatalf("Encode(chunkSize=%d) meta = %+v with %d chunks", chunkSize, meta, len(chunks))
+		}
+		got, err := Decode(meta, chunks)
+		if err != nil {
+			t.Fatalf("Decode(chunkSize=%d): %v", chunkSize, err)
+		}
+		if !reflect.DeepEqual(got, record) {
+			t.Fatalf("Decode(chunkSize=%d) = %+v, want %+v", chunkSize, got, record)
+		}
+	}
+}
+
+func TestHashIgnoresHighQC(t *testing.T) {
+	a := testRecord()
+	b := testRecord()
+	b.HighQC = storage.QCRecord{BlockID: "b14", View: 14, Height: 14, QC: "qc14"}
+	metaA, _, err := Encode(a, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metaB, _, err := Encode(b, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metaA.Hash != metaB.Hash {
+		t.Fatalf("snapshot hash depends on local HighQC: %s vs %s", metaA.Hash, metaB.Hash)
+	}
+	b.StateRoot = "other"
+	if metaC, _, _ := Encode(b, 0); metaC.Hash == metaA.Hash {
+		t.Fatal("snapshot hash does not cover state root")
+	}
+}
+
+func TestCheckMeta(t *testing.T) {
+	cases := []struct {
+		name string
+		meta storage.SnapshotMeta
+		err  string
+	}{
+		{"ok", storage.SnapshotMeta{Chunks: 2, ChunkSize: 64, ChunkHashes: []string{"a", "b"}}, ""},
+		{"no chunks", storage.SnapshotMeta{}, "invalid chunk count"},
+		{"too many chunks", storage.SnapshotMeta{Chunks: MaxChunks + 1}, "invalid chunk count"},
+		{"no chunk size", storage.SnapshotMeta{Chunks: 2, ChunkHashes: []string{"a", "b"}}, "invalid chunk size"},
+		{"chunk too large", storage.SnapshotMeta{Chunks: 2, ChunkSize: MaxChunkSize + 1, ChunkHashes: []string{"a", "b"}}, "invalid chunk size"},
+		{"hash count mismatch", storage.SnapshotMeta{Chunks: 2, ChunkSize: 64, ChunkHashes: []string{"a"}}, "chunk hashes"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckMeta(tc.meta)
+			if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("CheckMeta = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	meta, chunks, err := Encode(testRecord(), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		mutate func(meta *storage.SnapshotMeta, chunks [][]byte) [][]byte
+	}{
+		{"missing chunk", func(_ *storage.SnapshotMeta, c [][]byte) [][]byte { return c[1:] }},
+		{"altered chunk", func(_ *storage.SnapshotMeta, c [][]byte) [][]byte {
+			c[0] = append([]byte("x"), c[0][1:]...)
+			return c
+		}},
+		{"altered hash", func(m *storage.SnapshotMeta, c [][]byte) [][]byte {
+			m.Hash = strings.Repeat("0", 64)
+			return c
+		}},
+		{"meta for other block", func(m *storage.SnapshotMeta, c [][]byte) [][]byte {
+			m.BlockID = "b99"
+			return c
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := meta
+			m.ChunkHashes = append([]string(nil), meta.ChunkHashes...)
+			c := append([][]byte(nil), chunks...)
+			c = tc.mutate(&m, c)
+			if _, err := Decode(m, c); err == nil {
+				t.Fatal("Decode accepted tampered snapshot")
+			}
+		})
+	}
+}
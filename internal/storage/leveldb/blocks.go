@@ -1,9 +1,11 @@
 package leveldbstore
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"mybft/internal/storage"
 )
@@ -35,3 +37,66 @@ func (s *BlockStore) GetQC(blockID string) (storage.QCRecord, error) {
 	err := getJSON(s.db, fmt.Sprintf("qc:%s", blockID), &qc)
 	return qc, err
 }
+
+func (s *BlockStore) SaveCommit(record storage.CommitRecord) error {
+	return putJSON(s.db, fmt.Sprintf("commit:%09d", record.Height), record)
+}
+
+func (s *BlockStore) ListCommits(from, limit int) ([]storage.CommitRecord, error) {
+	iter := s.db.NewIterator(&util.Range{Start: []byte(fmt.Sprintf("commit:%09d", from)), Limit: []byte("commit;")}, nil)
+	defer iter.Release()
+
+	records := make([]storage.CommitRecord, 0)
+	for len(records) < limit && iter.Next() {
+		var record storage.CommitRecord
+		if err := unmarshalJSON(iter.Value(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, iter.Error()
+}
+
+func evidenceKey(record storage.EvidenceRecord) string {
+	return fmt.Sprintf("evidence:%s:%09d:%d", record.Kind, record.View, record.Offender)
+}
+
+func (s *BlockStore) SaveEvidence(record storage.EvidenceRecord) (bool, error) {
+	key := evidenceKey(record)
+	if ok, err := s.db.Has([]byte(key), nil); err != nil || ok {
+		return false, err
+	}
+	return true, putJSON(s.db, key, record)
+}
+
+func (s *BlockStore) MarkEvidenceIncluded(record storage.EvidenceRecord, height int) error {
+	key := evidenceKey(record)
+	var existing storage.EvidenceRecord
+	err := getJSON(s.db, key, &existing)
+	switch {
+	case err == nil:
+		if existing.IncludedHeight != 0 {
+			return nil
+		}
+		record = existing
+	case !errors.Is(err, leveldb.ErrNotFound):
+		return err
+	}
+	record.IncludedHeight = height
+	return putJSON(s.db, key, record)
+}
+
+func (s *BlockStore) ListEvidence() ([]storage.EvidenceRecord, error) {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte("evidence:")), nil)
+	defer iter.Release()
+
+	records := make([]storage.EvidenceRecord, 0)
+	for iter.Next() {
+		var record storage.EvidenceRecord
+		if err := unmarshalJSON(iter.Value(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, iter.Error()
+}
@@ -10,7 +10,7 @@ import (
 )
 
 type ClientStores struct {
-	Metrics storage.MetricsStore
+	Runs storage.RunStore
 
 	metricsDB *leveldb.DB
 }
@@ -25,11 +25,25 @@ func OpenClientStores(root string) (*ClientStores, error) {
 		return nil, err
 	}
 	return &ClientStores{
-		Metrics:   NewMetricsStore(metricsDB),
+		Runs:      NewRunStore(metricsDB),
 		metricsDB: metricsDB,
 	}, nil
 }
 
+// Metrics 返回指定运行的指标存储，空 ID 读取引入运行 ID 之前的旧数据。
+func (s *ClientStores) Metrics(runID string) storage.MetricsStore {
+	return NewMetricsStore(s.metricsDB, runID)
+}
+
+// LatestRun 返回开始时间最晚的运行 ID；没有运行元数据时返回空串（即旧数据）。
+func (s *ClientStores) LatestRun() (string, error) {
+	runs, err := s.Runs.ListRuns()
+	if err != nil || len(runs) == 0 {
+		return "", err
+	}
+	return runs[len(runs)-1].ID, nil
+}
+
 func (s *ClientStores) Close() error {
 	if s == nil {
 		return nil
@@ -7,7 +7,7 @@ import (
 )
 
 func putJSON(db *leveldb.DB, key string, value any) error {
-	raw, err := json.Marshal(value)
+	raw, err := jsonMarshal(value)
 	if err != nil {
 		return err
 	}
@@ -25,3 +25,7 @@ func getJSON(db *leveldb.DB, key string, out any) error {
 func unmarshalJSON(raw []byte, out any) error {
 	return json.Unmarshal(raw, out)
 }
+
+func jsonMarshal(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
@@ -0,0 +1,55 @@
+package leveldbstore
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"mybft/internal/storage"
+)
+
+// 键值应用与账本共用应用状态库，键以 kv: 为前缀，状态根记录共用 root: 前缀。
+
+func (s *LedgerStore) LoadKV() (map[string]string, error) {
+	prefix := []byte("kv:")
+	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	entries := map[string]string{}
+	for iter.Next() {
+		entries[string(iter.Key()[len(prefix):])] = string(iter.Value())
+	}
+	return entries, iter.Error()
+}
+
+func (s *LedgerStore) SaveKVBlock(sets map[string]string, deletes []string, root storage.StateRootRecord) error {
+	batch := new(leveldb.Batch)
+	for k, v := range sets {
+		batch.Put([]byte("kv:"+k), []byte(v))
+	}
+	for _, k := range deletes {
+		batch.Delete([]byte("kv:" + k))
+	}
+	if err := putRoot(batch, root); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+func (s *LedgerStore) ReplaceKV(entries map[string]string, root storage.StateRootRecord) error {
+	batch := new(leveldb.Batch)
+	iter := s.db.NewIterator(util.BytesPrefix([]byte("kv:")), nil)
+	for iter.Next() {
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	for k, v := range entries {
+		batch.Put([]byte("kv:"+k), []byte(v))
+	}
+	if err := putRoot(batch, root); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
@@ -0,0 +1,105 @@
+package leveldbstore
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"mybft/internal/storage"
+)
+
+type LedgerStore struct {
+	db *leveldb.DB
+}
+
+func NewLedgerStore(db *leveldb.DB) *LedgerStore {
+	return &LedgerStore{db: db}
+}
+
+func (s *LedgerStore) LoadAccounts() ([]storage.AccountRecord, error) {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte("account:")), nil)
+	defer iter.Release()
+
+	records := make([]storage.AccountRecord, 0)
+	for iter.Next() {
+		var record storage.AccountRecord
+		if err := jsonUnmarshal(iter.Value(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, iter.Error()
+}
+
+// SaveBlockState 在同一个 batch 中写入本块变更的账户与新的状态根。
+func (s *LedgerStore) SaveBlockState(accounts []storage.AccountRecord, root storage.StateRootRecord) error {
+	batch := new(leveldb.Batch)
+	if err := putAccounts(batch, accounts); err != nil {
+		return err
+	}
+	if err := putRoot(batch, root); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+// ReplaceAccounts 清空现有账户后写入完整账户集合（用于安装快照）。
+func (s *LedgerStore) ReplaceAccounts(accounts []storage.AccountRecord, root storage.StateRootRecord) error {
+	batch := new(leveldb.Batch)
+	iter := s.db.NewIterator(util.BytesPrefix([]byte("account:")), nil)
+	for iter.Next() {
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := putAccounts(batch, accounts); err != nil {
+		return err
+	}
+	if err := putRoot(batch, root); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+func (s *LedgerStore) LoadStateRoot(height int) (storage.StateRootRecord, error) {
+	var root storage.StateRootRecord
+	err := getJSON(s.db, fmt.Sprintf("root:%09d", height), &root)
+	return root, err
+}
+
+func (s *LedgerStore) LoadLatestStateRoot() (storage.StateRootRecord, error) {
+	raw, err := s.db.Get([]byte("meta:lastRoot"), nil)
+	if err != nil {
+		return storage.StateRootRecord{}, err
+	}
+	height, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return storage.StateRootRecord{}, err
+	}
+	return s.LoadStateRoot(height)
+}
+
+func putAccounts(batch *leveldb.Batch, accounts []storage.AccountRecord) error {
+	for _, account := range accounts {
+		raw, err := jsonMarshal(account)
+		if err != nil {
+			return err
+		}
+		batch.Put([]byte(fmt.Sprintf("account:%06d", account.ID)), raw)
+	}
+	return nil
+}
+
+func putRoot(batch *leveldb.Batch, root storage.StateRootRecord) error {
+	raw, err := jsonMarshal(root)
+	if err != nil {
+		return err
+	}
+	batch.Put([]byte(fmt.Sprintf("root:%09d", root.Height)), raw)
+	batch.Put([]byte("meta:lastRoot"), []byte(strconv.Itoa(root.Height)))
+	return nil
+}
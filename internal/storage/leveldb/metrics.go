@@ -2,6 +2,7 @@ package leveldbstore
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
@@ -9,26 +10,32 @@ import (
 	"mybft/internal/storage"
 )
 
+// MetricsStore 的键以 run:<id>: 为前缀；空 ID 对应引入运行 ID 之前写入的旧键。
 type MetricsStore struct {
-	db *leveldb.DB
+	db     *leveldb.DB
+	prefix string
 }
 
-func NewMetricsStore(db *leveldb.DB) *MetricsStore {
-	return &MetricsStore{db: db}
+func NewMetricsStore(db *leveldb.DB, runID string) *MetricsStore {
+	prefix := ""
+	if runID != "" {
+		prefix = "run:" + runID + ":"
+	}
+	return &MetricsStore{db: db, prefix: prefix}
 }
 
 func (s *MetricsStore) SaveMetric(record storage.MetricRecord) error {
-	return putJSON(s.db, fmt.Sprintf("metric:%09d", record.Height), record)
+	return putJSON(s.db, s.prefix+fmt.Sprintf("metric:%09d", record.Height), record)
 }
 
 func (s *MetricsStore) LoadMetric(height int) (storage.MetricRecord, error) {
 	var record storage.MetricRecord
-	err := getJSON(s.db, fmt.Sprintf("metric:%09d", height), &record)
+	err := getJSON(s.db, s.prefix+fmt.Sprintf("metric:%09d", height), &record)
 	return record, err
 }
 
 func (s *MetricsStore) ListMetrics() ([]storage.MetricRecord, error) {
-	prefix := []byte("metric:")
+	prefix := []byte(s.prefix + "metric:")
 	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
 	defer iter.Release()
 
@@ -44,11 +51,11 @@ func (s *MetricsStore) ListMetrics() ([]storage.MetricRecord, error) {
 }
 
 func (s *MetricsStore) AppendThroughputSample(record storage.ThroughputSampleRecord) error {
-	return putJSON(s.db, fmt.Sprintf("sample:%020d", record.RecordedAt), record)
+	return putJSON(s.db, s.prefix+fmt.Sprintf("sample:%020d", record.RecordedAt), record)
 }
 
 func (s *MetricsStore) LoadThroughputSamplesSince(sinceUnixNano int64) ([]storage.ThroughputSampleRecord, error) {
-	prefix := []byte("sample:")
+	prefix := []byte(s.prefix + "sample:")
 	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
 	defer iter.Release()
 
@@ -66,6 +73,70 @@ func (s *MetricsStore) LoadThroughputSamplesSince(sinceUnixNano int64) ([]storag
 	return records, iter.Error()
 }
 
+func (s *MetricsStore) SavePhases(record storage.PhaseRecord) error {
+	return putJSON(s.db, s.prefix+fmt.Sprintf("phase:%09d", record.Height), record)
+}
+
+func (s *MetricsStore) ListPhases() ([]storage.PhaseRecord, error) {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(s.prefix+"phase:")), nil)
+	defer iter.Release()
+
+	records := make([]storage.PhaseRecord, 0)
+	for iter.Next() {
+		var record storage.PhaseRecord
+		if err := jsonUnmarshal(iter.Value(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, iter.Error()
+}
+
+func (s *MetricsStore) SaveHistogram(name string, record storage.HistogramRecord) error {
+	return putJSON(s.db, s.prefix+"histogram:"+name, record)
+}
+
+func (s *MetricsStore) LoadHistogram(name string) (storage.HistogramRecord, error) {
+	var record storage.HistogramRecord
+	err := getJSON(s.db, s.prefix+"histogram:"+name, &record)
+	return record, err
+}
+
+type RunStore struct {
+	db *leveldb.DB
+}
+
+func NewRunStore(db *leveldb.DB) *RunStore {
+	return &RunStore{db: db}
+}
+
+func (s *RunStore) SaveRun(record storage.RunRecord) error {
+	return putJSON(s.db, "runmeta:"+record.ID, record)
+}
+
+func (s *RunStore) LoadRun(id string) (storage.RunRecord, error) {
+	var record storage.RunRecord
+	err := getJSON(s.db, "runmeta:"+id, &record)
+	return record, err
+}
+
+// ListRuns 按开始时间升序返回所有运行。
+func (s *RunStore) ListRuns() ([]storage.RunRecord, error) {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte("runmeta:")), nil)
+	defer iter.Release()
+
+	records := make([]storage.RunRecord, 0)
+	for iter.Next() {
+		var record storage.RunRecord
+		if err := jsonUnmarshal(iter.Value(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].StartedAt < records[j].StartedAt })
+	return records, iter.Error()
+}
+
 func jsonUnmarshal(raw []byte, out any) error {
 	return unmarshalJSON(raw, out)
 }
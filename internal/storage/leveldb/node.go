@@ -11,23 +11,27 @@ import (
 )
 
 type NodeStores struct {
-	Blocks storage.BlockStore
-	State  storage.StateStore
+	Blocks    storage.BlockStore
+	State     storage.StateStore
+	Snapshots storage.SnapshotStore
+	Ledger    storage.LedgerStore
+	KV        storage.KVStore
 
 	blocksDB *leveldb.DB
 	stateDB  *leveldb.DB
+	ledgerDB *leveldb.DB
 }
 
 func OpenNodeStores(root string, nodeID int) (*NodeStores, error) {
 	base := filepath.Join(root, fmt.Sprintf("node-%d", nodeID))
 	blocksPath := filepath.Join(base, "blocks", "leveldb")
 	statePath := filepath.Join(base, "state", "leveldb")
+	ledgerPath := filepath.Join(base, "ledger", "leveldb")
 
-	if err := os.MkdirAll(blocksPath, 0o755); err != nil {
-		return nil, err
-	}
-	if err := os.MkdirAll(statePath, 0o755); err != nil {
-		return nil, err
+	for _, path := range []string{blocksPath, statePath, ledgerPath} {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return nil, err
+		}
 	}
 
 	blocksDB, err := leveldb.OpenFile(blocksPath, nil)
@@ -39,12 +43,23 @@ func OpenNodeStores(root string, nodeID int) (*NodeStores, error) {
 		_ = blocksDB.Close()
 		return nil, err
 	}
+	ledgerDB, err := leveldb.OpenFile(ledgerPath, nil)
+	if err != nil {
+		_ = blocksDB.Close()
+		_ = stateDB.Close()
+		return nil, err
+	}
 
+	ledgerStore := NewLedgerStore(ledgerDB)
 	return &NodeStores{
-		Blocks:   NewBlockStore(blocksDB),
-		State:    NewStateStore(stateDB),
-		blocksDB: blocksDB,
-		stateDB:  stateDB,
+		Blocks:    NewBlockStore(blocksDB),
+		State:     NewStateStore(stateDB),
+		Snapshots: NewSnapshotStore(stateDB),
+		Ledger:    ledgerStore,
+		KV:        ledgerStore,
+		blocksDB:  blocksDB,
+		stateDB:   stateDB,
+		ledgerDB:  ledgerDB,
 	}, nil
 }
 
@@ -58,5 +73,8 @@ func (s *NodeStores) Close() error {
 	if s.stateDB != nil {
 		_ = s.stateDB.Close()
 	}
+	if s.ledgerDB != nil {
+		_ = s.ledgerDB.Close()
+	}
 	return nil
 }
@@ -0,0 +1,85 @@
+package leveldbstore
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"mybft/internal/storage"
+)
+
+type SnapshotStore struct {
+	db *leveldb.DB
+}
+
+func NewSnapshotStore(db *leveldb.DB) *SnapshotStore {
+	return &SnapshotStore{db: db}
+}
+
+func (s *SnapshotStore) SaveSnapshot(meta storage.SnapshotMeta, chunks [][]byte) error {
+	raw, err := jsonMarshal(meta)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	for i, chunk := range chunks {
+		batch.Put([]byte(snapshotChunkKey(meta.Height, i)), chunk)
+	}
+	batch.Put([]byte(snapshotMetaKey(meta.Height)), raw)
+	batch.Put([]byte("meta:latestSnapshot"), []byte(strconv.Itoa(meta.Height)))
+	return s.db.Write(batch, nil)
+}
+
+func (s *SnapshotStore) LoadLatestSnapshotMeta() (storage.SnapshotMeta, error) {
+	raw, err := s.db.Get([]byte("meta:latestSnapshot"), nil)
+	if err != nil {
+		return storage.SnapshotMeta{}, err
+	}
+	height, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return storage.SnapshotMeta{}, err
+	}
+	return s.LoadSnapshotMeta(height)
+}
+
+func (s *SnapshotStore) LoadSnapshotMeta(height int) (storage.SnapshotMeta, error) {
+	var meta storage.SnapshotMeta
+	err := getJSON(s.db, snapshotMetaKey(height), &meta)
+	return meta, err
+}
+
+func (s *SnapshotStore) LoadSnapshotChunk(height, index int) ([]byte, error) {
+	return s.db.Get([]byte(snapshotChunkKey(height, index)), nil)
+}
+
+// PruneSnapshotsBefore 删除低于指定高度的快照元数据与分块。
+func (s *SnapshotStore) PruneSnapshotsBefore(height int) error {
+	batch := new(leveldb.Batch)
+	for _, prefix := range []string{"snapshot:meta:", "snapshot:chunk:"} {
+		iter := s.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+		for iter.Next() {
+			var h int
+			if _, err := fmt.Sscanf(string(iter.Key()[len(prefix):]), "%09d", &h); err != nil {
+				continue
+			}
+			if h < height {
+				batch.Delete(append([]byte(nil), iter.Key()...))
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+	return s.db.Write(batch, nil)
+}
+
+func snapshotMetaKey(height int) string {
+	return fmt.Sprintf("snapshot:meta:%09d", height)
+}
+
+func snapshotChunkKey(height, index int) string {
+	return fmt.Sprintf("snapshot:chunk:%09d:%04d", height, index)
+}
@@ -1,6 +1,7 @@
 package leveldbstore
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -65,6 +66,14 @@ func (s *StateStore) LoadLastCommittedBlock() (string, error) {
 	return string(raw), nil
 }
 
+func (s *StateStore) SaveLastCommittedHeight(height int) error {
+	return s.db.Put([]byte("meta:lastCommittedHeight"), []byte(strconv.Itoa(height)), nil)
+}
+
+func (s *StateStore) LoadLastCommittedHeight() (int, error) {
+	return s.loadInt("meta:lastCommittedHeight")
+}
+
 func (s *StateStore) SaveVote(view int, blockID string) error {
 	return s.db.Put([]byte(fmt.Sprintf("vote:%d", view)), []byte(blockID), nil)
 }
@@ -87,6 +96,14 @@ func (s *StateStore) SaveCommitProof(qc storage.QCRecord) error {
 	return putJSON(s.db, key, qc)
 }
 
+func (s *StateStore) SaveMembership(raw json.RawMessage) error {
+	return s.db.Put([]byte("meta:membership"), raw, nil)
+}
+
+func (s *StateStore) LoadMembership() (json.RawMessage, error) {
+	return s.db.Get([]byte("meta:membership"), nil)
+}
+
 func (s *StateStore) loadInt(key string) (int, error) {
 	raw, err := s.db.Get([]byte(key), nil)
 	if err != nil {
@@ -1,16 +1,29 @@
 package storage
 
+import "encoding/json"
+
 type BlockRecord struct {
-	BlockID       string   `json:"block_id"`
-	ParentBlockID string   `json:"parent_block_id,omitempty"`
-	Alg           string   `json:"alg"`
-	MessageType   string   `json:"message_type"`
-	Digest        string   `json:"digest"`
-	View          int      `json:"view"`
-	Height        int      `json:"height"`
-	From          int      `json:"from"`
-	Tx            []string `json:"tx,omitempty"`
-	CreatedAt     int64    `json:"created_at"`
+	BlockID       string `json:"block_id"`
+	ParentBlockID string `json:"parent_block_id,omitempty"`
+	// Justify* 为 HotStuff 提案所附的 justify QC，追赶的节点据此恢复链上的签名者。
+	JustifyBlockID string   `json:"justify_block_id,omitempty"`
+	JustifyView    int      `json:"justify_view,omitempty"`
+	JustifyQC      string   `json:"justify_qc,omitempty"`
+	JustifySigners []int    `json:"justify_signers,omitempty"`
+	Alg            string   `json:"alg"`
+	MessageType    string   `json:"message_type"`
+	Digest         string   `json:"digest"`
+	View           int      `json:"view"`
+	Height         int      `json:"height"`
+	From           int      `json:"from"`
+	Tx             []string `json:"tx,omitempty"`
+	CreatedAt      int64    `json:"created_at"`
+	// Evidence 为区块打包的作恶证据，校验区块摘要时需要其 ID。
+	Evidence []EvidenceRecord `json:"evidence,omitempty"`
+	// Reconfig 为区块打包的成员变更交易。
+	Reconfig []string `json:"reconfig,omitempty"`
+	// SigFull 为提案者对提案的签名，向其他节点提供区块时一并返回供其校验。
+	SigFull string `json:"sig_full,omitempty"`
 }
 
 type QCRecord struct {
@@ -22,9 +35,53 @@ type QCRecord struct {
 	Height    int    `json:"height"`
 	From      int    `json:"from"`
 	QC        string `json:"qc"`
+	Signers   []int  `json:"signers,omitempty"`
 	CreatedAt int64  `json:"created_at"`
 }
 
+// CommitRecord 是按高度索引的已执行区块：交易全文、逐笔执行结果码与提交证明，供提交订阅断点续传。
+type CommitRecord struct {
+	Height    int    `json:"height"`
+	BlockID   string `json:"block_id"`
+	Proposer  int    `json:"proposer,omitempty"`
+	QCType    string `json:"qc_type"`
+	QCView    int    `json:"qc_view"`
+	QC        string `json:"qc"`
+	QCSigners []int  `json:"qc_signers,omitempty"`
+	// Justify* 为 HotStuff 区块自身携带的 justify QC，重启后回放选主历史时使用。
+	JustifyBlockID string   `json:"justify_block_id,omitempty"`
+	JustifyView    int      `json:"justify_view,omitempty"`
+	JustifyQC      string   `json:"justify_qc,omitempty"`
+	JustifySigners []int    `json:"justify_signers,omitempty"`
+	Tx             []string `json:"tx,omitempty"`
+	Codes          []int    `json:"codes,omitempty"`
+	CommittedAt    int64    `json:"committed_at"`
+}
+
+// 证据类型。
+const (
+	// 同一 view 下两条冲突的签名提案。
+	EvidenceConflictingProposal = "conflicting_proposal"
+	// 同一 view 同一类型下投给不同区块的两张签名票。
+	EvidenceDuplicateVote = "duplicate_vote"
+	// leader 签名广播、但聚合值与签名者不符或签名者不足门限的 QC。
+	EvidenceInvalidQC = "invalid_qc"
+)
+
+// EvidenceRecord 是一条节点作恶证据：First/Second 为作恶节点签名的、相互冲突的消息原文
+// （invalid_qc 只有 First）。Reporter 为最先发现的节点，IncludedHeight 为证据被打包进已提交区块的高度。
+type EvidenceRecord struct {
+	Kind           string          `json:"kind"`
+	Offender       int             `json:"offender"`
+	View           int             `json:"view"`
+	Height         int             `json:"height"`
+	Reporter       int             `json:"reporter,omitempty"`
+	First          json.RawMessage `json:"first"`
+	Second         json.RawMessage `json:"second,omitempty"`
+	DetectedAt     int64           `json:"detected_at"`
+	IncludedHeight int             `json:"included_height,omitempty"`
+}
+
 type PrepareRecord struct {
 	Alg       string `json:"alg"`
 	Digest    string `json:"digest"`
@@ -47,6 +104,75 @@ type MetricRecord struct {
 	Throughput    float64 `json:"throughput"`
 	RecordedAt    int64   `json:"recorded_at"`
 	WindowSeconds int     `json:"window_seconds"`
+	// 截至该高度的全程时延统计与最近窗口内的时延统计，旧记录中为空。
+	RunLatency    *LatencySummary `json:"run_latency,omitempty"`
+	WindowLatency *LatencySummary `json:"window_latency,omitempty"`
+}
+
+// LatencySummary 是时延直方图的汇总，单位为秒。
+type LatencySummary struct {
+	Count int64   `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p999"`
+}
+
+// HistogramRecord 是以微秒计的时延直方图，Buckets 为非空桶下标到计数的映射。
+type HistogramRecord struct {
+	Count   int64         `json:"count"`
+	Min     int64         `json:"min"`
+	Max     int64         `json:"max"`
+	Sum     int64         `json:"sum"`
+	Buckets map[int]int64 `json:"buckets"`
+}
+
+type AccountRecord struct {
+	ID      int `json:"id"`
+	Balance int `json:"balance"`
+	Nonce   int `json:"nonce"`
+}
+
+type StateRootRecord struct {
+	Height    int    `json:"height"`
+	BlockID   string `json:"block_id"`
+	Root      string `json:"root"`
+	Applied   int    `json:"applied"`
+	Skipped   int    `json:"skipped"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// SnapshotRecord 的序列化内容即快照哈希的输入，只包含各节点在同一已提交高度上一致的字段；
+// HighQC 是节点本地状态，随 SnapshotMeta 传递，不计入哈希。
+type SnapshotRecord struct {
+	Height    int             `json:"height"`
+	View      int             `json:"view"`
+	Alg       string          `json:"alg"`
+	BlockID   string          `json:"block_id"`
+	QC        QCRecord        `json:"qc"`
+	HighQC    QCRecord        `json:"-"`
+	App       string          `json:"app"`
+	StateRoot string          `json:"state_root,omitempty"`
+	AppState  json.RawMessage `json:"app_state,omitempty"`
+	// Election 为选主策略所需的最近已提交历史，引导节点据此与集群得出相同的 leader。
+	Election json.RawMessage `json:"election,omitempty"`
+	// Membership 为验证者集合的变更历史，新加入的节点据此得知自己何时成为验证者。
+	Membership json.RawMessage `json:"membership,omitempty"`
+}
+
+type SnapshotMeta struct {
+	Height      int      `json:"height"`
+	View        int      `json:"view"`
+	BlockID     string   `json:"block_id"`
+	Hash        string   `json:"hash"`
+	ChunkSize   int      `json:"chunk_size"`
+	Chunks      int      `json:"chunks"`
+	ChunkHashes []string `json:"chunk_hashes"`
+	HighQC      QCRecord `json:"high_qc"`
+	CreatedAt   int64    `json:"created_at"`
 }
 
 type BlockStore interface {
@@ -54,6 +180,14 @@ type BlockStore interface {
 	GetBlock(id string) (BlockRecord, error)
 	SaveQC(qc QCRecord) error
 	GetQC(blockID string) (QCRecord, error)
+	SaveCommit(record CommitRecord) error
+	// ListCommits 按高度升序返回不低于 from 的至多 limit 条提交记录。
+	ListCommits(from, limit int) ([]CommitRecord, error)
+	// SaveEvidence 按 (类型, view, 作恶节点) 保存证据，同一键只保留首次收到的记录；返回是否为新证据。
+	SaveEvidence(record EvidenceRecord) (bool, error)
+	// MarkEvidenceIncluded 记录证据被打包进已提交区块的高度（只记录首次），本地尚无该证据时一并保存。
+	MarkEvidenceIncluded(record EvidenceRecord, height int) error
+	ListEvidence() ([]EvidenceRecord, error)
 }
 
 type StateStore interface {
@@ -67,16 +201,88 @@ type StateStore interface {
 	LoadLockedQC() (QCRecord, error)
 	SaveLastCommittedBlock(blockID string) error
 	LoadLastCommittedBlock() (string, error)
+	SaveLastCommittedHeight(height int) error
+	LoadLastCommittedHeight() (int, error)
 	SaveVote(view int, blockID string) error
 	LoadVote(view int) (string, error)
 	SavePrepare(record PrepareRecord) error
 	SaveCommitProof(qc QCRecord) error
+	// SaveMembership / LoadMembership 保存验证者集合的变更历史（membership.Set 的导出）。
+	SaveMembership(raw json.RawMessage) error
+	LoadMembership() (json.RawMessage, error)
+}
+
+type SnapshotStore interface {
+	SaveSnapshot(meta SnapshotMeta, chunks [][]byte) error
+	LoadLatestSnapshotMeta() (SnapshotMeta, error)
+	LoadSnapshotMeta(height int) (SnapshotMeta, error)
+	LoadSnapshotChunk(height, index int) ([]byte, error)
+	PruneSnapshotsBefore(height int) error
+}
+
+type LedgerStore interface {
+	LoadAccounts() ([]AccountRecord, error)
+	SaveBlockState(accounts []AccountRecord, root StateRootRecord) error
+	ReplaceAccounts(accounts []AccountRecord, root StateRootRecord) error
+	LoadStateRoot(height int) (StateRootRecord, error)
+	LoadLatestStateRoot() (StateRootRecord, error)
+}
+
+type KVStore interface {
+	LoadKV() (map[string]string, error)
+	SaveKVBlock(sets map[string]string, deletes []string, root StateRootRecord) error
+	ReplaceKV(entries map[string]string, root StateRootRecord) error
+	LoadStateRoot(height int) (StateRootRecord, error)
+	LoadLatestStateRoot() (StateRootRecord, error)
+}
+
+// 分阶段耗时的名称，按流水线顺序排列。
+const (
+	PhaseProposal       = "proposal"        // client 记录 /start → leader 广播提案（组批与上报 /start）
+	PhaseDissemination  = "dissemination"   // leader 广播提案 → 其余节点收到提案（中位数）
+	PhaseVoteCollection = "vote_collection" // 其余节点收到提案 → leader 凑齐法定票数
+	PhaseQCBroadcast    = "qc_broadcast"    // leader 凑齐票数 → 其余节点收到 QC/提交证明
+	PhaseExecution      = "execution"       // 收到 QC → 执行完成（链式 HotStuff 含等待后继块的时间）
+	PhaseTotal          = "total"           // leader 广播提案 → 执行完成
+)
+
+// PhaseOrder 为展示时的阶段顺序。
+var PhaseOrder = []string{PhaseProposal, PhaseDissemination, PhaseVoteCollection, PhaseQCBroadcast, PhaseExecution, PhaseTotal}
+
+// PhaseRecord 是 client 由各节点阶段时间戳算出的某高度分阶段耗时（秒），键为上面的阶段名称。
+type PhaseRecord struct {
+	Height     int                `json:"height"`
+	Alg        string             `json:"alg"`
+	Leader     int                `json:"leader"`
+	Reports    int                `json:"reports"`
+	Durations  map[string]float64 `json:"durations"`
+	RecordedAt int64              `json:"recorded_at"`
+}
+
+// RunRecord 是一次运行的元数据；该运行的指标、样本与直方图都以 ID 为命名空间保存。
+type RunRecord struct {
+	ID        string            `json:"id"`
+	Alg       string            `json:"alg,omitempty"`
+	N         int               `json:"n"`
+	StartedAt int64             `json:"started_at"`
+	Config    map[string]string `json:"config,omitempty"`
+}
+
+type RunStore interface {
+	SaveRun(record RunRecord) error
+	LoadRun(id string) (RunRecord, error)
+	ListRuns() ([]RunRecord, error)
 }
 
+// MetricsStore 保存单次运行的逐高度指标，实现按运行 ID 隔离键空间。
 type MetricsStore interface {
 	SaveMetric(record MetricRecord) error
 	LoadMetric(height int) (MetricRecord, error)
 	ListMetrics() ([]MetricRecord, error)
 	AppendThroughputSample(record ThroughputSampleRecord) error
 	LoadThroughputSamplesSince(sinceUnixNano int64) ([]ThroughputSampleRecord, error)
+	SavePhases(record PhaseRecord) error
+	ListPhases() ([]PhaseRecord, error)
+	SaveHistogram(name string, record HistogramRecord) error
+	LoadHistogram(name string) (HistogramRecord, error)
 }
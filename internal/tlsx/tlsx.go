@@ -0,0 +1,234 @@
+package tlsx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mybft/internal/redisx"
+)
+
+// 证书材料在 Redis 中的位置：CA 证书与 client 身份在 cluster:tls，节点证书在 Node:<id>。
+const (
+	clusterKey = "cluster:tls"
+	nodePrefix = "node-"
+	// ClientName 为 client 证书的 CN；节点证书的 CN 为 node-<id>。
+	ClientName = "client"
+)
+
+// Enabled 由 MYBFT_TLS=1 开启；默认明文 HTTP，便于作为基准对照。
+func Enabled() bool {
+	return os.Getenv("MYBFT_TLS") == "1"
+}
+
+// Scheme 返回传输方式对应的 URL scheme。
+func Scheme(enabled bool) string {
+	if enabled {
+		return "https"
+	}
+	return "http"
+}
+
+// NodeName 返回节点证书的 CN。
+func NodeName(id int) string {
+	return fmt.Sprintf("%s%d", nodePrefix, id)
+}
+
+// CA 是 genkey 生成的本地根证书，只用于签发集群内的证书，私钥不落盘。
+type CA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	CertPEM string
+}
+
+// NewCA 生成自签名的本地 CA。
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial(),
+		Subject:               pkix.Name{CommonName: "mybft-local-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{cert: cert, key: key, CertPEM: encodePEM("CERTIFICATE", der)}, nil
+}
+
+// Issue 签发 CN 为 name 的证书，同时用于服务端与客户端认证，地址覆盖 127.0.0.1 与 localhost。
+func (ca *CA) Issue(name string) (certPEM, keyPEM string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial(),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return "", "", err
+	}
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return encodePEM("CERTIFICATE", der), encodePEM("EC PRIVATE KEY", rawKey), nil
+}
+
+// Save 把 CA 证书、client 身份与各节点证书写入 Redis。
+func (ca *CA) Save(rdb *redisx.Client, ids []int) error {
+	certPEM, keyPEM, err := ca.Issue(ClientName)
+	if err != nil {
+		return err
+	}
+	if err := rdb.HSet(clusterKey, map[string]string{"ca_cert": ca.CertPEM, "client_cert": certPEM, "client_key": keyPEM}); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		certPEM, keyPEM, err := ca.Issue(NodeName(id))
+		if err != nil {
+			return err
+		}
+		if err := rdb.HSet(fmt.Sprintf("Node:%d", id), map[string]string{"tls_cert": certPEM, "tls_key": keyPEM}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Identity 是一方的证书、私钥与信任的 CA。
+type Identity struct {
+	cert tls.Certificate
+	pool *x509.CertPool
+}
+
+// LoadNode 从 Redis 读取节点 id 的证书。
+func LoadNode(rdb *redisx.Client, id int) (*Identity, error) {
+	key := fmt.Sprintf("Node:%d", id)
+	return load(rdb, key, "tls_cert", "tls_key")
+}
+
+// LoadClient 读取 client 身份，client 服务与 loadgen/audit 等工具共用。
+func LoadClient(rdb *redisx.Client) (*Identity, error) {
+	return load(rdb, clusterKey, "client_cert", "client_key")
+}
+
+func load(rdb *redisx.Client, key, certField, keyField string) (*Identity, error) {
+	caPEM, err := rdb.HGet(clusterKey, "ca_cert")
+	if err != nil || caPEM == "" {
+		return nil, fmt.Errorf("load %s ca_cert: %v (run genkey first)", clusterKey, err)
+	}
+	certPEM, err := rdb.HGet(key, certField)
+	if err != nil || certPEM == "" {
+		return nil, fmt.Errorf("load %s %s: %v", key, certField, err)
+	}
+	keyPEM, err := rdb.HGet(key, keyField)
+	if err != nil || keyPEM == "" {
+		return nil, fmt.Errorf("load %s %s: %v", key, keyField, err)
+	}
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s certificate: %w", key, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+		return nil, fmt.Errorf("parse %s ca_cert", clusterKey)
+	}
+	return &Identity{cert: cert, pool: pool}, nil
+}
+
+// ServerConfig 要求对端提供证书时由本地 CA 签发；不提供证书的请求（如 curl 查询）仍被接受，
+// 需要认证的入口用 PeerNode/PeerName 检查身份。
+func (id *Identity) ServerConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{id.cert},
+		ClientCAs:    id.pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// HTTPClient 返回出示本方证书、只信任本地 CA 的 HTTP 客户端。
+func (id *Identity) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates: []tls.Certificate{id.cert},
+			RootCAs:      id.pool,
+			MinVersion:   tls.VersionTLS12,
+		}},
+	}
+}
+
+// NewHTTPClient 供 loadgen/audit/bench 等工具构造 HTTP 客户端：enabled 时以 client 身份访问节点。
+func NewHTTPClient(rdb *redisx.Client, enabled bool, timeout time.Duration) (*http.Client, error) {
+	if !enabled {
+		return &http.Client{Timeout: timeout}, nil
+	}
+	id, err := LoadClient(rdb)
+	if err != nil {
+		return nil, err
+	}
+	return id.HTTPClient(timeout), nil
+}
+
+// PeerName 返回请求方已验证证书的 CN，未出示证书时返回 false。
+func PeerName(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+// PeerNode 返回请求方节点证书中的节点编号。
+func PeerNode(r *http.Request) (int, bool) {
+	name, ok := PeerName(r)
+	if !ok || !strings.HasPrefix(name, nodePrefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(name, nodePrefix))
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+func serial() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 120))
+	return n
+}
+
+func encodePEM(kind string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}))
+}
@@ -0,0 +1,154 @@
+package workload
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	AccessUniform  = "uniform"
+	AccessZipf     = "zipf"
+	AccessConflict = "conflict"
+)
+
+// Dist 描述金额/手续费分布：uniform:min:max、fixed:v 或 exp:mean。
+type Dist struct {
+	Kind string
+	Min  int
+	Max  int
+	Mean float64
+}
+
+func (d Dist) String() string {
+	switch d.Kind {
+	case "fixed":
+		return fmt.Sprintf("fixed:%d", d.Min)
+	case "exp":
+		return fmt.Sprintf("exp:%g", d.Mean)
+	default:
+		return fmt.Sprintf("uniform:%d:%d", d.Min, d.Max)
+	}
+}
+
+// ParseDist 解析分布描述字符串。
+func ParseDist(raw string) (Dist, error) {
+	parts := strings.Split(raw, ":")
+	switch parts[0] {
+	case "uniform":
+		if len(parts) != 3 {
+			return Dist{}, fmt.Errorf("uniform dist needs min:max: %q", raw)
+		}
+		lo, err1 := strconv.Atoi(parts[1])
+		hi, err2 := strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil || lo < 0 || hi < lo {
+			return Dist{}, fmt.Errorf("invalid uniform dist: %q", raw)
+		}
+		return Dist{Kind: "uniform", Min: lo, Max: hi}, nil
+	case "fixed":
+		if len(parts) != 2 {
+			return Dist{}, fmt.Errorf("fixed dist needs a value: %q", raw)
+		}
+		v, err := strconv.Atoi(parts[1])
+		if err != nil || v < 0 {
+			return Dist{}, fmt.Errorf("invalid fixed dist: %q", raw)
+		}
+		return Dist{Kind: "fixed", Min: v, Max: v}, nil
+	case "exp":
+		if len(parts) != 2 {
+			return Dist{}, fmt.Errorf("exp dist needs a mean: %q", raw)
+		}
+		mean, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || mean <= 0 {
+			return Dist{}, fmt.Errorf("invalid exp dist: %q", raw)
+		}
+		return Dist{Kind: "exp", Mean: mean}, nil
+	}
+	return Dist{}, fmt.Errorf("unknown dist: %q", raw)
+}
+
+type Config struct {
+	Seed          int64
+	Accounts      int
+	Access        string
+	ZipfS         float64
+	ConflictRatio float64
+	HotAccounts   int
+	Amount        Dist
+	Fee           Dist
+	TxSize        int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Seed:          1,
+		Access:        AccessUniform,
+		ZipfS:         1.1,
+		ConflictRatio: 0.1,
+		HotAccounts:   10,
+		Amount:        Dist{Kind: "uniform", Min: 1, Max: 50},
+		Fee:           Dist{Kind: "uniform", Min: 1, Max: 3},
+	}
+}
+
+// ConfigFromEnv 读取负载配置：
+// MYBFT_WORKLOAD_SEED、MYBFT_WORKLOAD_ACCESS=uniform|zipf|conflict、MYBFT_WORKLOAD_ZIPF_S、
+// MYBFT_WORKLOAD_CONFLICT_RATIO、MYBFT_WORKLOAD_HOT_ACCOUNTS、MYBFT_WORKLOAD_AMOUNT、
+// MYBFT_WORKLOAD_FEE、MYBFT_WORKLOAD_TX_SIZE。
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	if raw := os.Getenv("MYBFT_WORKLOAD_SEED"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("MYBFT_WORKLOAD_SEED: %w", err)
+		}
+		cfg.Seed = v
+	}
+	if raw := os.Getenv("MYBFT_WORKLOAD_ACCESS"); raw != "" {
+		switch raw {
+		case AccessUniform, AccessZipf, AccessConflict:
+			cfg.Access = raw
+		default:
+			return cfg, fmt.Errorf("MYBFT_WORKLOAD_ACCESS: unknown distribution %q", raw)
+		}
+	}
+	if raw := os.Getenv("MYBFT_WORKLOAD_ZIPF_S"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v <= 1 {
+			return cfg, fmt.Errorf("MYBFT_WORKLOAD_ZIPF_S must be > 1: %q", raw)
+		}
+		cfg.ZipfS = v
+	}
+	if raw := os.Getenv("MYBFT_WORKLOAD_CONFLICT_RATIO"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > 1 {
+			return cfg, fmt.Errorf("MYBFT_WORKLOAD_CONFLICT_RATIO must be in [0,1]: %q", raw)
+		}
+		cfg.ConflictRatio = v
+	}
+	if raw := os.Getenv("MYBFT_WORKLOAD_HOT_ACCOUNTS"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			return cfg, fmt.Errorf("MYBFT_WORKLOAD_HOT_ACCOUNTS: %q", raw)
+		}
+		cfg.HotAccounts = v
+	}
+	for key, dst := range map[string]*Dist{"MYBFT_WORKLOAD_AMOUNT": &cfg.Amount, "MYBFT_WORKLOAD_FEE": &cfg.Fee} {
+		if raw := os.Getenv(key); raw != "" {
+			d, err := ParseDist(raw)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: %w", key, err)
+			}
+			*dst = d
+		}
+	}
+	if raw := os.Getenv("MYBFT_WORKLOAD_TX_SIZE"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return cfg, fmt.Errorf("MYBFT_WORKLOAD_TX_SIZE: %q", raw)
+		}
+		cfg.TxSize = v
+	}
+	return cfg, nil
+}
@@ -0,0 +1,145 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"mybft/internal/ledger"
+)
+
+// State 为生成转账时使用的账户视图（通常是 ledger.Overlay）。
+type State interface {
+	Account(id int) (balance, nonce int)
+	Exec(tx ledger.Tx) error
+}
+
+// Generator 是可复现的负载生成器：每个高度使用 (seed, height) 派生的独立随机源，
+// 同一种子、同一初始状态下生成的交易序列完全一致。
+type Generator struct {
+	cfg Config
+}
+
+func New(cfg Config) *Generator {
+	if cfg.Accounts <= 0 || cfg.Accounts > ledger.AccountCount {
+		cfg.Accounts = ledger.AccountCount
+	}
+	if cfg.HotAccounts >= cfg.Accounts {
+		cfg.HotAccounts = cfg.Accounts - 1
+	}
+	if cfg.ZipfS <= 1 {
+		cfg.ZipfS = 1.1
+	}
+	return &Generator{cfg: cfg}
+}
+
+func (g *Generator) Config() Config { return g.cfg }
+
+func (g *Generator) rng(height int) *rand.Rand {
+	return rand.New(rand.NewSource(g.cfg.Seed*1_000_003 + int64(height)))
+}
+
+// picker 按访问分布选取账户编号（1..Accounts）。
+type picker struct {
+	cfg  Config
+	r    *rand.Rand
+	zipf *rand.Zipf
+}
+
+func (g *Generator) newPicker(r *rand.Rand) *picker {
+	p := &picker{cfg: g.cfg, r: r}
+	if g.cfg.Access == AccessZipf {
+		p.zipf = rand.NewZipf(r, g.cfg.ZipfS, 1, uint64(g.cfg.Accounts-1))
+	}
+	return p
+}
+
+func (p *picker) pick() int {
+	switch p.cfg.Access {
+	case AccessZipf:
+		return 1 + int(p.zipf.Uint64())
+	case AccessConflict:
+		if p.r.Float64() < p.cfg.ConflictRatio {
+			return 1 + p.r.Intn(p.cfg.HotAccounts)
+		}
+		return p.cfg.HotAccounts + 1 + p.r.Intn(p.cfg.Accounts-p.cfg.HotAccounts)
+	default:
+		return 1 + p.r.Intn(p.cfg.Accounts)
+	}
+}
+
+func sample(r *rand.Rand, d Dist) int {
+	switch d.Kind {
+	case "fixed":
+		return d.Min
+	case "exp":
+		return int(math.Round(r.ExpFloat64() * d.Mean))
+	default:
+		return d.Min + r.Intn(d.Max-d.Min+1)
+	}
+}
+
+// Transfers 在给定状态上生成 count 笔可执行的转账，并把它们依次写入 state。
+func (g *Generator) Transfers(height, count int, state State) []string {
+	r := g.rng(height)
+	p := g.newPicker(r)
+	out := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		from := p.pick()
+		balance, nonce := state.Account(from)
+		for retries := 0; balance < 2 && retries < g.cfg.Accounts; retries++ {
+			from = p.pick()
+			balance, nonce = state.Account(from)
+		}
+		to := p.pick()
+		for to == from {
+			to = 1 + r.Intn(g.cfg.Accounts)
+		}
+		fee := sample(r, g.cfg.Fee)
+		amount := sample(r, g.cfg.Amount)
+		if amount < 1 {
+			amount = 1
+		}
+		if fee+amount > balance {
+			fee = 0
+			amount = balance
+		}
+		tx := ledger.Tx{From: from, To: to, Amount: amount, Nonce: nonce + 1, Fee: fee}
+		tx.Memo = padding(r, len(tx.String())+1, g.cfg.TxSize)
+		if err := state.Exec(tx); err != nil {
+			continue
+		}
+		out = append(out, tx.String())
+	}
+	return out
+}
+
+// KVWrites 生成 count 笔键值写入，键按访问分布选取，值填充到 TxSize。
+func (g *Generator) KVWrites(height, count int) []string {
+	r := g.rng(height)
+	p := g.newPicker(r)
+	out := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		tx := fmt.Sprintf("set k%d v%d-%d", p.pick(), height, i)
+		if pad := padding(r, len(tx), g.cfg.TxSize); pad != "" {
+			tx += "-" + pad
+		}
+		out = append(out, tx)
+	}
+	return out
+}
+
+const paddingAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// 生成把交易补齐到 size 字节所需的随机填充；已超过目标大小时不填充。
+func padding(r *rand.Rand, have, size int) string {
+	if size <= 0 || have >= size {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i < size-have; i++ {
+		b.WriteByte(paddingAlphabet[r.Intn(len(paddingAlphabet))])
+	}
+	return b.String()
+}